@@ -0,0 +1,223 @@
+// Command batches re-classifies tasks in bulk, like the
+// /api/admin/classification-batches endpoints, running the batch in this
+// process until it finishes.
+//
+//	batches dry-run -user carlos@kemeny.studio -classified-before 2025-01-01
+//	batches create -user carlos@kemeny.studio -unclassified -rate 60
+//	batches cancel <id>
+//	batches resume <id>
+//
+// Interrupting create or resume cancels the batch; resume continues it.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/classification"
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/llm"
+	"github.com/KemenyStudio/task-manager/internal/model"
+	"github.com/KemenyStudio/task-manager/internal/usage"
+)
+
+// progressEvery is how often a running batch reports its progress.
+const progressEvery = 10 * time.Second
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		printUsage()
+	}
+	if err := db.Connect(); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	switch os.Args[1] {
+	case "create":
+		createCmd(os.Args[2:], false)
+	case "dry-run":
+		createCmd(os.Args[2:], true)
+	case "cancel":
+		cancelCmd(os.Args[2:])
+	case "resume":
+		resumeCmd(os.Args[2:])
+	default:
+		printUsage()
+	}
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, `usage:
+  batches create|dry-run -user email [-unclassified] [-classified-before date] [-category name]
+                         [-limit n] [-force] [-concurrency n] [-rate n]
+  batches cancel <id>
+  batches resume <id>`)
+	os.Exit(2)
+}
+
+func createCmd(args []string, dryRun bool) {
+	fs := flag.NewFlagSet("create", flag.ExitOnError)
+	email := fs.String("user", "", "email of the user the batch runs for; suggestions are made on their behalf")
+	unclassified := fs.Bool("unclassified", false, "only tasks without a category that were never classified")
+	before := fs.String("classified-before", "", "only tasks last classified before this date (YYYY-MM-DD) or RFC 3339 time")
+	category := fs.String("category", "", "only tasks in this category")
+	limit := fs.Int("limit", 0, "classify at most this many tasks")
+	force := fs.Bool("force", false, "bypass the classification cache")
+	concurrency := fs.Int("concurrency", 0, "tasks classified in parallel (default 4)")
+	rate := fs.Int("rate", 0, "classifications started per minute; 0 means unlimited")
+	fs.Parse(args)
+	if *email == "" || fs.NArg() != 0 {
+		printUsage()
+	}
+
+	f := model.ClassificationBatchFilter{Unclassified: *unclassified, Limit: *limit}
+	if *category != "" {
+		f.Category = category
+	}
+	if *before != "" {
+		t, err := time.Parse(time.RFC3339, *before)
+		if err != nil {
+			if t, err = time.Parse("2006-01-02", *before); err != nil {
+				log.Fatal("-classified-before must be a date (YYYY-MM-DD) or RFC 3339 time")
+			}
+		}
+		f.ClassifiedBefore = &t
+	}
+
+	ctx := context.Background()
+	var userID string
+	err := db.Pool.QueryRow(ctx, "SELECT id FROM users WHERE email = $1", *email).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		log.Fatalf("No user with email %s", *email)
+	} else if err != nil {
+		log.Fatalf("Failed to look up user: %v", err)
+	}
+
+	client := newClient()
+	b, err := classification.CreateBatch(ctx, userID, f, classification.BatchOptions{
+		DryRun: dryRun, Force: *force, Concurrency: *concurrency, RatePerMinute: *rate,
+	})
+	if err != nil {
+		log.Fatalf("Failed to create batch: %v", err)
+	}
+	fmt.Printf("Batch %s: %d tasks\n", b.ID, b.Progress.Total)
+	run(client, b.ID)
+}
+
+func cancelCmd(args []string) {
+	if len(args) != 1 {
+		printUsage()
+	}
+	b, err := classification.CancelBatch(context.Background(), args[0])
+	if err != nil {
+		log.Fatalf("Failed to cancel batch: %v", err)
+	}
+	report(b)
+}
+
+func resumeCmd(args []string) {
+	if len(args) != 1 {
+		printUsage()
+	}
+	client := newClient()
+	b, err := classification.ResumeBatch(context.Background(), args[0])
+	if err != nil {
+		log.Fatalf("Failed to resume batch: %v", err)
+	}
+	run(client, b.ID)
+}
+
+// newClient configures the LLM client the way the server does.
+func newClient() llm.LLMClient {
+	client, err := llm.NewClientFromEnv(classification.Cache{},
+		usage.Recorder{Workspace: usage.Workspace()}, usage.RedactionAuditor{Workspace: usage.Workspace()})
+	if err != nil {
+		log.Fatalf("Failed to configure LLM client: %v", err)
+	}
+	return client
+}
+
+// run classifies the batch until it finishes, reporting progress on the
+// way. On interrupt the batch is cancelled so it can be resumed.
+func run(client llm.LLMClient, id string) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		classification.RunBatch(ctx, client, id)
+	}()
+	t := time.NewTicker(progressEvery)
+	defer t.Stop()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		case <-t.C:
+			if b, err := classification.GetBatch(context.Background(), id); err == nil {
+				p := b.Progress
+				fmt.Printf("%d/%d done, %d failed, %d changed\n", p.Done, p.Total, p.Failed, p.Changed)
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		b, err := classification.CancelBatch(context.Background(), id)
+		if err != nil && !errors.Is(err, classification.ErrBatchState) {
+			log.Fatalf("Failed to cancel batch: %v", err)
+		}
+		if b != nil {
+			report(b)
+			return
+		}
+	}
+	b, err := classification.GetBatch(context.Background(), id)
+	if err != nil {
+		log.Fatalf("Failed to load batch: %v", err)
+	}
+	report(b)
+	if b.DryRun {
+		changes(id)
+	}
+}
+
+func report(b *model.ClassificationBatch) {
+	p := b.Progress
+	fmt.Printf("Batch %s %s: %d tasks, %d done, %d failed, %d changed, %d pending\n",
+		b.ID, b.Status, p.Total, p.Done, p.Failed, p.Changed, p.Pending)
+	if b.LastError != nil {
+		fmt.Printf("Last error: %s\n", *b.LastError)
+	}
+}
+
+// changes lists what the batch would change on each task.
+func changes(id string) {
+	items, err := classification.BatchItems(context.Background(), id, "", true)
+	if err != nil {
+		log.Fatalf("Failed to list batch tasks: %v", err)
+	}
+	for _, it := range items {
+		fmt.Printf("\n%s  %s\n", it.TaskID, it.Title)
+		for _, field := range slices.Sorted(maps.Keys(it.Changes)) {
+			c := it.Changes[field]
+			from := "-"
+			if c.From != nil {
+				from = *c.From
+			}
+			fmt.Printf("  %-9s %s -> %s\n", field, from, c.To)
+		}
+	}
+}
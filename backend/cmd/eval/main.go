@@ -0,0 +1,158 @@
+// Command eval scores LLM classification against a labeled dataset.
+//
+//	eval run -provider openai -model gpt-4o-mini -prompt candidate.tmpl -out candidate.json
+//	eval diff baseline.json candidate.json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/KemenyStudio/task-manager/internal/eval"
+	"github.com/KemenyStudio/task-manager/internal/llm"
+)
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "run":
+		runCmd(os.Args[2:])
+	case "diff":
+		diffCmd(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage:
+  eval run [-dataset file] [-provider name] [-model name] [-prompt file] [-replay run.json]
+           [-cassette file [-record]] [-out run.json]
+  eval diff [-json] baseline.json candidate.json`)
+	os.Exit(2)
+}
+
+func runCmd(args []string) {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	dataset := fs.String("dataset", "testdata/eval/classification.jsonl", "labeled dataset, one JSON case per line")
+	provider := fs.String("provider", "mock", "openai, anthropic, local, ollama, mock or replay")
+	modelName := fs.String("model", "", "model to use instead of the provider's default")
+	promptFile := fs.String("prompt", "", "text/template file to classify with instead of the built-in prompt")
+	replay := fs.String("replay", "", "saved run to replay answers from (with -provider replay)")
+	cassette := fs.String("cassette", "", "replay provider HTTP calls from this cassette instead of the network")
+	record := fs.Bool("record", false, "with -cassette, call the provider for unrecorded requests and save them")
+	out := fs.String("out", "", "write the run as JSON to this file")
+	name := fs.String("name", "", "run name (default provider:model)")
+	concurrency := fs.Int("concurrency", 4, "cases classified in parallel")
+	timeout := fs.Duration("timeout", 10*time.Minute, "deadline for the whole run")
+	fs.Parse(args)
+
+	cases, err := eval.LoadDataset(*dataset)
+	if err != nil {
+		log.Fatalf("Failed to load dataset: %v", err)
+	}
+
+	var tape *llm.Cassette
+	if *cassette != "" {
+		tape, err = llm.LoadCassette(*cassette, !*record)
+		if err != nil {
+			log.Fatalf("Failed to load cassette: %v", err)
+		}
+		llm.SetHTTPClient(tape.HTTPClient())
+	}
+
+	var client llm.LLMClient
+	if *provider == "replay" {
+		if *replay == "" {
+			log.Fatal("-provider replay needs -replay run.json")
+		}
+		saved, err := eval.ReadRun(*replay)
+		if err != nil {
+			log.Fatalf("Failed to read replayed run: %v", err)
+		}
+		client = eval.NewReplayClient(saved)
+	} else {
+		client, err = llm.NewProvider(*provider, *modelName)
+		if err != nil {
+			log.Fatalf("Failed to configure provider: %v", err)
+		}
+		if _, isMock := client.(*llm.MockClient); !isMock {
+			client = llm.NewResilientClient(client, llm.ResilientConfig{})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	if *promptFile != "" {
+		body, err := os.ReadFile(*promptFile)
+		if err != nil {
+			log.Fatalf("Failed to read prompt: %v", err)
+		}
+		p, err := llm.NewPromptTemplate(filepath.Base(*promptFile), string(body), nil)
+		if err != nil {
+			log.Fatalf("Invalid prompt template: %v", err)
+		}
+		ctx = llm.WithPromptTemplate(ctx, p)
+	}
+
+	if *name == "" {
+		*name = strings.TrimSuffix(*provider+":"+*modelName, ":")
+		if *promptFile != "" {
+			*name += "+" + filepath.Base(*promptFile)
+		}
+	}
+	run := eval.Evaluate(ctx, *name, client, cases, *concurrency)
+	eval.WriteReport(os.Stdout, run)
+
+	if tape != nil {
+		if err := tape.Save(); err != nil {
+			log.Fatalf("Failed to save cassette: %v", err)
+		}
+	}
+
+	if *out != "" {
+		if err := eval.WriteRun(*out, run); err != nil {
+			log.Fatalf("Failed to write run: %v", err)
+		}
+		fmt.Printf("\nRun written to %s\n", *out)
+	}
+}
+
+func diffCmd(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the diff as JSON")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		usage()
+	}
+
+	baseline, err := eval.ReadRun(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed to read baseline: %v", err)
+	}
+	candidate, err := eval.ReadRun(fs.Arg(1))
+	if err != nil {
+		log.Fatalf("Failed to read candidate: %v", err)
+	}
+
+	d := eval.Compare(baseline, candidate)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(d); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	eval.WriteDiff(os.Stdout, d)
+}
@@ -1,19 +1,29 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	_ "time/tzdata" // user timezones must resolve even on images without zoneinfo
 
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/rs/cors"
 
+	"github.com/KemenyStudio/task-manager/internal/classification"
 	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/duplicates"
+	"github.com/KemenyStudio/task-manager/internal/escalation"
 	"github.com/KemenyStudio/task-manager/internal/handler"
 	"github.com/KemenyStudio/task-manager/internal/llm"
 	"github.com/KemenyStudio/task-manager/internal/middleware"
+	"github.com/KemenyStudio/task-manager/internal/notification"
+	"github.com/KemenyStudio/task-manager/internal/scheduler"
+	"github.com/KemenyStudio/task-manager/internal/summary"
+	"github.com/KemenyStudio/task-manager/internal/usage"
 )
 
 // NOTE: No graceful shutdown implemented.
@@ -58,6 +68,7 @@ func main() {
 		// Tasks CRUD
 		r.Get("/tasks", handler.ListTasks)
 		r.Post("/tasks", handler.CreateTask)
+		r.Post("/tasks/parse", handler.ParseTaskText)
 		r.Get("/tasks/{id}", handler.GetTask)
 		r.Put("/tasks/{id}", handler.UpdateTask)
 		r.Delete("/tasks/{id}", handler.DeleteTask)
@@ -66,42 +77,177 @@ func main() {
 		r.Get("/tasks/{id}/history", handler.GetTaskHistory)
 		r.Get("/tasks/search", handler.SearchTasks)
 
+		// Watchers
+		r.Get("/tasks/{id}/watchers", handler.ListTaskWatchers)
+		r.Put("/tasks/{id}/watch", handler.WatchTask)
+		r.Delete("/tasks/{id}/watch", handler.UnwatchTask)
+
+		// Escalations
+		r.Get("/tasks/{id}/escalations", handler.ListTaskEscalations)
+		r.Post("/escalations/{id}/acknowledge", handler.AcknowledgeEscalation)
+
 		// AI classification
 		r.Post("/tasks/{id}/classify", handler.ClassifyTask)
+		r.Post("/tasks/{id}/classify/stream", handler.ClassifyTaskStream)
+		r.Get("/tasks/{id}/classification", handler.GetTaskClassification)
+		r.Get("/tasks/{id}/suggestions", handler.ListTaskSuggestions)
+		r.Post("/suggestions/{id}/accept", handler.AcceptSuggestion)
+		r.Post("/suggestions/{id}/reject", handler.RejectSuggestion)
+
+		// AI effort estimation
+		r.Post("/tasks/estimate", handler.EstimateDraft)
+		r.Post("/tasks/{id}/estimate", handler.EstimateTask)
+		r.Get("/tasks/{id}/estimates", handler.ListTaskEstimates)
+
+		// Duplicate detection
+		r.Get("/tasks/{id}/similar", handler.ListSimilarTasks)
+		r.Post("/tasks/{id}/merge", handler.MergeDuplicateTasks)
+
+		// Assignee suggestions
+		r.Get("/tasks/{id}/suggest-assignee", handler.SuggestAssignee)
+
+		// AI summaries
+		r.Get("/tasks/{id}/history/summary", handler.SummarizeTaskHistory)
+		r.Get("/reports/weekly", handler.ListWeeklyReports)
+		r.Get("/reports/weekly/{week}", handler.GetWeeklyReport)
 
 		// Dashboard
 		r.Get("/dashboard/stats", handler.GetDashboardStats)
+
+		// Notification inbox
+		r.Get("/notifications", handler.ListNotifications)
+		r.Get("/notifications/unread-count", handler.GetUnreadNotificationCount)
+		r.Post("/notifications/read-all", handler.MarkAllNotificationsRead)
+		r.Post("/notifications/{id}/read", handler.MarkNotificationRead)
+		r.Get("/notifications/preferences", handler.GetNotificationPreferences)
+		r.Put("/notifications/preferences", handler.UpdateNotificationPreferences)
+		r.Get("/notifications/digest", handler.GetDigestSettings)
+		r.Put("/notifications/digest", handler.UpdateDigestSettings)
+		r.Get("/notifications/digest/preview", handler.PreviewDigest)
+
+		// Admin
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(middleware.RequireAdmin)
+
+			r.Get("/jobs", handler.ListJobs)
+			r.Get("/jobs/{name}/runs", handler.ListJobRuns)
+			r.Post("/jobs/{name}/run", handler.TriggerJob)
+
+			r.Get("/suggestions/stats", handler.GetSuggestionStats)
+			r.Get("/classification-cache/stats", handler.GetClassificationCacheStats)
+			r.Get("/classification-batches", handler.ListClassificationBatches)
+			r.Post("/classification-batches", handler.CreateClassificationBatch)
+			r.Get("/classification-batches/{id}", handler.GetClassificationBatch)
+			r.Get("/classification-batches/{id}/items", handler.ListClassificationBatchItems)
+			r.Post("/classification-batches/{id}/cancel", handler.CancelClassificationBatch)
+			r.Post("/classification-batches/{id}/resume", handler.ResumeClassificationBatch)
+
+			r.Get("/prompt-templates/{name}", handler.ListPromptTemplates)
+			r.Post("/prompt-templates/{name}", handler.CreatePromptTemplate)
+			r.Post("/prompt-templates/{name}/versions/{version}/activate", handler.ActivatePromptTemplate)
+
+			r.Get("/llm-usage", handler.GetLLMUsage)
+			r.Get("/llm-redactions", handler.GetLLMRedactions)
+			r.Get("/llm-budgets", handler.ListLLMBudgets)
+			r.Put("/llm-budgets/{workspace}", handler.SetLLMBudget)
+
+			r.Get("/estimation/accuracy", handler.GetEstimationAccuracy)
+			r.Post("/reports/weekly", handler.GenerateWeeklyReport)
+
+			r.Get("/escalation-rules", handler.ListEscalationRules)
+			r.Post("/escalation-rules", handler.CreateEscalationRule)
+			r.Put("/escalation-rules/{id}", handler.UpdateEscalationRule)
+			r.Delete("/escalation-rules/{id}", handler.DeleteEscalationRule)
+		})
 	})
 
     // Wire LLM client after routes so handler.SetLLMClient is called before server start
-    var selected llm.LLMClient
-    // Provider selection logic: prefer LLM_PROVIDER if set, else detect available keys
-    provider := os.Getenv("LLM_PROVIDER")
-    openaiKey := os.Getenv("OPENAI_API_KEY")
-    anthropicKey := os.Getenv("ANTHROPIC_API_KEY")
-    switch provider {
-    case "openai":
-        if openaiKey != "" {
-            if c, err := llm.NewOpenAIClient(); err == nil { selected = c }
-        }
-    case "anthropic":
-        if anthropicKey != "" {
-            if c, err := llm.NewAnthropicClient(); err == nil { selected = c }
-        }
-    default:
-        // auto-detect: prefer OpenAI if key present
-        if openaiKey != "" {
-            if c, err := llm.NewOpenAIClient(); err == nil { selected = c }
-        } else if anthropicKey != "" {
-            if c, err := llm.NewAnthropicClient(); err == nil { selected = c }
-        }
-    }
-    if selected == nil {
-        // fallback to mock
-        selected = llm.NewMockClient()
+    selected, err := llm.NewClientFromEnv(classification.Cache{},
+        usage.Recorder{Workspace: usage.Workspace()}, usage.RedactionAuditor{Workspace: usage.Workspace()})
+    if err != nil {
+        log.Fatalf("Failed to configure LLM client: %v", err)
     }
     handler.SetLLMClient(selected)
 
+	// Embeddings for duplicate detection (EMBEDDING_PROVIDER=off disables them)
+	embedder, err := llm.NewEmbedderFromEnv(usage.Recorder{Workspace: usage.Workspace()},
+		usage.RedactionAuditor{Workspace: usage.Workspace()})
+	if err != nil {
+		log.Fatalf("Failed to configure embeddings: %v", err)
+	}
+	handler.SetEmbedder(embedder)
+
+	// Email delivery for notifications: SMTP when SMTP_HOST is set, log-only otherwise
+	notifier, err := notification.NewNotifierFromEnv()
+	if err != nil {
+		log.Printf("SMTP notifier not configured, falling back to log output: %v", err)
+	} else {
+		notification.SetNotifier(notifier)
+	}
+
+	// Background jobs. Every replica runs the scheduler; advisory locks make
+	// sure each job only executes on one of them at a time.
+	jobs := scheduler.New()
+	if err := jobs.Register("deadline-notifications",
+		getEnv("DEADLINE_NOTIFICATIONS_SCHEDULE", "0 * * * *"),
+		notification.SendDeadlineNotifications,
+	); err != nil {
+		log.Fatalf("Failed to register job: %v", err)
+	}
+	if err := jobs.Register("send-digests",
+		getEnv("DIGEST_SCHEDULE", "*/15 * * * *"),
+		notification.SendDigests,
+	); err != nil {
+		log.Fatalf("Failed to register job: %v", err)
+	}
+	if err := jobs.Register("notification-retention",
+		getEnv("NOTIFICATION_RETENTION_SCHEDULE", "30 3 * * *"),
+		notification.CleanupNotifications,
+	); err != nil {
+		log.Fatalf("Failed to register job: %v", err)
+	}
+	if err := jobs.Register("evaluate-escalations",
+		getEnv("ESCALATION_SCHEDULE", "*/10 * * * *"),
+		escalation.Evaluate,
+	); err != nil {
+		log.Fatalf("Failed to register job: %v", err)
+	}
+	if err := jobs.Register("purge-classification-cache",
+		getEnv("CLASSIFICATION_CACHE_PURGE_SCHEDULE", "45 3 * * *"),
+		classification.PurgeCache,
+	); err != nil {
+		log.Fatalf("Failed to register job: %v", err)
+	}
+	if embedder != nil {
+		if err := jobs.Register("embed-tasks",
+			getEnv("EMBED_TASKS_SCHEDULE", "*/10 * * * *"),
+			func(ctx context.Context) error { return duplicates.Backfill(ctx, embedder) },
+		); err != nil {
+			log.Fatalf("Failed to register job: %v", err)
+		}
+	}
+	if summarizer, ok := selected.(llm.Summarizer); ok {
+		if err := jobs.Register("weekly-report",
+			getEnv("WEEKLY_REPORT_SCHEDULE", "0 6 * * 1"),
+			summary.GenerateLastWeek(summarizer),
+		); err != nil {
+			log.Fatalf("Failed to register job: %v", err)
+		}
+	}
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	jobs.Start(schedulerCtx)
+	handler.SetScheduler(jobs)
+
+	// Classification queue workers (CLASSIFICATION_WORKERS, default 2; 0 disables them)
+	workers, err := strconv.Atoi(getEnv("CLASSIFICATION_WORKERS", "2"))
+	if err != nil || workers < 0 {
+		log.Fatalf("Invalid CLASSIFICATION_WORKERS: %q", os.Getenv("CLASSIFICATION_WORKERS"))
+	}
+	classification.StartWorkers(schedulerCtx, selected, workers)
+	// Bulk re-classification, resuming batches interrupted by a restart
+	classification.StartBatches(schedulerCtx, selected)
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
@@ -115,3 +261,10 @@ func main() {
 		log.Fatalf("Server failed: %v", err)
 	}
 }
+
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
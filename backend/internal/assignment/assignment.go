@@ -0,0 +1,320 @@
+// Package assignment suggests who to assign a task to, from the similar
+// tasks each user completed, their open workload and their upcoming due
+// dates.
+package assignment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/estimation"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+// Score weights; they add up to 1.
+const (
+	WeightExpertise    = 0.5
+	WeightAvailability = 0.3
+	WeightDeadlines    = 0.2
+)
+
+const (
+	// Capacity is the open work, in hours, at which availability is 0.5.
+	Capacity = 40.0
+	// DefaultTaskHours is what an open task without estimated_hours counts
+	// for in availability.
+	DefaultTaskHours = 4.0
+	// DueWindow is how far ahead due dates count when the task has no due
+	// date of its own.
+	DueWindow = 7 * 24 * time.Hour
+	// historyLimit caps the completed tasks compared, most recent first.
+	historyLimit = 5000
+)
+
+// ErrTaskNotFound is returned when the task to assign does not exist.
+var ErrTaskNotFound = errors.New("task not found")
+
+// Target is the task to assign.
+type Target struct {
+	Task       estimation.Sample
+	DueDate    *time.Time // set when the task is due in the future
+	AssigneeID string
+}
+
+// Candidate is a user who could take the task.
+type Candidate struct {
+	User        model.User
+	Completed   []estimation.Sample // done tasks assigned to the user
+	OpenTasks   int
+	OpenHours   float64 // sum of estimated_hours of open tasks
+	Unestimated int     // open tasks without estimated_hours
+	DueSoon     int     // open tasks due before the task, or within DueWindow
+	Overdue     int
+}
+
+// Suggest ranks users as assignees for a task, best first, returning at most
+// limit of them. Tasks merged into another are ignored, and the task itself
+// doesn't count towards its assignee's workload. A task without category or
+// tags uses those of its pending AI suggestion, if any.
+func Suggest(ctx context.Context, taskID string, limit int) ([]model.AssigneeSuggestion, error) {
+	target, err := loadTarget(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	horizon := time.Now().Add(DueWindow)
+	if target.DueDate != nil {
+		horizon = *target.DueDate
+	}
+	candidates, err := loadCandidates(ctx, taskID, horizon)
+	if err != nil {
+		return nil, err
+	}
+	ranked := Rank(target, candidates)
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked, nil
+}
+
+// Rank scores each candidate for the target and sorts them, best first.
+// Expertise grows with the similarity of the tasks a user completed to the
+// target, availability shrinks as open hours approach Capacity, and each
+// task due soon or overdue lowers the deadline score.
+func Rank(target Target, candidates []Candidate) []model.AssigneeSuggestion {
+	out := make([]model.AssigneeSuggestion, 0, len(candidates))
+	for _, c := range candidates {
+		neighbours := estimation.Nearest(target.Task, withHours(c.Completed), estimation.K)
+		var similarity float64
+		for _, n := range neighbours {
+			similarity += n.Similarity
+		}
+		load := c.OpenHours + float64(c.Unestimated)*DefaultTaskHours
+
+		s := model.AssigneeSuggestion{
+			User:         c.User,
+			Expertise:    round(1 - math.Exp(-similarity)),
+			Availability: round(Capacity / (Capacity + load)),
+			Deadlines:    round(1 / float64(1+c.DueSoon+2*c.Overdue)),
+			SimilarDone:  countSimilar(target.Task, c.Completed),
+			OpenTasks:    c.OpenTasks,
+			OpenHours:    round(c.OpenHours),
+			DueSoon:      c.DueSoon,
+			Overdue:      c.Overdue,
+			Current:      target.AssigneeID != "" && c.User.ID == target.AssigneeID,
+		}
+		s.Score = round(WeightExpertise*s.Expertise + WeightAvailability*s.Availability + WeightDeadlines*s.Deadlines)
+		s.Reasons = reasons(target, c, s, neighbours)
+		out = append(out, s)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].User.Name < out[j].User.Name
+	})
+	return out
+}
+
+// withHours gives completed tasks a nominal actual_hours, which Nearest
+// requires but expertise doesn't use.
+func withHours(completed []estimation.Sample) []estimation.Sample {
+	out := make([]estimation.Sample, len(completed))
+	for i, s := range completed {
+		s.ActualHours = 1
+		out[i] = s
+	}
+	return out
+}
+
+func countSimilar(target estimation.Sample, completed []estimation.Sample) int {
+	n := 0
+	for _, s := range completed {
+		if estimation.Similarity(target, s) >= estimation.MinSimilarity {
+			n++
+		}
+	}
+	return n
+}
+
+func reasons(target Target, c Candidate, s model.AssigneeSuggestion, neighbours []model.EffortNeighbour) []string {
+	var out []string
+	if s.Current {
+		out = append(out, "Currently assigned")
+	}
+
+	if s.SimilarDone == 0 {
+		out = append(out, "Has not completed tasks similar to this one")
+	} else {
+		out = append(out, fmt.Sprintf("Completed %s similar to this one, e.g. %q", plural(s.SimilarDone, "task"), neighbours[0].Title))
+	}
+	if target.Task.Category != "" {
+		n := 0
+		for _, t := range c.Completed {
+			if t.Category == target.Task.Category {
+				n++
+			}
+		}
+		if n > 0 {
+			out = append(out, fmt.Sprintf("Completed %d %s %s", n, target.Task.Category, pluralWord(n, "task")))
+		}
+	}
+	if shared := sharedTags(target.Task.Tags, c.Completed); len(shared) > 0 {
+		out = append(out, "Completed tasks tagged "+strings.Join(shared, ", "))
+	}
+
+	switch {
+	case c.OpenTasks == 0:
+		out = append(out, "Has no open tasks")
+	case c.Unestimated > 0:
+		out = append(out, fmt.Sprintf("%s h of estimated open work across %s, %d without an estimate",
+			formatHours(c.OpenHours), plural(c.OpenTasks, "task"), c.Unestimated))
+	default:
+		out = append(out, fmt.Sprintf("%s h of estimated open work across %s", formatHours(c.OpenHours), plural(c.OpenTasks, "task")))
+	}
+
+	if c.Overdue > 0 {
+		out = append(out, plural(c.Overdue, "overdue task"))
+	}
+	if c.DueSoon > 0 {
+		when := "in the next 7 days"
+		if target.DueDate != nil {
+			when = "before this one"
+		}
+		out = append(out, fmt.Sprintf("%s due %s", plural(c.DueSoon, "task"), when))
+	}
+	return out
+}
+
+// sharedTags returns the target's tags that the completed tasks have, in
+// the target's order.
+func sharedTags(tags []string, completed []estimation.Sample) []string {
+	have := map[string]bool{}
+	for _, s := range completed {
+		for _, t := range s.Tags {
+			have[strings.ToLower(t)] = true
+		}
+	}
+	var out []string
+	for _, t := range tags {
+		if have[strings.ToLower(t)] {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func plural(n int, word string) string {
+	return strconv.Itoa(n) + " " + pluralWord(n, word)
+}
+
+func pluralWord(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+func formatHours(h float64) string {
+	return strconv.FormatFloat(math.Round(h*10)/10, 'f', -1, 64)
+}
+
+func round(x float64) float64 {
+	return math.Round(x*1000) / 1000
+}
+
+func loadTarget(ctx context.Context, taskID string) (Target, error) {
+	t := Target{Task: estimation.Sample{TaskID: taskID}}
+	var suggestedTags []string
+	var assigneeID *string
+	var dueDate *time.Time
+	err := db.Pool.QueryRow(ctx,
+		`SELECT t.title, COALESCE(t.description, ''), COALESCE(t.category, s.category, ''),
+		        ARRAY(SELECT g.name FROM task_tags tt JOIN tags g ON g.id = tt.tag_id WHERE tt.task_id = t.id ORDER BY g.name),
+		        s.tags, t.assignee_id, t.due_date
+		 FROM tasks t
+		 LEFT JOIN ai_suggestions s ON s.task_id = t.id AND s.status = 'pending'
+		 WHERE t.id = $1`, taskID,
+	).Scan(&t.Task.Title, &t.Task.Description, &t.Task.Category, &t.Task.Tags, &suggestedTags, &assigneeID, &dueDate)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return t, ErrTaskNotFound
+	}
+	if err != nil {
+		return t, fmt.Errorf("failed to get task: %w", err)
+	}
+	if len(t.Task.Tags) == 0 {
+		t.Task.Tags = suggestedTags
+	}
+	if assigneeID != nil {
+		t.AssigneeID = *assigneeID
+	}
+	if dueDate != nil && dueDate.After(time.Now()) {
+		t.DueDate = dueDate
+	}
+	return t, nil
+}
+
+// loadCandidates returns every user with their open workload and the tasks
+// they completed, except taskID. Open tasks due before horizon count as due
+// soon.
+func loadCandidates(ctx context.Context, taskID string, horizon time.Time) ([]Candidate, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT u.id, u.email, u.name, u.role, u.avatar_url, u.created_at, u.updated_at,
+		        COUNT(t.id), COALESCE(SUM(t.estimated_hours), 0)::float8,
+		        COUNT(t.id) FILTER (WHERE t.estimated_hours IS NULL),
+		        COUNT(t.id) FILTER (WHERE t.due_date >= NOW() AND t.due_date <= $2),
+		        COUNT(t.id) FILTER (WHERE t.due_date < NOW())
+		 FROM users u
+		 LEFT JOIN tasks t ON t.assignee_id = u.id AND t.status <> 'done' AND t.duplicate_of IS NULL AND t.id <> $1
+		 GROUP BY u.id
+		 ORDER BY u.name`, taskID, horizon)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []Candidate
+	byUser := map[string]int{}
+	for rows.Next() {
+		var c Candidate
+		if err := rows.Scan(&c.User.ID, &c.User.Email, &c.User.Name, &c.User.Role, &c.User.AvatarURL,
+			&c.User.CreatedAt, &c.User.UpdatedAt, &c.OpenTasks, &c.OpenHours, &c.Unestimated, &c.DueSoon, &c.Overdue); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		byUser[c.User.ID] = len(candidates)
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Pool.Query(ctx,
+		`SELECT t.assignee_id, t.id, t.title, COALESCE(t.description, ''), COALESCE(t.category, ''),
+		        ARRAY(SELECT g.name FROM task_tags tt JOIN tags g ON g.id = tt.tag_id WHERE tt.task_id = t.id ORDER BY g.name)
+		 FROM tasks t
+		 WHERE t.status = 'done' AND t.assignee_id IS NOT NULL AND t.duplicate_of IS NULL AND t.id <> $1
+		 ORDER BY t.updated_at DESC LIMIT $2`, taskID, historyLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query completed tasks: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID string
+		var s estimation.Sample
+		if err := rows.Scan(&userID, &s.TaskID, &s.Title, &s.Description, &s.Category, &s.Tags); err != nil {
+			return nil, fmt.Errorf("failed to scan completed task: %w", err)
+		}
+		if i, ok := byUser[userID]; ok {
+			candidates[i].Completed = append(candidates[i].Completed, s)
+		}
+	}
+	return candidates, rows.Err()
+}
@@ -0,0 +1,517 @@
+package classification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/llm"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+// Batch statuses.
+const (
+	BatchRunning   = "running"
+	BatchSucceeded = "succeeded"
+	BatchFailed    = "failed"
+	BatchCancelled = "cancelled"
+)
+
+// MaxBatchConcurrency caps the tasks a batch classifies in parallel.
+const MaxBatchConcurrency = 16
+
+var (
+	ErrBatchNotFound = errors.New("classification batch not found")
+	ErrInvalidBatch  = errors.New("invalid classification batch")
+	ErrBatchState    = errors.New("classification batch can't do that in its current state")
+)
+
+// BatchOptions controls how a batch runs.
+type BatchOptions struct {
+	DryRun        bool // report what would change without storing suggestions
+	Force         bool // bypass the classification cache
+	Concurrency   int  // default 4
+	RatePerMinute int  // classifications started per minute; 0 means unlimited
+}
+
+// ValidateBatch checks a batch request and fills in defaults.
+func ValidateBatch(f model.ClassificationBatchFilter, opts *BatchOptions) error {
+	if f.Category != nil && !slices.Contains(llm.Categories, *f.Category) {
+		return fmt.Errorf("%w: category must be one of %s", ErrInvalidBatch, strings.Join(llm.Categories, ", "))
+	}
+	if f.Unclassified && f.ClassifiedBefore != nil {
+		return fmt.Errorf("%w: unclassified and classified_before exclude each other", ErrInvalidBatch)
+	}
+	if f.Limit < 0 {
+		return fmt.Errorf("%w: limit must not be negative", ErrInvalidBatch)
+	}
+	if opts.Concurrency == 0 {
+		opts.Concurrency = 4
+	}
+	if opts.Concurrency < 1 || opts.Concurrency > MaxBatchConcurrency {
+		return fmt.Errorf("%w: concurrency must be between 1 and %d", ErrInvalidBatch, MaxBatchConcurrency)
+	}
+	if opts.RatePerMinute < 0 {
+		return fmt.Errorf("%w: rate_per_minute must not be negative", ErrInvalidBatch)
+	}
+	return nil
+}
+
+// batchSelection returns the query selecting the ids and positions of the
+// tasks matching f, oldest first, with its arguments.
+func batchSelection(f model.ClassificationBatchFilter) (string, []any) {
+	var where []string
+	var args []any
+	if f.Unclassified {
+		where = append(where, `t.category IS NULL AND NOT EXISTS (SELECT 1 FROM ai_suggestions s WHERE s.task_id = t.id)`)
+	}
+	if f.ClassifiedBefore != nil {
+		args = append(args, *f.ClassifiedBefore)
+		where = append(where, fmt.Sprintf(`(SELECT MAX(s.created_at) FROM ai_suggestions s WHERE s.task_id = t.id) < $%d`, len(args)))
+	}
+	if f.Category != nil {
+		args = append(args, *f.Category)
+		where = append(where, fmt.Sprintf(`t.category = $%d`, len(args)))
+	}
+
+	q := `SELECT t.id, ROW_NUMBER() OVER (ORDER BY t.created_at, t.id) AS position FROM tasks t`
+	if len(where) > 0 {
+		q += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	q += ` ORDER BY position`
+	if f.Limit > 0 {
+		q += fmt.Sprintf(` LIMIT %d`, f.Limit)
+	}
+	return q, args
+}
+
+// Changes returns the fields c would change on a task, given the task's
+// current values as read with taskFieldsQuery.
+func Changes(current map[string]*string, c *llm.TaskClassification) map[string]model.FieldChange {
+	// Current tags are sorted by name, so compare the proposed ones sorted too.
+	tags := slices.Clone(c.Tags)
+	slices.Sort(tags)
+	proposed := map[string]string{
+		"priority": c.Priority, "category": c.Category, "summary": c.Summary, "tags": strings.Join(tags, ","),
+	}
+	changes := map[string]model.FieldChange{}
+	for _, f := range Fields {
+		if old := current[f]; old == nil || *old != proposed[f] {
+			changes[f] = model.FieldChange{From: old, To: proposed[f]}
+		}
+	}
+	return changes
+}
+
+func currentFields(ctx context.Context, taskID string) (map[string]*string, error) {
+	var priority, aiTags string
+	var category, summary *string
+	if err := db.Pool.QueryRow(ctx, taskFieldsQuery, taskID).Scan(&priority, &category, &summary, &aiTags); err != nil {
+		return nil, err
+	}
+	return map[string]*string{"priority": &priority, "category": category, "summary": summary, "tags": &aiTags}, nil
+}
+
+const batchColumns = `b.id, b.requested_by, b.status, b.filter, b.dry_run, b.force, b.concurrency, b.rate_per_minute,
+	b.last_error, b.created_at, b.finished_at,
+	COUNT(i.task_id), COUNT(i.task_id) FILTER (WHERE i.status IN ('pending', 'running')),
+	COUNT(i.task_id) FILTER (WHERE i.status = 'done'), COUNT(i.task_id) FILTER (WHERE i.status = 'failed'),
+	COUNT(i.task_id) FILTER (WHERE i.status = 'done' AND i.changes <> '{}'::jsonb)`
+
+const batchFrom = ` FROM classification_batches b LEFT JOIN classification_batch_items i ON i.batch_id = b.id`
+
+func scanBatch(row pgx.Row) (*model.ClassificationBatch, error) {
+	var b model.ClassificationBatch
+	var filter []byte
+	p := &b.Progress
+	err := row.Scan(&b.ID, &b.RequestedBy, &b.Status, &filter, &b.DryRun, &b.Force, &b.Concurrency, &b.RatePerMinute,
+		&b.LastError, &b.CreatedAt, &b.FinishedAt, &p.Total, &p.Pending, &p.Done, &p.Failed, &p.Changed)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(filter, &b.Filter); err != nil {
+		return nil, fmt.Errorf("invalid batch filter: %w", err)
+	}
+	return &b, nil
+}
+
+// runner holds what batches run with; see StartBatches.
+var runner struct {
+	mu      sync.Mutex
+	ctx     context.Context
+	client  llm.LLMClient
+	running map[string]bool
+}
+
+// StartBatches resumes the batches left running by a previous process and
+// makes batches created later run with client until ctx is cancelled.
+// Replicas may run the same batch: items are claimed with SKIP LOCKED.
+func StartBatches(ctx context.Context, client llm.LLMClient) {
+	runner.mu.Lock()
+	runner.ctx, runner.client, runner.running = ctx, client, map[string]bool{}
+	runner.mu.Unlock()
+
+	rows, err := db.Pool.Query(ctx, `SELECT id FROM classification_batches WHERE status = 'running'`)
+	if err != nil {
+		log.Printf("error listing classification batches to resume: %v", err)
+		return
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		log.Printf("error listing classification batches to resume: %v", err)
+		return
+	}
+	for _, id := range ids {
+		log.Printf("Resuming classification batch %s", id)
+		launch(id)
+	}
+}
+
+// CreateBatch selects the tasks matching f and starts classifying them on
+// behalf of userID. Unless it is a dry run, every task whose classification
+// changes gets a pending suggestion, without the usual notification.
+func CreateBatch(ctx context.Context, userID string, f model.ClassificationBatchFilter, opts BatchOptions) (*model.ClassificationBatch, error) {
+	if err := ValidateBatch(f, &opts); err != nil {
+		return nil, err
+	}
+	filter, _ := json.Marshal(f)
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var id string
+	if err := tx.QueryRow(ctx,
+		`INSERT INTO classification_batches (requested_by, filter, dry_run, force, concurrency, rate_per_minute)
+		 VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		userID, filter, opts.DryRun, opts.Force, opts.Concurrency, opts.RatePerMinute,
+	).Scan(&id); err != nil {
+		return nil, fmt.Errorf("failed to create classification batch: %w", err)
+	}
+	selection, args := batchSelection(f)
+	args = append(args, id)
+	if _, err := tx.Exec(ctx,
+		fmt.Sprintf(`INSERT INTO classification_batch_items (batch_id, task_id, position)
+		             SELECT $%d::uuid, sel.id, sel.position FROM (%s) sel`, len(args), selection),
+		args...,
+	); err != nil {
+		return nil, fmt.Errorf("failed to select batch tasks: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	launch(id)
+	return GetBatch(ctx, id)
+}
+
+// GetBatch returns a batch with its progress.
+func GetBatch(ctx context.Context, id string) (*model.ClassificationBatch, error) {
+	b, err := scanBatch(db.Pool.QueryRow(ctx,
+		`SELECT `+batchColumns+batchFrom+` WHERE b.id = $1 GROUP BY b.id`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrBatchNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get classification batch: %w", err)
+	}
+	return b, nil
+}
+
+// ListBatches returns the most recent batches, newest first.
+func ListBatches(ctx context.Context, limit int) ([]model.ClassificationBatch, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT `+batchColumns+batchFrom+` GROUP BY b.id ORDER BY b.created_at DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list classification batches: %w", err)
+	}
+	defer rows.Close()
+	batches := []model.ClassificationBatch{}
+	for rows.Next() {
+		b, err := scanBatch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan classification batch: %w", err)
+		}
+		batches = append(batches, *b)
+	}
+	return batches, rows.Err()
+}
+
+// BatchItems returns the tasks of a batch in selection order, optionally only
+// those with the given status or, with changedOnly, those that would change.
+func BatchItems(ctx context.Context, id, status string, changedOnly bool) ([]model.ClassificationBatchItem, error) {
+	if _, err := GetBatch(ctx, id); err != nil {
+		return nil, err
+	}
+	rows, err := db.Pool.Query(ctx,
+		`SELECT i.task_id, t.title, i.status, i.changes, i.error, i.finished_at
+		 FROM classification_batch_items i
+		 JOIN tasks t ON t.id = i.task_id
+		 WHERE i.batch_id = $1 AND ($2 = '' OR i.status = $2)
+		   AND (NOT $3 OR (i.changes IS NOT NULL AND i.changes <> '{}'::jsonb))
+		 ORDER BY i.position`, id, status, changedOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query batch items: %w", err)
+	}
+	defer rows.Close()
+	items := []model.ClassificationBatchItem{}
+	for rows.Next() {
+		var it model.ClassificationBatchItem
+		var changes []byte
+		if err := rows.Scan(&it.TaskID, &it.Title, &it.Status, &changes, &it.Error, &it.FinishedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan batch item: %w", err)
+		}
+		if changes != nil {
+			if err := json.Unmarshal(changes, &it.Changes); err != nil {
+				return nil, fmt.Errorf("invalid batch item changes: %w", err)
+			}
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+// CancelBatch stops a running batch. Tasks already being classified finish;
+// the rest stay pending so the batch can be resumed.
+func CancelBatch(ctx context.Context, id string) (*model.ClassificationBatch, error) {
+	return transition(ctx, id,
+		`UPDATE classification_batches SET status = 'cancelled', finished_at = NOW()
+		 WHERE id = $1 AND status = 'running'`)
+}
+
+// ResumeBatch restarts a cancelled or failed batch where it stopped; tasks
+// that failed are tried again.
+func ResumeBatch(ctx context.Context, id string) (*model.ClassificationBatch, error) {
+	b, err := transition(ctx, id,
+		`UPDATE classification_batches SET status = 'running', last_error = NULL, finished_at = NULL
+		 WHERE id = $1 AND status IN ('cancelled', 'failed')`)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Pool.Exec(ctx,
+		`UPDATE classification_batch_items SET status = 'pending', error = NULL
+		 WHERE batch_id = $1 AND status = 'failed'`, id,
+	); err != nil {
+		return nil, fmt.Errorf("failed to reset failed batch items: %w", err)
+	}
+	launch(id)
+	return GetBatch(ctx, b.ID)
+}
+
+func transition(ctx context.Context, id, update string) (*model.ClassificationBatch, error) {
+	tag, err := db.Pool.Exec(ctx, update, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update classification batch: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		if _, err := GetBatch(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrBatchState
+	}
+	return GetBatch(ctx, id)
+}
+
+// launch runs the batch in the background unless this process already does.
+// Processes that never called StartBatches, like cmd/batches, run batches
+// themselves with RunBatch.
+func launch(id string) {
+	runner.mu.Lock()
+	defer runner.mu.Unlock()
+	if runner.client == nil {
+		return
+	}
+	if runner.running[id] {
+		return
+	}
+	runner.running[id] = true
+	go func() {
+		defer func() {
+			runner.mu.Lock()
+			delete(runner.running, id)
+			runner.mu.Unlock()
+		}()
+		runBatch(runner.ctx, runner.client, id)
+	}()
+}
+
+// RunBatch classifies the tasks of a running batch with client, alongside
+// any server running it, and returns once none are left or ctx is cancelled.
+func RunBatch(ctx context.Context, client llm.LLMClient, id string) {
+	runBatch(ctx, client, id)
+}
+
+func runBatch(ctx context.Context, client llm.LLMClient, id string) {
+	b, err := GetBatch(ctx, id)
+	if err != nil {
+		log.Printf("error starting classification batch %s: %v", id, err)
+		return
+	}
+	log.Printf("Classification batch %s: %d tasks, concurrency %d, %d/min", id, b.Progress.Total, b.Concurrency, b.RatePerMinute)
+
+	var tick <-chan time.Time
+	if b.RatePerMinute > 0 {
+		t := time.NewTicker(time.Minute / time.Duration(b.RatePerMinute))
+		defer t.Stop()
+		tick = t.C
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < b.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				if tick != nil {
+					select {
+					case <-tick:
+					case <-ctx.Done():
+						return
+					}
+				}
+				taskID, err := claimItem(ctx, id)
+				if errors.Is(err, pgx.ErrNoRows) && itemsHeld(ctx, id) {
+					// Tasks held by other workers, including ones that died
+					// with a previous process, become claimable once stale.
+					select {
+					case <-time.After(pollInterval):
+						continue
+					case <-ctx.Done():
+						return
+					}
+				}
+				if err != nil {
+					if !errors.Is(err, pgx.ErrNoRows) && ctx.Err() == nil {
+						log.Printf("error claiming task of classification batch %s: %v", id, err)
+					}
+					return
+				}
+				if !processItem(ctx, client, b, taskID) {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return
+	}
+
+	// Finish the batch once nothing is left, unless another replica is still
+	// classifying some of it.
+	if _, err := db.Pool.Exec(ctx,
+		`UPDATE classification_batches SET status = 'succeeded', finished_at = NOW()
+		 WHERE id = $1 AND status = 'running'
+		   AND NOT EXISTS (SELECT 1 FROM classification_batch_items
+		                   WHERE batch_id = $1 AND status IN ('pending', 'running'))`, id,
+	); err != nil {
+		log.Printf("error finishing classification batch %s: %v", id, err)
+	}
+}
+
+// claimItem takes the next pending task of a running batch, or one whose
+// worker died.
+func claimItem(ctx context.Context, batchID string) (string, error) {
+	var taskID string
+	err := db.Pool.QueryRow(ctx,
+		`UPDATE classification_batch_items
+		 SET status = 'running', started_at = NOW()
+		 WHERE (batch_id, task_id) = (
+		     SELECT i.batch_id, i.task_id FROM classification_batch_items i
+		     JOIN classification_batches b ON b.id = i.batch_id
+		     WHERE i.batch_id = $1 AND b.status = 'running'
+		       AND (i.status = 'pending' OR (i.status = 'running' AND i.started_at < $2))
+		     ORDER BY i.position
+		     FOR UPDATE OF i SKIP LOCKED
+		     LIMIT 1)
+		 RETURNING task_id`, batchID, time.Now().Add(-staleAfter),
+	).Scan(&taskID)
+	return taskID, err
+}
+
+// itemsHeld reports whether a running batch has tasks being classified.
+func itemsHeld(ctx context.Context, batchID string) bool {
+	var held bool
+	if err := db.Pool.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM classification_batch_items i
+		                JOIN classification_batches b ON b.id = i.batch_id
+		                WHERE i.batch_id = $1 AND b.status = 'running' AND i.status = 'running')`, batchID,
+	).Scan(&held); err != nil && ctx.Err() == nil {
+		log.Printf("error checking classification batch %s: %v", batchID, err)
+	}
+	return held
+}
+
+// processItem classifies one task of the batch and records the outcome. It
+// reports whether the batch should go on.
+func processItem(ctx context.Context, client llm.LLMClient, b *model.ClassificationBatch, taskID string) bool {
+	classifyCtx := ctx
+	if b.Force {
+		classifyCtx = llm.WithForceRefresh(ctx)
+	}
+	changes, err := reclassify(classifyCtx, client, b, taskID)
+	switch {
+	case errors.Is(err, ErrTaskNotFound):
+		// Deleted tasks cascade to their items.
+		return true
+	case errors.Is(err, llm.ErrBudgetExceeded):
+		// Put the task back and stop; the batch can be resumed next month
+		// or once the budget is raised.
+		_, dbErr := db.Pool.Exec(ctx,
+			`UPDATE classification_batch_items SET status = 'pending' WHERE batch_id = $1 AND task_id = $2`, b.ID, taskID)
+		if dbErr == nil {
+			_, dbErr = db.Pool.Exec(ctx,
+				`UPDATE classification_batches SET status = 'failed', last_error = $2, finished_at = NOW()
+				 WHERE id = $1 AND status = 'running'`, b.ID, err.Error())
+		}
+		if dbErr != nil {
+			log.Printf("error stopping classification batch %s: %v", b.ID, dbErr)
+		}
+		return false
+	case err != nil:
+		_, err = db.Pool.Exec(ctx,
+			`UPDATE classification_batch_items SET status = 'failed', error = $3, finished_at = NOW()
+			 WHERE batch_id = $1 AND task_id = $2`, b.ID, taskID, err.Error())
+	default:
+		encoded, _ := json.Marshal(changes)
+		_, err = db.Pool.Exec(ctx,
+			`UPDATE classification_batch_items SET status = 'done', changes = $3, error = NULL, finished_at = NOW()
+			 WHERE batch_id = $1 AND task_id = $2`, b.ID, taskID, encoded)
+	}
+	if err != nil {
+		log.Printf("error recording task %s of classification batch %s: %v", taskID, b.ID, err)
+	}
+	return true
+}
+
+func reclassify(ctx context.Context, client llm.LLMClient, b *model.ClassificationBatch, taskID string) (map[string]model.FieldChange, error) {
+	_, c, err := classify(ctx, client, taskID, b.RequestedBy)
+	if err != nil {
+		return nil, err
+	}
+	current, err := currentFields(ctx, taskID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	changes := Changes(current, c)
+	if !b.DryRun && len(changes) > 0 {
+		if _, err := suggest(ctx, taskID, b.RequestedBy, c); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,82 @@
+package classification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/llm"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+// Cache stores LLM classifications in the classification_cache table.
+type Cache struct{}
+
+var _ llm.CacheStore = Cache{}
+
+func (Cache) Get(ctx context.Context, key string) (*llm.TaskClassification, error) {
+	var result []byte
+	err := db.Pool.QueryRow(ctx,
+		`UPDATE classification_cache SET hits = hits + 1
+		 WHERE key = $1 AND expires_at > NOW()
+		 RETURNING result`, key,
+	).Scan(&result)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read classification cache: %w", err)
+	}
+	var tc llm.TaskClassification
+	if err := json.Unmarshal(result, &tc); err != nil {
+		return nil, fmt.Errorf("failed to decode cached classification: %w", err)
+	}
+	return &tc, nil
+}
+
+func (Cache) Put(ctx context.Context, key string, tc *llm.TaskClassification, ttl time.Duration) error {
+	result, err := json.Marshal(tc)
+	if err != nil {
+		return err
+	}
+	_, err = db.Pool.Exec(ctx,
+		`INSERT INTO classification_cache (key, provider, model, result, expires_at)
+		 VALUES ($1, $2, NULLIF($3, ''), $4, $5)
+		 ON CONFLICT (key) DO UPDATE
+		 SET result = EXCLUDED.result, hits = 0, created_at = NOW(), expires_at = EXCLUDED.expires_at`,
+		key, tc.Provider, tc.Model, result, time.Now().Add(ttl))
+	if err != nil {
+		return fmt.Errorf("failed to write classification cache: %w", err)
+	}
+	return nil
+}
+
+// CacheStats combines the stored entries with this replica's hit counters.
+func CacheStats(ctx context.Context) (*model.ClassificationCacheStats, error) {
+	var s model.ClassificationCacheStats
+	err := db.Pool.QueryRow(ctx,
+		`SELECT COUNT(*), COALESCE(SUM(hits), 0) FROM classification_cache WHERE expires_at > NOW()`,
+	).Scan(&s.Entries, &s.TotalHits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query classification cache: %w", err)
+	}
+	c := llm.CacheCounters()
+	s.Hits, s.Misses, s.HitRate = c.Hits, c.Misses, c.HitRate
+	return &s, nil
+}
+
+// PurgeCache deletes expired cache entries.
+func PurgeCache(ctx context.Context) error {
+	result, err := db.Pool.Exec(ctx, `DELETE FROM classification_cache WHERE expires_at <= NOW()`)
+	if err != nil {
+		return fmt.Errorf("failed to purge classification cache: %w", err)
+	}
+	log.Printf("Deleted %d expired classification cache entries", result.RowsAffected())
+	return nil
+}
@@ -0,0 +1,105 @@
+// Package classification runs AI task classification, either inline or
+// through a durable Postgres-backed job queue.
+package classification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/llm"
+	"github.com/KemenyStudio/task-manager/internal/model"
+	"github.com/KemenyStudio/task-manager/internal/notification"
+	"github.com/KemenyStudio/task-manager/internal/prompts"
+)
+
+// ErrTaskNotFound is returned when the task to classify does not exist.
+var ErrTaskNotFound = errors.New("task not found")
+
+// callTimeout bounds a classification, including the retries made by the
+// configured client.
+const callTimeout = 45 * time.Second
+
+// Classify asks the LLM to classify a task and stores the result as a pending
+// suggestion requested by userID. Nothing changes on the task until a user
+// accepts the suggestion; an older pending suggestion is superseded.
+func Classify(ctx context.Context, client llm.LLMClient, taskID, userID string) (*model.AISuggestion, error) {
+	return classifyAndSuggest(ctx, client.ClassifyTask, taskID, userID)
+}
+
+// ClassifyStream is Classify passing the model's raw output to onDelta as it
+// is generated. The suggestion is only stored once the complete answer has
+// been validated. Clients that can't stream classify in one go, without
+// calling onDelta.
+func ClassifyStream(ctx context.Context, client llm.LLMClient, taskID, userID string, onDelta func(string)) (*model.AISuggestion, error) {
+	streamer, ok := client.(llm.StreamingLLMClient)
+	if !ok {
+		return Classify(ctx, client, taskID, userID)
+	}
+	return classifyAndSuggest(ctx, func(ctx context.Context, title, description string) (*llm.TaskClassification, error) {
+		c, err := streamer.ClassifyTaskStream(ctx, title, description, onDelta)
+		if errors.Is(err, llm.ErrUnsupported) {
+			return client.ClassifyTask(ctx, title, description)
+		}
+		return c, err
+	}, taskID, userID)
+}
+
+// classifyFunc is a client's ClassifyTask or a stand-in for it.
+type classifyFunc func(ctx context.Context, title, description string) (*llm.TaskClassification, error)
+
+func classifyAndSuggest(ctx context.Context, call classifyFunc, taskID, userID string) (*model.AISuggestion, error) {
+	title, c, err := classifyWith(ctx, call, taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := suggest(ctx, taskID, userID, c)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := notification.TaskClassified(ctx, taskID, title, c.Category, c.Priority, userID); err != nil {
+		log.Printf("error notifying classification of task %s: %v", taskID, err)
+	}
+	return s, nil
+}
+
+// classify asks the LLM to classify a task without storing anything. It
+// returns the task's title along with the validated classification.
+func classify(ctx context.Context, client llm.LLMClient, taskID, userID string) (string, *llm.TaskClassification, error) {
+	return classifyWith(ctx, client.ClassifyTask, taskID, userID)
+}
+
+func classifyWith(ctx context.Context, call classifyFunc, taskID, userID string) (string, *llm.TaskClassification, error) {
+	var title, description string
+	err := db.Pool.QueryRow(ctx,
+		`SELECT title, COALESCE(description, '') FROM tasks WHERE id = $1`, taskID,
+	).Scan(&title, &description)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	prompt := prompts.ForTask(ctx, taskID)
+	callCtx, cancel := context.WithTimeout(llm.WithPromptTemplate(llm.WithCaller(ctx, taskID, userID), prompt), callTimeout)
+	defer cancel()
+	c, err := call(callCtx, title, description)
+	if err != nil {
+		return "", nil, fmt.Errorf("llm classification failed: %w", err)
+	}
+	c.PromptVersion = prompt.Version
+	// Clients are expected to validate, but mocks and wrappers may not.
+	llm.NormalizeClassification(c)
+	if err := llm.ValidateClassification(c); err != nil {
+		return "", nil, fmt.Errorf("llm classification failed: %w", err)
+	}
+	return title, c, nil
+}
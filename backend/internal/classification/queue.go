@@ -0,0 +1,214 @@
+package classification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/llm"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+// Job statuses.
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+const (
+	pollInterval = 2 * time.Second
+	// staleAfter is how long a job may stay running before another worker
+	// assumes its worker died and picks it up again.
+	staleAfter = 5 * time.Minute
+)
+
+// ErrNoJob is returned when a task has never been queued for classification.
+var ErrNoJob = errors.New("no classification job")
+
+const jobColumns = `id, task_id, requested_by, status, attempts, max_attempts, force, last_error, result,
+	run_after, created_at, started_at, finished_at`
+
+func scanJob(row pgx.Row) (*model.ClassificationJob, error) {
+	var j model.ClassificationJob
+	var result []byte
+	err := row.Scan(&j.ID, &j.TaskID, &j.RequestedBy, &j.Status, &j.Attempts, &j.MaxAttempts, &j.Force, &j.LastError, &result,
+		&j.RunAfter, &j.CreatedAt, &j.StartedAt, &j.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	j.Result = result
+	return &j, nil
+}
+
+// Backoff returns the delay before retrying a job that failed its attempt-th
+// try: 30s doubling per attempt, capped at 30 minutes.
+func Backoff(attempt int) time.Duration {
+	d := 30 * time.Second
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= 30*time.Minute {
+			return 30 * time.Minute
+		}
+	}
+	return d
+}
+
+// Enqueue queues a classification of the task. A task has at most one queued
+// job: enqueueing again while one is waiting just makes it run as soon as
+// possible on behalf of the latest requester. With force the job bypasses
+// the classification cache.
+func Enqueue(ctx context.Context, taskID, userID string, force bool) (*model.ClassificationJob, error) {
+	job, err := scanJob(db.Pool.QueryRow(ctx,
+		`INSERT INTO classification_jobs (task_id, requested_by, max_attempts, force)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (task_id) WHERE status = 'queued'
+		 DO UPDATE SET requested_by = EXCLUDED.requested_by, run_after = NOW(),
+		               force = classification_jobs.force OR EXCLUDED.force
+		 RETURNING `+jobColumns,
+		taskID, userID, maxAttempts(), force,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue classification: %w", err)
+	}
+	return job, nil
+}
+
+// LatestJob returns the most recent classification job of a task.
+func LatestJob(ctx context.Context, taskID string) (*model.ClassificationJob, error) {
+	job, err := scanJob(db.Pool.QueryRow(ctx,
+		`SELECT `+jobColumns+` FROM classification_jobs
+		 WHERE task_id = $1 ORDER BY created_at DESC LIMIT 1`, taskID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNoJob
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get classification job: %w", err)
+	}
+	return job, nil
+}
+
+// StartWorkers launches n workers that process queued jobs with client until
+// ctx is cancelled. Workers on every replica share the queue: jobs are claimed
+// with FOR UPDATE SKIP LOCKED so each one is processed once.
+func StartWorkers(ctx context.Context, client llm.LLMClient, n int) {
+	for i := 0; i < n; i++ {
+		go work(ctx, client)
+	}
+}
+
+func work(ctx context.Context, client llm.LLMClient) {
+	for {
+		job, err := claim(ctx)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) && ctx.Err() == nil {
+			log.Printf("error claiming classification job: %v", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+		process(ctx, client, job)
+	}
+}
+
+func claim(ctx context.Context) (*model.ClassificationJob, error) {
+	return scanJob(db.Pool.QueryRow(ctx,
+		`UPDATE classification_jobs
+		 SET status = 'running', attempts = attempts + 1, started_at = NOW()
+		 WHERE id = (
+		     SELECT id FROM classification_jobs
+		     WHERE (status = 'queued' AND run_after <= NOW())
+		        OR (status = 'running' AND started_at < $1)
+		     ORDER BY run_after
+		     FOR UPDATE SKIP LOCKED
+		     LIMIT 1)
+		 RETURNING `+jobColumns,
+		time.Now().Add(-staleAfter),
+	))
+}
+
+func process(ctx context.Context, client llm.LLMClient, job *model.ClassificationJob) {
+	defer func() {
+		if p := recover(); p != nil {
+			finish(ctx, job, nil, fmt.Errorf("panic: %v", p))
+		}
+	}()
+
+	classifyCtx := ctx
+	if job.Force {
+		classifyCtx = llm.WithForceRefresh(ctx)
+	}
+	s, err := Classify(classifyCtx, client, job.TaskID, job.RequestedBy)
+	if errors.Is(err, ErrTaskNotFound) {
+		// Deleted tasks cascade to their jobs; nothing left to retry.
+		return
+	}
+	finish(ctx, job, s, err)
+}
+
+// supersededPrefix starts the error of a retry dropped for a newer job.
+const supersededPrefix = "superseded by a newer job: "
+
+func finish(ctx context.Context, job *model.ClassificationJob, s *model.AISuggestion, jobErr error) {
+	var err error
+	switch {
+	case jobErr == nil:
+		result, _ := json.Marshal(s)
+		_, err = db.Pool.Exec(ctx,
+			`UPDATE classification_jobs
+			 SET status = 'succeeded', result = $2, last_error = NULL, finished_at = NOW()
+			 WHERE id = $1`, job.ID, result)
+	case job.Attempts < job.MaxAttempts:
+		log.Printf("classification of task %s failed (attempt %d/%d), retrying: %v",
+			job.TaskID, job.Attempts, job.MaxAttempts, jobErr)
+		// A newer job queued for the task meanwhile supersedes the retry. The
+		// check can race with Enqueue, in which case the unique index on
+		// queued jobs rejects the retry and it is superseded all the same.
+		_, err = db.Pool.Exec(ctx,
+			`UPDATE classification_jobs j
+			 SET status = CASE WHEN s.superseded THEN 'failed' ELSE 'queued' END,
+			     last_error = CASE WHEN s.superseded THEN $3 ELSE $2 END,
+			     finished_at = CASE WHEN s.superseded THEN NOW() END,
+			     run_after = $4
+			 FROM (SELECT EXISTS (SELECT 1 FROM classification_jobs q
+			                      WHERE q.task_id = $5 AND q.status = 'queued') AS superseded) s
+			 WHERE j.id = $1`,
+			job.ID, jobErr.Error(), supersededPrefix+jobErr.Error(), time.Now().Add(Backoff(job.Attempts)), job.TaskID)
+		if db.IsUniqueViolation(err) {
+			_, err = db.Pool.Exec(ctx,
+				`UPDATE classification_jobs
+				 SET status = 'failed', last_error = $2, finished_at = NOW()
+				 WHERE id = $1`, job.ID, supersededPrefix+jobErr.Error())
+		}
+	default:
+		log.Printf("classification of task %s failed permanently: %v", job.TaskID, jobErr)
+		_, err = db.Pool.Exec(ctx,
+			`UPDATE classification_jobs
+			 SET status = 'failed', last_error = $2, finished_at = NOW()
+			 WHERE id = $1`, job.ID, jobErr.Error())
+	}
+	if err != nil {
+		log.Printf("error recording classification job %s: %v", job.ID, err)
+	}
+}
+
+// maxAttempts reads CLASSIFICATION_MAX_ATTEMPTS (default 5).
+func maxAttempts() int {
+	if n, err := strconv.Atoi(os.Getenv("CLASSIFICATION_MAX_ATTEMPTS")); err == nil && n > 0 {
+		return n
+	}
+	return 5
+}
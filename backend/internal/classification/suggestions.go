@@ -0,0 +1,315 @@
+package classification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/llm"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+// Suggestion statuses.
+const (
+	SuggestionPending           = "pending"
+	SuggestionAccepted          = "accepted"
+	SuggestionPartiallyAccepted = "partially_accepted"
+	SuggestionRejected          = "rejected"
+	SuggestionSuperseded        = "superseded"
+)
+
+// Fields lists the task fields a suggestion proposes, in display order.
+var Fields = []string{"priority", "category", "summary", "tags"}
+
+var (
+	ErrSuggestionNotFound = errors.New("suggestion not found")
+	ErrNotPending         = errors.New("suggestion already decided")
+	ErrInvalidField       = errors.New("invalid suggestion field")
+)
+
+const suggestionColumns = `id, task_id, requested_by, status, priority, category, summary, tags, provider, model,
+	prompt_version, reject_reason, decided_by, decided_at, created_at`
+
+// taskFieldsQuery selects a task's current priority, category, summary and
+// comma-separated AI-assigned tags, the values a suggestion would replace.
+const taskFieldsQuery = `SELECT t.priority, t.category, t.summary,
+	        COALESCE((SELECT STRING_AGG(g.name, ',' ORDER BY g.name) FROM task_tags tt
+	                  JOIN tags g ON g.id = tt.tag_id
+	                  WHERE tt.task_id = t.id AND tt.assigned_by = 'ai'), '')
+	 FROM tasks t WHERE t.id = $1`
+
+func scanSuggestion(row pgx.Row) (*model.AISuggestion, error) {
+	var s model.AISuggestion
+	err := row.Scan(&s.ID, &s.TaskID, &s.RequestedBy, &s.Status, &s.Priority, &s.Category, &s.Summary, &s.Tags, &s.Provider, &s.Model,
+		&s.PromptVersion, &s.RejectReason, &s.DecidedBy, &s.DecidedAt, &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// suggest stores c as the task's pending suggestion, superseding any older one.
+func suggest(ctx context.Context, taskID, userID string, c *llm.TaskClassification) (*model.AISuggestion, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx,
+		`UPDATE ai_suggestions SET status = 'superseded' WHERE task_id = $1 AND status = 'pending'`, taskID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to supersede suggestions: %w", err)
+	}
+	s, err := scanSuggestion(tx.QueryRow(ctx,
+		`INSERT INTO ai_suggestions (task_id, requested_by, priority, category, summary, tags, provider, model, prompt_version)
+		 VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''))
+		 RETURNING `+suggestionColumns,
+		taskID, userID, c.Priority, c.Category, c.Summary, c.Tags, c.Provider, c.Model, c.PromptVersion,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to store suggestion: %w", err)
+	}
+	return s, tx.Commit(ctx)
+}
+
+// PendingSuggestion returns the task's pending suggestion, or nil if there is none.
+func PendingSuggestion(ctx context.Context, taskID string) (*model.AISuggestion, error) {
+	s, err := scanSuggestion(db.Pool.QueryRow(ctx,
+		`SELECT `+suggestionColumns+` FROM ai_suggestions WHERE task_id = $1 AND status = 'pending'`, taskID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending suggestion: %w", err)
+	}
+	return s, nil
+}
+
+// ListSuggestions returns every suggestion made for a task with its per-field
+// decisions, newest first.
+func ListSuggestions(ctx context.Context, taskID string) ([]model.AISuggestion, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT `+suggestionColumns+` FROM ai_suggestions WHERE task_id = $1 ORDER BY created_at DESC`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query suggestions: %w", err)
+	}
+	suggestions := []model.AISuggestion{}
+	index := map[string]int{}
+	for rows.Next() {
+		s, err := scanSuggestion(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan suggestion: %w", err)
+		}
+		index[s.ID] = len(suggestions)
+		suggestions = append(suggestions, *s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Pool.Query(ctx,
+		`SELECT d.suggestion_id, d.field, d.suggested_value, d.previous_value, d.accepted, d.reason, d.decided_by, d.decided_at
+		 FROM ai_suggestion_decisions d
+		 JOIN ai_suggestions s ON s.id = d.suggestion_id
+		 WHERE s.task_id = $1`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query suggestion decisions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var d model.AISuggestionDecision
+		if err := rows.Scan(&id, &d.Field, &d.SuggestedValue, &d.PreviousValue, &d.Accepted, &d.Reason, &d.DecidedBy, &d.DecidedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan suggestion decision: %w", err)
+		}
+		if i, ok := index[id]; ok {
+			suggestions[i].Decisions = append(suggestions[i].Decisions, d)
+		}
+	}
+	return suggestions, rows.Err()
+}
+
+// SelectedFields validates the fields a user accepts; none means all of them.
+func SelectedFields(fields []string) (map[string]bool, error) {
+	selected := map[string]bool{}
+	for _, f := range fields {
+		valid := false
+		for _, known := range Fields {
+			valid = valid || f == known
+		}
+		if !valid {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidField, f)
+		}
+		selected[f] = true
+	}
+	if len(selected) == 0 {
+		for _, f := range Fields {
+			selected[f] = true
+		}
+	}
+	return selected, nil
+}
+
+// Accept applies the selected fields of a pending suggestion to its task
+// (all fields when none are given) and records a decision per field; fields
+// left out count as rejected.
+func Accept(ctx context.Context, id, userID string, fields []string) (*model.AISuggestion, error) {
+	selected, err := SelectedFields(fields)
+	if err != nil {
+		return nil, err
+	}
+	status := SuggestionAccepted
+	if len(selected) < len(Fields) {
+		status = SuggestionPartiallyAccepted
+	}
+	return decide(ctx, id, userID, status, selected, nil)
+}
+
+// Reject records that the suggestion was rejected as a whole, and why.
+func Reject(ctx context.Context, id, userID, reason string) (*model.AISuggestion, error) {
+	return decide(ctx, id, userID, SuggestionRejected, map[string]bool{}, &reason)
+}
+
+func decide(ctx context.Context, id, userID, status string, accepted map[string]bool, reason *string) (*model.AISuggestion, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	s, err := scanSuggestion(tx.QueryRow(ctx,
+		`SELECT `+suggestionColumns+` FROM ai_suggestions WHERE id = $1 FOR UPDATE`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSuggestionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get suggestion: %w", err)
+	}
+	if s.Status != SuggestionPending {
+		return nil, ErrNotPending
+	}
+
+	var priority string
+	var category, summary *string
+	var aiTags string
+	if err := tx.QueryRow(ctx, taskFieldsQuery+` FOR UPDATE`, s.TaskID).Scan(&priority, &category, &summary, &aiTags); err != nil {
+		return nil, fmt.Errorf("failed to lock task: %w", err)
+	}
+
+	previous := map[string]*string{"priority": &priority, "category": category, "summary": summary, "tags": &aiTags}
+	suggested := map[string]string{
+		"priority": s.Priority, "category": s.Category, "summary": s.Summary, "tags": strings.Join(s.Tags, ","),
+	}
+
+	for _, field := range Fields {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO ai_suggestion_decisions (suggestion_id, field, suggested_value, previous_value, accepted, reason, decided_by)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			s.ID, field, suggested[field], previous[field], accepted[field], reason, userID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to record decision for %s: %w", field, err)
+		}
+		if !accepted[field] {
+			continue
+		}
+		value := suggested[field]
+		if field == "tags" {
+			if err := replaceAITags(ctx, tx, s.TaskID, s.Tags); err != nil {
+				return nil, err
+			}
+			// In the history, as in previous, tags are sorted by name
+			value = strings.Join(slices.Sorted(slices.Values(s.Tags)), ",")
+		} else if _, err := tx.Exec(ctx,
+			// field comes from the fixed Fields list, never from user input
+			`UPDATE tasks SET `+field+` = $1, updated_at = NOW() WHERE id = $2`, value, s.TaskID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to update task %s: %w", field, err)
+		}
+		if old := previous[field]; old == nil || *old != value {
+			if _, err := tx.Exec(ctx,
+				`INSERT INTO edit_history (task_id, user_id, field_name, old_value, new_value) VALUES ($1, $2, $3, $4, $5)`,
+				s.TaskID, userID, field, old, value,
+			); err != nil {
+				return nil, fmt.Errorf("failed to record edit history: %w", err)
+			}
+		}
+	}
+
+	decided, err := scanSuggestion(tx.QueryRow(ctx,
+		`UPDATE ai_suggestions SET status = $2, reject_reason = $3, decided_by = $4, decided_at = NOW()
+		 WHERE id = $1
+		 RETURNING `+suggestionColumns,
+		s.ID, status, reason, userID,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update suggestion: %w", err)
+	}
+	return decided, tx.Commit(ctx)
+}
+
+// replaceAITags swaps the task's AI-assigned tags for tags, creating missing ones.
+func replaceAITags(ctx context.Context, tx pgx.Tx, taskID string, tags []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM task_tags WHERE task_id = $1 AND assigned_by = 'ai'`, taskID); err != nil {
+		return fmt.Errorf("failed to delete old task_tags: %w", err)
+	}
+	for _, name := range tags {
+		var tagID string
+		if err := tx.QueryRow(ctx,
+			`INSERT INTO tags (name, color) VALUES ($1, '#6B7280')
+			 ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+			 RETURNING id`, name,
+		).Scan(&tagID); err != nil {
+			return fmt.Errorf("failed to get tag id: %w", err)
+		}
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO task_tags (task_id, tag_id, assigned_by) VALUES ($1, $2, 'ai') ON CONFLICT DO NOTHING`,
+			taskID, tagID,
+		); err != nil {
+			return fmt.Errorf("failed to insert task_tag: %w", err)
+		}
+	}
+	return nil
+}
+
+// Stats returns per-field acceptance counts over every decided suggestion.
+func Stats(ctx context.Context) ([]model.SuggestionFieldStats, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT field, COUNT(*), COUNT(*) FILTER (WHERE accepted)
+		 FROM ai_suggestion_decisions GROUP BY field`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query suggestion stats: %w", err)
+	}
+	defer rows.Close()
+
+	byField := map[string]model.SuggestionFieldStats{}
+	for rows.Next() {
+		var s model.SuggestionFieldStats
+		if err := rows.Scan(&s.Field, &s.Decided, &s.Accepted); err != nil {
+			return nil, fmt.Errorf("failed to scan suggestion stats: %w", err)
+		}
+		byField[s.Field] = s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stats := make([]model.SuggestionFieldStats, 0, len(Fields))
+	for _, f := range Fields {
+		s := byField[f]
+		s.Field = f
+		s.Rejected = s.Decided - s.Accepted
+		if s.Decided > 0 {
+			s.AcceptanceRate = float64(s.Accepted) / float64(s.Decided)
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
@@ -0,0 +1,231 @@
+// Package duplicates finds tasks that describe the same work, by comparing
+// embeddings of their text, and merges them.
+package duplicates
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/llm"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+// DefaultThreshold is the similarity above which a task is reported as a
+// likely duplicate when DUPLICATE_THRESHOLD isn't set.
+const DefaultThreshold = 0.85
+
+const (
+	// MaxCandidates caps the likely duplicates returned for a new task.
+	MaxCandidates = 5
+	// candidatesTimeout bounds the duplicate check of a new task, which the
+	// client waits for.
+	candidatesTimeout = 2 * time.Second
+	// backfillBatch is how many tasks the backfill job embeds per call, and
+	// backfillMax how many per run.
+	backfillBatch = 64
+	backfillMax   = 5000
+	// callTimeout bounds an embedding call, including retries.
+	callTimeout = 15 * time.Second
+)
+
+var (
+	// ErrTaskNotFound is returned when a task to compare or merge does not
+	// exist.
+	ErrTaskNotFound = errors.New("task not found")
+	// ErrInvalidMerge is returned for merges that would lose or loop tasks.
+	ErrInvalidMerge = errors.New("invalid merge")
+)
+
+// Threshold returns DUPLICATE_THRESHOLD, or DefaultThreshold when unset or
+// invalid.
+func Threshold() float64 {
+	if v := os.Getenv("DUPLICATE_THRESHOLD"); v != "" {
+		if t, err := strconv.ParseFloat(v, 64); err == nil && t > 0 && t <= 1 {
+			return t
+		}
+		log.Printf("Invalid DUPLICATE_THRESHOLD %q, using %v", v, DefaultThreshold)
+	}
+	return DefaultThreshold
+}
+
+// Text is what is embedded for a task.
+func Text(title, description string) string {
+	return title + "\n\n" + description
+}
+
+// ContentHash identifies a task's embedded text; textHashSQL computes the
+// same in Postgres.
+func ContentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+const textHashSQL = `encode(sha256(convert_to(t.title || E'\n\n' || COALESCE(t.description, ''), 'UTF8')), 'hex')`
+
+type vector struct {
+	model string
+	v     []float32
+}
+
+// index returns the task's embedding, embedding it first when it has none
+// or its text changed since.
+func index(ctx context.Context, embedder llm.Embedder, taskID string) (*vector, error) {
+	var title, description string
+	var storedModel *string
+	var storedVec []float32
+	err := db.Pool.QueryRow(ctx,
+		`SELECT t.title, COALESCE(t.description, ''), e.model, e.embedding
+		 FROM tasks t
+		 LEFT JOIN task_embeddings e ON e.task_id = t.id AND e.content_hash = `+textHashSQL+`
+		 WHERE t.id = $1`, taskID,
+	).Scan(&title, &description, &storedModel, &storedVec)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	if storedModel != nil {
+		return &vector{model: *storedModel, v: storedVec}, nil
+	}
+
+	text := Text(title, description)
+	callCtx, cancel := context.WithTimeout(ctx, callTimeout)
+	defer cancel()
+	e, err := embedder.Embed(callCtx, []string{text})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed task: %w", err)
+	}
+	v := &vector{model: e.Model, v: e.Vectors[0]}
+	if err := store(ctx, taskID, ContentHash(text), v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func store(ctx context.Context, taskID, hash string, v *vector) error {
+	_, err := db.Pool.Exec(ctx,
+		`INSERT INTO task_embeddings (task_id, model, content_hash, embedding) VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (task_id) DO UPDATE
+		 SET model = EXCLUDED.model, content_hash = EXCLUDED.content_hash, embedding = EXCLUDED.embedding, updated_at = NOW()`,
+		taskID, v.model, hash, v.v)
+	if err != nil {
+		return fmt.Errorf("failed to store task embedding: %w", err)
+	}
+	return nil
+}
+
+// Similar returns up to limit tasks whose text is at least minScore similar
+// to the task's, most similar first. Tasks merged into another are left out.
+func Similar(ctx context.Context, embedder llm.Embedder, taskID string, limit int, minScore float64) ([]model.SimilarTask, error) {
+	v, err := index(ctx, embedder, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Vectors are unit length, so the dot product is the cosine similarity.
+	rows, err := db.Pool.Query(ctx,
+		`SELECT t.id, t.title, t.status, s.score
+		 FROM (SELECT e.task_id, (SELECT SUM(a * b) FROM unnest(e.embedding, $1::real[]) AS v(a, b))::float8 AS score
+		       FROM task_embeddings e WHERE e.model = $2 AND e.task_id <> $3) s
+		 JOIN tasks t ON t.id = s.task_id
+		 WHERE s.score >= $4 AND t.duplicate_of IS NULL
+		 ORDER BY s.score DESC LIMIT $5`, v.v, v.model, taskID, minScore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query similar tasks: %w", err)
+	}
+	defer rows.Close()
+
+	similar := []model.SimilarTask{}
+	for rows.Next() {
+		var s model.SimilarTask
+		if err := rows.Scan(&s.ID, &s.Title, &s.Status, &s.Score); err != nil {
+			return nil, fmt.Errorf("failed to scan similar task: %w", err)
+		}
+		similar = append(similar, s)
+	}
+	return similar, rows.Err()
+}
+
+// Candidates returns the likely duplicates of a task: up to MaxCandidates
+// tasks above Threshold. It gives up after candidatesTimeout; a task whose
+// embedding wasn't stored by then is embedded by the backfill job.
+func Candidates(ctx context.Context, embedder llm.Embedder, taskID string) ([]model.SimilarTask, error) {
+	ctx, cancel := context.WithTimeout(ctx, candidatesTimeout)
+	defer cancel()
+	return Similar(ctx, embedder, taskID, MaxCandidates, Threshold())
+}
+
+// Backfill embeds tasks that have no embedding yet, whose text changed, or
+// that were embedded with another model than the embedder's, in batches.
+func Backfill(ctx context.Context, embedder llm.Embedder) error {
+	if embedder == nil {
+		return nil
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, callTimeout)
+	probe, err := embedder.Embed(probeCtx, []string{"probe"})
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to probe embedding model: %w", err)
+	}
+
+	embedded := 0
+	for {
+		rows, err := db.Pool.Query(ctx,
+			`SELECT t.id, t.title, COALESCE(t.description, '')
+			 FROM tasks t
+			 LEFT JOIN task_embeddings e ON e.task_id = t.id
+			 WHERE e.task_id IS NULL OR e.model <> $1 OR e.content_hash <> `+textHashSQL+`
+			 ORDER BY t.created_at DESC LIMIT $2`, probe.Model, backfillBatch)
+		if err != nil {
+			return fmt.Errorf("failed to query tasks to embed: %w", err)
+		}
+		var ids, texts []string
+		for rows.Next() {
+			var id, title, description string
+			if err := rows.Scan(&id, &title, &description); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan task to embed: %w", err)
+			}
+			ids, texts = append(ids, id), append(texts, Text(title, description))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		callCtx, cancel := context.WithTimeout(ctx, callTimeout)
+		e, err := embedder.Embed(callCtx, texts)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to embed tasks: %w", err)
+		}
+		if e.Model != probe.Model {
+			return fmt.Errorf("embedding model changed from %s to %s during backfill", probe.Model, e.Model)
+		}
+		for i, id := range ids {
+			if err := store(ctx, id, ContentHash(texts[i]), &vector{model: e.Model, v: e.Vectors[i]}); err != nil {
+				return err
+			}
+		}
+		embedded += len(ids)
+		if len(ids) < backfillBatch || embedded >= backfillMax {
+			break
+		}
+	}
+	log.Printf("Embedded %d tasks with %s", embedded, probe.Model)
+	return nil
+}
@@ -0,0 +1,96 @@
+package duplicates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/db"
+)
+
+// Merge folds duplicateIDs into targetID: their watchers and tags move to
+// the target, and each duplicate is marked done with duplicate_of set rather
+// than deleted, so its history stays readable. Tasks that were merged into a
+// duplicate now point at the target. Either every duplicate is merged or
+// none is.
+func Merge(ctx context.Context, targetID string, duplicateIDs []string, userID string) error {
+	if len(duplicateIDs) == 0 {
+		return fmt.Errorf("%w: duplicate_ids is required", ErrInvalidMerge)
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin merge: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var targetDuplicateOf *string
+	err = tx.QueryRow(ctx, `SELECT duplicate_of FROM tasks WHERE id = $1 FOR UPDATE`, targetID).Scan(&targetDuplicateOf)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrTaskNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get task: %w", err)
+	}
+	if targetDuplicateOf != nil {
+		return fmt.Errorf("%w: task was already merged into %s", ErrInvalidMerge, *targetDuplicateOf)
+	}
+
+	seen := map[string]bool{}
+	for _, id := range duplicateIDs {
+		if id == targetID {
+			return fmt.Errorf("%w: a task can't be merged into itself", ErrInvalidMerge)
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		var status string
+		var duplicateOf *string
+		err := tx.QueryRow(ctx, `SELECT status, duplicate_of FROM tasks WHERE id = $1 FOR UPDATE`, id).Scan(&status, &duplicateOf)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w: %s", ErrTaskNotFound, id)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get task: %w", err)
+		}
+		if duplicateOf != nil {
+			return fmt.Errorf("%w: task %s was already merged into %s", ErrInvalidMerge, id, *duplicateOf)
+		}
+
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO task_watchers (task_id, user_id, in_app, email)
+			 SELECT $1, user_id, in_app, email FROM task_watchers WHERE task_id = $2
+			 ON CONFLICT (task_id, user_id) DO NOTHING`, targetID, id); err != nil {
+			return fmt.Errorf("failed to move watchers: %w", err)
+		}
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO task_tags (task_id, tag_id, assigned_by)
+			 SELECT $1, tag_id, assigned_by FROM task_tags WHERE task_id = $2
+			 ON CONFLICT (task_id, tag_id) DO NOTHING`, targetID, id); err != nil {
+			return fmt.Errorf("failed to move tags: %w", err)
+		}
+		if _, err := tx.Exec(ctx,
+			`UPDATE tasks SET duplicate_of = $1, updated_at = NOW() WHERE duplicate_of = $2`, targetID, id); err != nil {
+			return fmt.Errorf("failed to repoint merged tasks: %w", err)
+		}
+		if _, err := tx.Exec(ctx,
+			`UPDATE tasks SET duplicate_of = $1, status = 'done', updated_at = NOW() WHERE id = $2`, targetID, id); err != nil {
+			return fmt.Errorf("failed to mark duplicate: %w", err)
+		}
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO edit_history (task_id, user_id, field_name, old_value, new_value)
+			 VALUES ($1, $2, 'duplicate_of', NULL, $3), ($1, $2, 'status', $4, 'done')`,
+			id, userID, targetID, status); err != nil {
+			return fmt.Errorf("failed to record merge: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit merge: %w", err)
+	}
+	return nil
+}
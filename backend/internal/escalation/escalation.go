@@ -0,0 +1,400 @@
+// Package escalation evaluates configurable rules that flag overdue or stale
+// tasks and notifies the people responsible until someone acknowledges it.
+package escalation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/model"
+	"github.com/KemenyStudio/task-manager/internal/notification"
+)
+
+// Rule conditions.
+const (
+	ConditionOverdue     = "overdue"
+	ConditionStaleStatus = "stale_status"
+)
+
+// Notification targets.
+const (
+	TargetAssignee = "assignee"
+	TargetCreator  = "creator"
+	TargetWatchers = "watchers"
+	TargetAdmins   = "admins"
+)
+
+// ErrNotFound is returned when a rule or log entry does not exist.
+var ErrNotFound = errors.New("not found")
+
+var (
+	validStatuses   = map[string]bool{"todo": true, "in_progress": true, "review": true, "done": true}
+	validPriorities = map[string]bool{"low": true, "medium": true, "high": true, "urgent": true}
+	validTargets    = map[string]bool{TargetAssignee: true, TargetCreator: true, TargetWatchers: true, TargetAdmins: true}
+)
+
+// ValidateRule checks a rule before it is stored.
+func ValidateRule(r model.EscalationRule) error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	switch r.Condition {
+	case ConditionOverdue:
+		if r.Status != nil {
+			return errors.New("status only applies to stale_status rules")
+		}
+	case ConditionStaleStatus:
+		if r.Status == nil || !validStatuses[*r.Status] {
+			return errors.New("stale_status rules need a valid status")
+		}
+		if *r.Status == "done" {
+			return errors.New("done tasks cannot be stale")
+		}
+	default:
+		return errors.New("condition must be overdue or stale_status")
+	}
+	if r.Priority != nil && !validPriorities[*r.Priority] {
+		return errors.New("invalid priority")
+	}
+	if r.ThresholdMinutes < 1 {
+		return errors.New("threshold_minutes must be positive")
+	}
+	if len(r.Notify) == 0 {
+		return errors.New("notify needs at least one target")
+	}
+	for _, t := range r.Notify {
+		if !validTargets[t] {
+			return fmt.Errorf("invalid notify target %q", t)
+		}
+	}
+	if r.RepeatMinutes != nil && *r.RepeatMinutes < 1 {
+		return errors.New("repeat_minutes must be positive")
+	}
+	return nil
+}
+
+// ShouldNotify reports whether an open escalation should notify again at now.
+// A new escalation (entry == nil) always notifies; acknowledged ones never do;
+// otherwise only rules with repeat_minutes re-notify once the interval passed.
+func ShouldNotify(rule model.EscalationRule, entry *model.EscalationLog, now time.Time) bool {
+	if entry == nil {
+		return true
+	}
+	if entry.AcknowledgedAt != nil || rule.RepeatMinutes == nil {
+		return false
+	}
+	return now.Sub(entry.LastNotifiedAt) >= time.Duration(*rule.RepeatMinutes)*time.Minute
+}
+
+const ruleColumns = `id, name, condition, priority, status, threshold_minutes, notify, repeat_minutes, enabled, created_at, updated_at`
+
+func scanRule(row pgx.Row) (model.EscalationRule, error) {
+	var r model.EscalationRule
+	err := row.Scan(&r.ID, &r.Name, &r.Condition, &r.Priority, &r.Status, &r.ThresholdMinutes,
+		&r.Notify, &r.RepeatMinutes, &r.Enabled, &r.CreatedAt, &r.UpdatedAt)
+	return r, err
+}
+
+// ListRules returns every escalation rule, oldest first.
+func ListRules(ctx context.Context, enabledOnly bool) ([]model.EscalationRule, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT `+ruleColumns+` FROM escalation_rules
+		 WHERE ($1 = FALSE OR enabled) ORDER BY created_at`, enabledOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query escalation rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []model.EscalationRule{}
+	for rows.Next() {
+		r, err := scanRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan escalation rule: %w", err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// CreateRule stores a new rule and returns it.
+func CreateRule(ctx context.Context, r model.EscalationRule) (model.EscalationRule, error) {
+	created, err := scanRule(db.Pool.QueryRow(ctx,
+		`INSERT INTO escalation_rules (name, condition, priority, status, threshold_minutes, notify, repeat_minutes, enabled)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 RETURNING `+ruleColumns,
+		r.Name, r.Condition, r.Priority, r.Status, r.ThresholdMinutes, r.Notify, r.RepeatMinutes, r.Enabled,
+	))
+	if err != nil {
+		return created, fmt.Errorf("failed to create escalation rule: %w", err)
+	}
+	return created, nil
+}
+
+// UpdateRule replaces a rule's settings. Open escalations keep their state.
+func UpdateRule(ctx context.Context, id string, r model.EscalationRule) (model.EscalationRule, error) {
+	updated, err := scanRule(db.Pool.QueryRow(ctx,
+		`UPDATE escalation_rules
+		 SET name = $2, condition = $3, priority = $4, status = $5, threshold_minutes = $6,
+		     notify = $7, repeat_minutes = $8, enabled = $9, updated_at = NOW()
+		 WHERE id = $1
+		 RETURNING `+ruleColumns,
+		id, r.Name, r.Condition, r.Priority, r.Status, r.ThresholdMinutes, r.Notify, r.RepeatMinutes, r.Enabled,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return updated, ErrNotFound
+	}
+	if err != nil {
+		return updated, fmt.Errorf("failed to update escalation rule: %w", err)
+	}
+	return updated, nil
+}
+
+// DeleteRule removes a rule along with its escalation log.
+func DeleteRule(ctx context.Context, id string) error {
+	result, err := db.Pool.Exec(ctx, `DELETE FROM escalation_rules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete escalation rule: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListTaskEscalations returns a task's escalation log, newest first.
+func ListTaskEscalations(ctx context.Context, taskID string) ([]model.EscalationLog, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT l.id, l.rule_id, r.name, l.task_id, l.notified_user_ids::text[], l.notify_count,
+		        l.triggered_at, l.last_notified_at, l.acknowledged_at, l.acknowledged_by, l.resolved_at
+		 FROM escalation_log l
+		 JOIN escalation_rules r ON r.id = l.rule_id
+		 WHERE l.task_id = $1
+		 ORDER BY l.triggered_at DESC`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query escalations: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []model.EscalationLog{}
+	for rows.Next() {
+		var e model.EscalationLog
+		if err := rows.Scan(&e.ID, &e.RuleID, &e.RuleName, &e.TaskID, &e.NotifiedUserIDs, &e.NotifyCount,
+			&e.TriggeredAt, &e.LastNotifiedAt, &e.AcknowledgedAt, &e.AcknowledgedBy, &e.ResolvedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan escalation: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// Acknowledge marks an escalation as handled, which suppresses further
+// notifications for it until the task stops and starts matching again.
+func Acknowledge(ctx context.Context, id, userID string) error {
+	result, err := db.Pool.Exec(ctx,
+		`UPDATE escalation_log
+		 SET acknowledged_at = COALESCE(acknowledged_at, NOW()),
+		     acknowledged_by = COALESCE(acknowledged_by, $2)
+		 WHERE id = $1`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to acknowledge escalation: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Evaluate runs every enabled rule: tasks newly matching a rule are escalated,
+// open escalations re-notify per repeat_minutes unless acknowledged, and
+// escalations whose task no longer matches are resolved.
+func Evaluate(ctx context.Context) error {
+	rules, err := ListRules(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	escalated := 0
+	for _, rule := range rules {
+		n, err := evaluateRule(ctx, rule)
+		if err != nil {
+			log.Printf("error evaluating escalation rule %q: %v", rule.Name, err)
+			continue
+		}
+		escalated += n
+	}
+	log.Printf("Sent %d escalations", escalated)
+	return nil
+}
+
+type matchedTask struct {
+	id, title  string
+	creatorID  string
+	assigneeID *string
+}
+
+func evaluateRule(ctx context.Context, rule model.EscalationRule) (int, error) {
+	tasks, err := matchingTasks(ctx, rule)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	sent := 0
+	matchedIDs := make([]string, 0, len(tasks))
+	for _, t := range tasks {
+		matchedIDs = append(matchedIDs, t.id)
+
+		entry, err := openEntry(ctx, rule.ID, t.id)
+		if err != nil {
+			return sent, err
+		}
+		if !ShouldNotify(rule, entry, now) {
+			continue
+		}
+
+		recipients, err := resolveTargets(ctx, rule.Notify, t)
+		if err != nil {
+			return sent, err
+		}
+		if entry == nil {
+			_, err = db.Pool.Exec(ctx,
+				`INSERT INTO escalation_log (rule_id, task_id, notified_user_ids, triggered_at, last_notified_at)
+				 VALUES ($1, $2, $3, $4, $4)
+				 ON CONFLICT DO NOTHING`,
+				rule.ID, t.id, recipients, now)
+		} else {
+			_, err = db.Pool.Exec(ctx,
+				`UPDATE escalation_log
+				 SET notified_user_ids = $2, notify_count = notify_count + 1, last_notified_at = $3
+				 WHERE id = $1`,
+				entry.ID, recipients, now)
+		}
+		if err != nil {
+			return sent, fmt.Errorf("failed to record escalation: %w", err)
+		}
+
+		if err := notification.TaskEscalated(ctx, notification.TaskEvent{
+			TaskID:    t.id,
+			TaskTitle: t.title,
+			Title:     "Escalation: " + t.title,
+			Body:      rule.Name,
+			Reason:    fmt.Sprintf("You receive this because of the escalation rule %q.", rule.Name),
+		}, recipients); err != nil {
+			log.Printf("error notifying escalation of task %s: %v", t.id, err)
+		}
+		sent++
+	}
+
+	if _, err := db.Pool.Exec(ctx,
+		`UPDATE escalation_log SET resolved_at = NOW()
+		 WHERE rule_id = $1 AND resolved_at IS NULL AND task_id::text <> ALL($2)`,
+		rule.ID, matchedIDs,
+	); err != nil {
+		return sent, fmt.Errorf("failed to resolve escalations: %w", err)
+	}
+	return sent, nil
+}
+
+// matchingTasks returns the tasks currently matching rule. A task's time in
+// its status is measured from the last edit that set it, or from creation.
+func matchingTasks(ctx context.Context, rule model.EscalationRule) ([]matchedTask, error) {
+	threshold := time.Now().Add(-time.Duration(rule.ThresholdMinutes) * time.Minute)
+
+	var query string
+	args := []interface{}{rule.Priority, threshold}
+	switch rule.Condition {
+	case ConditionOverdue:
+		query = `SELECT t.id, t.title, t.creator_id, t.assignee_id FROM tasks t
+		         WHERE t.status != 'done' AND t.due_date < $2
+		           AND ($1::text IS NULL OR t.priority = $1)`
+	case ConditionStaleStatus:
+		query = `SELECT t.id, t.title, t.creator_id, t.assignee_id FROM tasks t
+		         WHERE t.status = $3
+		           AND ($1::text IS NULL OR t.priority = $1)
+		           AND COALESCE((SELECT MAX(h.edited_at) FROM edit_history h
+		                         WHERE h.task_id = t.id AND h.field_name = 'status' AND h.new_value = t.status),
+		                        t.created_at) < $2`
+		args = append(args, rule.Status)
+	default:
+		return nil, fmt.Errorf("unknown condition %q", rule.Condition)
+	}
+
+	rows, err := db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query matching tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []matchedTask
+	for rows.Next() {
+		var t matchedTask
+		if err := rows.Scan(&t.id, &t.title, &t.creatorID, &t.assigneeID); err != nil {
+			return nil, fmt.Errorf("failed to scan matching task: %w", err)
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+func openEntry(ctx context.Context, ruleID, taskID string) (*model.EscalationLog, error) {
+	var e model.EscalationLog
+	err := db.Pool.QueryRow(ctx,
+		`SELECT id, last_notified_at, acknowledged_at FROM escalation_log
+		 WHERE rule_id = $1 AND task_id = $2 AND resolved_at IS NULL`, ruleID, taskID,
+	).Scan(&e.ID, &e.LastNotifiedAt, &e.AcknowledgedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load escalation: %w", err)
+	}
+	return &e, nil
+}
+
+func resolveTargets(ctx context.Context, targets []string, t matchedTask) ([]string, error) {
+	var ids []string
+	seen := map[string]bool{}
+	add := func(id string) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	for _, target := range targets {
+		switch target {
+		case TargetAssignee:
+			if t.assigneeID != nil {
+				add(*t.assigneeID)
+			}
+		case TargetCreator:
+			add(t.creatorID)
+		case TargetWatchers, TargetAdmins:
+			query := `SELECT user_id FROM task_watchers WHERE task_id = $1`
+			args := []interface{}{t.id}
+			if target == TargetAdmins {
+				query, args = `SELECT id FROM users WHERE role = 'admin'`, nil
+			}
+			rows, err := db.Pool.Query(ctx, query, args...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve %s: %w", target, err)
+			}
+			for rows.Next() {
+				var id string
+				if err := rows.Scan(&id); err != nil {
+					rows.Close()
+					return nil, fmt.Errorf("failed to scan %s: %w", target, err)
+				}
+				add(id)
+			}
+			rows.Close()
+		}
+	}
+	return ids, nil
+}
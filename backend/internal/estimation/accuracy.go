@@ -0,0 +1,64 @@
+package estimation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+// ErrInvalidGroupBy is returned for an unknown accuracy report period.
+var ErrInvalidGroupBy = errors.New("group_by must be week or month")
+
+// Accuracy compares estimates with the actual hours of tasks done in
+// [from, to), per week or month. A task is done when its status last became
+// "done". Human estimates are the tasks' estimated_hours; for AI estimates,
+// the last one made before the task was done counts.
+func Accuracy(ctx context.Context, from, to time.Time, groupBy string) (*model.EffortAccuracyReport, error) {
+	if groupBy != "week" && groupBy != "month" {
+		return nil, ErrInvalidGroupBy
+	}
+
+	rows, err := db.Pool.Query(ctx,
+		`WITH completed AS (
+		     SELECT t.id, t.estimated_hours, t.actual_hours,
+		            COALESCE((SELECT MAX(h.edited_at) FROM edit_history h
+		                      WHERE h.task_id = t.id AND h.field_name = 'status' AND h.new_value = 'done'), t.updated_at) AS done_at
+		     FROM tasks t WHERE t.status = 'done' AND t.actual_hours > 0 AND t.duplicate_of IS NULL
+		 ), pairs AS (
+		     SELECT c.done_at, 'human' AS source, c.estimated_hours AS estimate,
+		            NULL::numeric AS low, NULL::numeric AS high, c.actual_hours
+		     FROM completed c WHERE c.estimated_hours > 0
+		     UNION ALL
+		     SELECT c.done_at, e.method, e.hours, e.low_hours, e.high_hours, c.actual_hours
+		     FROM completed c
+		     JOIN LATERAL (SELECT method, hours, low_hours, high_hours FROM effort_estimates
+		                   WHERE task_id = c.id AND created_at <= c.done_at
+		                   ORDER BY created_at DESC LIMIT 1) e ON true
+		 )
+		 SELECT date_trunc($3, done_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC', source, COUNT(*),
+		        AVG(ABS(estimate - actual_hours))::float8,
+		        AVG(ABS(estimate - actual_hours) / actual_hours)::float8,
+		        AVG(estimate - actual_hours)::float8,
+		        AVG(CASE WHEN actual_hours BETWEEN low AND high THEN 1 WHEN low IS NOT NULL THEN 0 END)::float8
+		 FROM pairs
+		 WHERE done_at >= $1 AND done_at < $2
+		 GROUP BY 1, 2 ORDER BY 1, 2`, from, to, groupBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query estimation accuracy: %w", err)
+	}
+	defer rows.Close()
+
+	report := &model.EffortAccuracyReport{From: from, To: to, GroupBy: groupBy, Rows: []model.EffortAccuracyRow{}}
+	for rows.Next() {
+		var r model.EffortAccuracyRow
+		if err := rows.Scan(&r.Period, &r.Source, &r.Tasks, &r.MeanAbsError, &r.MeanAbsPctErr, &r.Bias, &r.WithinInterval); err != nil {
+			return nil, fmt.Errorf("failed to scan estimation accuracy: %w", err)
+		}
+		report.Rows = append(report.Rows, r)
+	}
+	return report, rows.Err()
+}
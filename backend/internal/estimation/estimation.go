@@ -0,0 +1,380 @@
+// Package estimation suggests estimated_hours for tasks from the actual
+// hours of similar completed tasks, optionally refined by the LLM, and
+// tracks how far estimates were from the actuals.
+package estimation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/llm"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+// Estimation methods.
+const (
+	MethodNeighbours = "neighbours"
+	MethodLLM        = "llm"
+)
+
+const (
+	// K is how many similar tasks an estimate is based on.
+	K = 5
+	// MinSimilarity is the similarity below which completed tasks are not
+	// considered comparable.
+	MinSimilarity = 0.15
+	// historyLimit caps the completed tasks compared, most recent first.
+	historyLimit = 2000
+	// callTimeout bounds a refinement, including the retries made by the
+	// configured client.
+	callTimeout = 30 * time.Second
+)
+
+var (
+	// ErrTaskNotFound is returned when the task to estimate does not exist.
+	ErrTaskNotFound = errors.New("task not found")
+	// ErrNoHistory is returned when no completed task is similar enough and
+	// the LLM wasn't asked to estimate.
+	ErrNoHistory = errors.New("no similar completed tasks with actual hours")
+	// ErrInvalidTask is returned for a draft without a title.
+	ErrInvalidTask = errors.New("title is required")
+	// ErrRefine wraps errors from the LLM refining an estimate.
+	ErrRefine = errors.New("llm effort estimation failed")
+)
+
+// Sample is a task as the estimator compares it. ActualHours is set for
+// completed tasks only.
+type Sample struct {
+	TaskID      string
+	Title       string
+	Description string
+	Category    string
+	Tags        []string
+	ActualHours float64
+}
+
+// Options controls how an estimate is made.
+type Options struct {
+	// Refine asks the LLM to adjust the estimate, or to make one when there
+	// is no similar task.
+	Refine bool
+	// Estimator refines estimates; required when Refine is set.
+	Estimator llm.EffortEstimator
+}
+
+// ForTask estimates an existing task and stores the estimate, so it can be
+// compared with the task's actual hours once it is done. Nothing changes on
+// the task itself.
+func ForTask(ctx context.Context, taskID, userID string, opts Options) (*model.EffortEstimate, error) {
+	target, err := loadTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	e, err := estimate(ctx, target, userID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	neighbours, err := json.Marshal(e.Neighbours)
+	if err != nil {
+		return nil, err
+	}
+	var createdAt time.Time
+	err = db.Pool.QueryRow(ctx,
+		`INSERT INTO effort_estimates (task_id, requested_by, method, hours, low_hours, high_hours,
+		                               confidence, neighbours, rationale, provider, model)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		 RETURNING id, created_at`,
+		taskID, userID, e.Method, e.Hours, e.Low, e.High, e.Confidence, neighbours, e.Rationale, e.Provider, e.Model,
+	).Scan(&e.ID, &createdAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store effort estimate: %w", err)
+	}
+	e.TaskID, e.RequestedBy, e.CreatedAt = &taskID, userID, &createdAt
+	return e, nil
+}
+
+// ForDraft estimates a task that hasn't been created yet. Nothing is stored.
+func ForDraft(ctx context.Context, draft Sample, userID string, opts Options) (*model.EffortEstimate, error) {
+	draft.Title = strings.TrimSpace(draft.Title)
+	if draft.Title == "" {
+		return nil, ErrInvalidTask
+	}
+	draft.TaskID, draft.ActualHours = "", 0
+	return estimate(ctx, draft, userID, opts)
+}
+
+// List returns a task's stored estimates, newest first.
+func List(ctx context.Context, taskID string) ([]model.EffortEstimate, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT id, task_id, COALESCE(requested_by::text, ''), method, hours::float8, low_hours::float8, high_hours::float8,
+		        confidence, neighbours, rationale, provider, model, created_at
+		 FROM effort_estimates WHERE task_id = $1 ORDER BY created_at DESC`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list effort estimates: %w", err)
+	}
+	defer rows.Close()
+
+	estimates := []model.EffortEstimate{}
+	for rows.Next() {
+		var e model.EffortEstimate
+		var neighbours []byte
+		var createdAt time.Time
+		if err := rows.Scan(&e.ID, &e.TaskID, &e.RequestedBy, &e.Method, &e.Hours, &e.Low, &e.High,
+			&e.Confidence, &neighbours, &e.Rationale, &e.Provider, &e.Model, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan effort estimate: %w", err)
+		}
+		if err := json.Unmarshal(neighbours, &e.Neighbours); err != nil {
+			return nil, fmt.Errorf("failed to decode effort estimate neighbours: %w", err)
+		}
+		e.CreatedAt = &createdAt
+		estimates = append(estimates, e)
+	}
+	return estimates, rows.Err()
+}
+
+func estimate(ctx context.Context, target Sample, userID string, opts Options) (*model.EffortEstimate, error) {
+	history, err := loadHistory(ctx, target.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	neighbours := Nearest(target, history, K)
+	if len(neighbours) == 0 && !opts.Refine {
+		return nil, ErrNoHistory
+	}
+	e := FromNeighbours(neighbours)
+	if !opts.Refine {
+		return e, nil
+	}
+	if opts.Estimator == nil {
+		return nil, llm.ErrUnsupported
+	}
+
+	req := llm.EffortRequest{Title: target.Title, Description: target.Description, Category: target.Category, Tags: target.Tags}
+	if len(neighbours) > 0 {
+		byID := make(map[string]Sample, len(history))
+		for _, s := range history {
+			byID[s.TaskID] = s
+		}
+		for _, n := range neighbours {
+			s := byID[n.TaskID]
+			req.Examples = append(req.Examples, llm.EffortExample{
+				Title: s.Title, Description: s.Description, Category: s.Category, Tags: s.Tags, ActualHours: s.ActualHours,
+			})
+		}
+		baseline := e.Hours
+		req.Baseline = &baseline
+	}
+
+	callCtx, cancel := context.WithTimeout(llm.WithCaller(ctx, target.TaskID, userID), callTimeout)
+	defer cancel()
+	refined, err := opts.Estimator.EstimateEffort(callCtx, req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRefine, err)
+	}
+	if err := llm.ValidateEffortEstimate(refined); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRefine, err)
+	}
+	e.Method = MethodLLM
+	e.Hours, e.Low, e.High = refined.Hours, refined.Low, refined.High
+	e.Rationale = optional(refined.Rationale)
+	e.Provider, e.Model = optional(refined.Provider), optional(refined.Model)
+	return e, nil
+}
+
+func loadTask(ctx context.Context, taskID string) (Sample, error) {
+	s := Sample{TaskID: taskID}
+	err := db.Pool.QueryRow(ctx,
+		`SELECT title, COALESCE(description, ''), COALESCE(category, ''),
+		        ARRAY(SELECT g.name FROM task_tags tt JOIN tags g ON g.id = tt.tag_id WHERE tt.task_id = t.id ORDER BY g.name)
+		 FROM tasks t WHERE id = $1`, taskID,
+	).Scan(&s.Title, &s.Description, &s.Category, &s.Tags)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s, ErrTaskNotFound
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to get task: %w", err)
+	}
+	return s, nil
+}
+
+// loadHistory returns the most recently updated completed tasks with actual
+// hours, except excludeID.
+func loadHistory(ctx context.Context, excludeID string) ([]Sample, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT t.id, t.title, COALESCE(t.description, ''), COALESCE(t.category, ''), t.actual_hours::float8,
+		        ARRAY(SELECT g.name FROM task_tags tt JOIN tags g ON g.id = tt.tag_id WHERE tt.task_id = t.id ORDER BY g.name)
+		 FROM tasks t
+		 WHERE t.status = 'done' AND t.actual_hours > 0 AND t.duplicate_of IS NULL AND t.id::text <> $1
+		 ORDER BY t.updated_at DESC LIMIT $2`, excludeID, historyLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query completed tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var history []Sample
+	for rows.Next() {
+		var s Sample
+		if err := rows.Scan(&s.TaskID, &s.Title, &s.Description, &s.Category, &s.ActualHours, &s.Tags); err != nil {
+			return nil, fmt.Errorf("failed to scan completed task: %w", err)
+		}
+		history = append(history, s)
+	}
+	return history, rows.Err()
+}
+
+// Similarity scores how comparable two tasks are, from 0 to 1: mostly the
+// words of their title and description, then shared tags and category.
+func Similarity(a, b Sample) float64 {
+	score := 0.6 * cosine(terms(a.Title+" "+a.Description), terms(b.Title+" "+b.Description))
+	score += 0.25 * jaccard(a.Tags, b.Tags)
+	if a.Category != "" && a.Category == b.Category {
+		score += 0.15
+	}
+	return score
+}
+
+// Nearest returns up to k tasks of history most similar to target, most
+// similar first, leaving out those below MinSimilarity.
+func Nearest(target Sample, history []Sample, k int) []model.EffortNeighbour {
+	var out []model.EffortNeighbour
+	for _, s := range history {
+		if s.ActualHours <= 0 {
+			continue
+		}
+		if sim := Similarity(target, s); sim >= MinSimilarity {
+			out = append(out, model.EffortNeighbour{
+				TaskID: s.TaskID, Title: s.Title, ActualHours: s.ActualHours, Similarity: math.Round(sim*1000) / 1000,
+			})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Similarity > out[j].Similarity })
+	if len(out) > k {
+		out = out[:k]
+	}
+	return out
+}
+
+// z80 is the z-score bounding a central 80% interval.
+const z80 = 1.2816
+
+// FromNeighbours estimates from the neighbours' actual hours, weighted by
+// similarity. Effort is compared in log space, so a 2h and an 8h neighbour
+// average to 4h, and the interval is widened when there are few neighbours.
+// Without neighbours the estimate is empty and of low confidence.
+func FromNeighbours(neighbours []model.EffortNeighbour) *model.EffortEstimate {
+	e := &model.EffortEstimate{Method: MethodNeighbours, Confidence: "low", Neighbours: neighbours}
+	if len(neighbours) == 0 {
+		e.Neighbours = []model.EffortNeighbour{}
+		return e
+	}
+
+	var weights, mean float64
+	for _, n := range neighbours {
+		weights += n.Similarity
+		mean += n.Similarity * math.Log(n.ActualHours)
+	}
+	mean /= weights
+	var variance float64
+	for _, n := range neighbours {
+		d := math.Log(n.ActualHours) - mean
+		variance += n.Similarity * d * d
+	}
+	variance /= weights
+	// A factor-of-two prior uncertainty, shrinking with more neighbours.
+	spread := math.Sqrt(variance + math.Ln2*math.Ln2/float64(len(neighbours)))
+
+	e.Hours = clampHours(math.Exp(mean))
+	e.Low = clampHours(math.Exp(mean - z80*spread))
+	e.High = clampHours(math.Exp(mean + z80*spread))
+
+	ratio := e.High / e.Low
+	avgSimilarity := weights / float64(len(neighbours))
+	switch {
+	case len(neighbours) >= 3 && ratio <= 3 && avgSimilarity >= 0.4:
+		e.Confidence = "high"
+	case len(neighbours) >= 2 && ratio <= 6:
+		e.Confidence = "medium"
+	}
+	return e
+}
+
+func clampHours(h float64) float64 {
+	return math.Min(math.Max(math.Round(h*100)/100, 0.01), llm.MaxEffortHours)
+}
+
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "from": true, "that": true, "this": true,
+	"into": true, "when": true, "should": true, "are": true, "not": true, "all": true, "our": true,
+	"para": true, "con": true, "los": true, "las": true, "del": true, "que": true, "una": true, "por": true,
+}
+
+// terms counts the words of s, lowercased, ignoring short and common ones.
+func terms(s string) map[string]float64 {
+	tf := map[string]float64{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(w)) >= 3 && !stopWords[w] {
+			tf[w]++
+		}
+	}
+	return tf
+}
+
+func cosine(a, b map[string]float64) float64 {
+	var dot, na, nb float64
+	for w, x := range a {
+		dot += x * b[w]
+		na += x * x
+	}
+	for _, y := range b {
+		nb += y * y
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+func jaccard(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	set := make(map[string]bool, len(a))
+	for _, t := range a {
+		set[strings.ToLower(t)] = true
+	}
+	union, inter := len(set), 0
+	seen := map[string]bool{}
+	for _, t := range b {
+		t = strings.ToLower(t)
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		if set[t] {
+			inter++
+		} else {
+			union++
+		}
+	}
+	return float64(inter) / float64(union)
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,106 @@
+package eval
+
+// Verdicts of a changed prediction.
+const (
+	VerdictFixed     = "fixed"     // wrong in the baseline, right in the candidate
+	VerdictRegressed = "regressed" // right in the baseline, wrong in the candidate
+	VerdictChanged   = "changed"   // different, but neither or both right
+)
+
+// MetricDelta is a metric of two runs.
+type MetricDelta struct {
+	Metric    string  `json:"metric"`
+	Baseline  float64 `json:"baseline"`
+	Candidate float64 `json:"candidate"`
+	Delta     float64 `json:"delta"`
+}
+
+// Change is a case field whose prediction differs between two runs.
+type Change struct {
+	CaseID   string `json:"case_id"`
+	Field    string `json:"field"` // "priority", "category", "tags"
+	Expected string `json:"expected"`
+	Before   string `json:"before"`
+	After    string `json:"after"`
+	Verdict  string `json:"verdict"`
+}
+
+// Diff compares a candidate run with a baseline.
+type Diff struct {
+	Baseline  string        `json:"baseline"`
+	Candidate string        `json:"candidate"`
+	Metrics   []MetricDelta `json:"metrics"`
+	Changes   []Change      `json:"changes"`
+	Fixed     int           `json:"fixed"`
+	Regressed int           `json:"regressed"`
+}
+
+// Compare diffs two runs over the cases of the candidate. Cases missing
+// from the baseline count as errors there.
+func Compare(baseline, candidate *Run) Diff {
+	d := Diff{Baseline: baseline.Name, Candidate: candidate.Name}
+	b, c := baseline.Metrics, candidate.Metrics
+	for _, m := range []struct {
+		name string
+		b, c float64
+	}{
+		{"priority_accuracy", b.PriorityAccuracy, c.PriorityAccuracy},
+		{"category_accuracy", b.CategoryAccuracy, c.CategoryAccuracy},
+		{"tag_precision", b.TagPrecision, c.TagPrecision},
+		{"tag_recall", b.TagRecall, c.TagRecall},
+		{"tag_f1", b.TagF1, c.TagF1},
+		{"errors", float64(b.Errors), float64(c.Errors)},
+		{"avg_latency_ms", b.AvgLatencyMs, c.AvgLatencyMs},
+	} {
+		d.Metrics = append(d.Metrics, MetricDelta{Metric: m.name, Baseline: m.b, Candidate: m.c, Delta: m.c - m.b})
+	}
+
+	before := fieldValues(baseline)
+	after := fieldValues(candidate)
+	for _, tc := range candidate.Cases {
+		expected := map[string]string{
+			"priority": tc.Expected.Priority,
+			"category": tc.Expected.Category,
+			"tags":     tagSet(tc.Expected.Tags),
+		}
+		for _, field := range []string{"priority", "category", "tags"} {
+			if expected[field] == "" {
+				continue
+			}
+			old, ok := before[tc.ID][field]
+			if !ok {
+				old = errorLabel
+			}
+			now := after[tc.ID][field]
+			if old == now {
+				continue
+			}
+			change := Change{CaseID: tc.ID, Field: field, Expected: expected[field], Before: old, After: now, Verdict: VerdictChanged}
+			switch {
+			case old != expected[field] && now == expected[field]:
+				change.Verdict = VerdictFixed
+				d.Fixed++
+			case old == expected[field] && now != expected[field]:
+				change.Verdict = VerdictRegressed
+				d.Regressed++
+			}
+			d.Changes = append(d.Changes, change)
+		}
+	}
+	return d
+}
+
+// fieldValues returns the predicted value of each field per case ID.
+func fieldValues(run *Run) map[string]map[string]string {
+	values := map[string]map[string]string{}
+	for _, p := range run.Predictions {
+		v := map[string]string{"priority": errorLabel, "category": errorLabel, "tags": errorLabel}
+		if p.Predicted != nil {
+			v["priority"] = p.Predicted.Priority
+			v["category"] = p.Predicted.Category
+			v["tags"] = tagSet(p.Predicted.Tags)
+		}
+		values[p.CaseID] = v
+	}
+	return values
+}
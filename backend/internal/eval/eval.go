@@ -0,0 +1,187 @@
+// Package eval scores an llm.LLMClient against a labeled dataset of tasks so
+// prompt and model changes can be compared before rollout.
+package eval
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KemenyStudio/task-manager/internal/llm"
+)
+
+// Case is one labeled task of a dataset.
+type Case struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Expected    Label  `json:"expected"`
+}
+
+// Label is the classification a human expects for a case. An empty
+// priority or category is not scored.
+type Label struct {
+	Priority string   `json:"priority,omitempty"`
+	Category string   `json:"category,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+// Prediction is a client's answer for one case.
+type Prediction struct {
+	CaseID    string                  `json:"case_id"`
+	Predicted *llm.TaskClassification `json:"predicted,omitempty"`
+	Error     string                  `json:"error,omitempty"`
+	LatencyMs int64                   `json:"latency_ms"`
+}
+
+// Run is the outcome of evaluating a client over a dataset.
+type Run struct {
+	Name        string       `json:"name"`
+	StartedAt   time.Time    `json:"started_at"`
+	Cases       []Case       `json:"cases"`
+	Predictions []Prediction `json:"predictions"`
+	Metrics     Metrics      `json:"metrics"`
+}
+
+// LoadDataset reads a JSON Lines dataset, one Case per line. Blank lines
+// and lines starting with # are skipped.
+func LoadDataset(path string) ([]Case, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var cases []Case
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var c Case
+		if err := json.Unmarshal([]byte(text), &c); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if c.ID == "" {
+			c.ID = fmt.Sprintf("line-%d", line)
+		}
+		if seen[c.ID] {
+			return nil, fmt.Errorf("%s:%d: duplicate case id %q", path, line, c.ID)
+		}
+		seen[c.ID] = true
+		c.Expected.Tags = llm.NormalizeTags(c.Expected.Tags)
+		cases = append(cases, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(cases) == 0 {
+		return nil, errors.New("dataset is empty")
+	}
+	return cases, nil
+}
+
+// Evaluate classifies every case with client, concurrency at a time, and
+// scores the answers. Failed calls are kept as predictions with an error.
+func Evaluate(ctx context.Context, name string, client llm.LLMClient, cases []Case, concurrency int) *Run {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	run := &Run{Name: name, StartedAt: time.Now().UTC(), Cases: cases, Predictions: make([]Prediction, len(cases))}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i, c := range cases {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, c Case) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			p := Prediction{CaseID: c.ID}
+			start := time.Now()
+			tc, err := client.ClassifyTask(ctx, c.Title, c.Description)
+			p.LatencyMs = time.Since(start).Milliseconds()
+			if err != nil {
+				p.Error = err.Error()
+			} else {
+				llm.NormalizeClassification(tc)
+				p.Predicted = tc
+			}
+			run.Predictions[i] = p
+		}(i, c)
+	}
+	wg.Wait()
+
+	run.Metrics = Score(cases, run.Predictions)
+	return run
+}
+
+// WriteRun saves a run as indented JSON.
+func WriteRun(path string, run *Run) error {
+	b, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// ReadRun loads a run saved by WriteRun.
+func ReadRun(path string) (*Run, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var run Run
+	if err := json.Unmarshal(b, &run); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &run, nil
+}
+
+// ReplayClient answers with the predictions of a saved run, matched by task
+// title and description, so a run can be re-scored without calling a
+// provider. Tasks the run didn't see are an error.
+type ReplayClient struct {
+	answers map[string]Prediction
+}
+
+// NewReplayClient replays run.
+func NewReplayClient(run *Run) *ReplayClient {
+	c := &ReplayClient{answers: map[string]Prediction{}}
+	byID := map[string]Case{}
+	for _, tc := range run.Cases {
+		byID[tc.ID] = tc
+	}
+	for _, p := range run.Predictions {
+		if tc, ok := byID[p.CaseID]; ok {
+			c.answers[replayKey(tc.Title, tc.Description)] = p
+		}
+	}
+	return c
+}
+
+func replayKey(title, description string) string {
+	return title + "\x00" + description
+}
+
+func (c *ReplayClient) ClassifyTask(ctx context.Context, title, description string) (*llm.TaskClassification, error) {
+	p, ok := c.answers[replayKey(title, description)]
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("no recorded answer for task %q", title)
+	case p.Error != "":
+		return nil, errors.New(p.Error)
+	}
+	tc := *p.Predicted
+	return &tc, nil
+}
@@ -0,0 +1,147 @@
+package eval
+
+import (
+	"sort"
+	"strings"
+)
+
+// errorLabel is the predicted value recorded in confusion matrices for
+// cases the client failed to classify.
+const errorLabel = "(error)"
+
+// Confusion counts predictions per expected value: Confusion[expected][predicted].
+type Confusion map[string]map[string]int
+
+func (c Confusion) add(expected, predicted string) {
+	if c[expected] == nil {
+		c[expected] = map[string]int{}
+	}
+	c[expected][predicted]++
+}
+
+// Labels returns every expected and predicted value, sorted.
+func (c Confusion) Labels() []string {
+	set := map[string]bool{}
+	for expected, row := range c {
+		set[expected] = true
+		for predicted := range row {
+			set[predicted] = true
+		}
+	}
+	labels := make([]string, 0, len(set))
+	for l := range set {
+		labels = append(labels, l)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// Metrics summarizes how well a run's predictions match the labels. Cases
+// without an expected priority, category or tags are not scored on that field.
+type Metrics struct {
+	Cases             int       `json:"cases"`
+	Errors            int       `json:"errors"`
+	PriorityScored    int       `json:"priority_scored"`
+	PriorityAccuracy  float64   `json:"priority_accuracy"`
+	CategoryScored    int       `json:"category_scored"`
+	CategoryAccuracy  float64   `json:"category_accuracy"`
+	TagsScored        int       `json:"tags_scored"`
+	TagPrecision      float64   `json:"tag_precision"` // micro-averaged over scored cases
+	TagRecall         float64   `json:"tag_recall"`
+	TagF1             float64   `json:"tag_f1"`
+	AvgLatencyMs      float64   `json:"avg_latency_ms"`
+	PriorityConfusion Confusion `json:"priority_confusion"`
+	CategoryConfusion Confusion `json:"category_confusion"`
+}
+
+// Score compares predictions with the cases' labels.
+func Score(cases []Case, predictions []Prediction) Metrics {
+	m := Metrics{Cases: len(cases), PriorityConfusion: Confusion{}, CategoryConfusion: Confusion{}}
+	byID := map[string]Prediction{}
+	for _, p := range predictions {
+		byID[p.CaseID] = p
+	}
+
+	var priorityHits, categoryHits, tagHits, predictedTags, expectedTags int
+	var latency int64
+	for _, c := range cases {
+		p, ok := byID[c.ID]
+		latency += p.LatencyMs
+		got := p.Predicted
+		if !ok || got == nil {
+			m.Errors++
+		}
+
+		if c.Expected.Priority != "" {
+			m.PriorityScored++
+			predicted := errorLabel
+			if got != nil {
+				predicted = got.Priority
+			}
+			m.PriorityConfusion.add(c.Expected.Priority, predicted)
+			if predicted == c.Expected.Priority {
+				priorityHits++
+			}
+		}
+		if c.Expected.Category != "" {
+			m.CategoryScored++
+			predicted := errorLabel
+			if got != nil {
+				predicted = got.Category
+			}
+			m.CategoryConfusion.add(c.Expected.Category, predicted)
+			if predicted == c.Expected.Category {
+				categoryHits++
+			}
+		}
+		if len(c.Expected.Tags) > 0 {
+			m.TagsScored++
+			expectedTags += len(c.Expected.Tags)
+			if got != nil {
+				predictedTags += len(got.Tags)
+				tagHits += overlap(c.Expected.Tags, got.Tags)
+			}
+		}
+	}
+
+	m.PriorityAccuracy = ratio(priorityHits, m.PriorityScored)
+	m.CategoryAccuracy = ratio(categoryHits, m.CategoryScored)
+	m.TagPrecision = ratio(tagHits, predictedTags)
+	m.TagRecall = ratio(tagHits, expectedTags)
+	if m.TagPrecision+m.TagRecall > 0 {
+		m.TagF1 = 2 * m.TagPrecision * m.TagRecall / (m.TagPrecision + m.TagRecall)
+	}
+	if m.Cases > 0 {
+		m.AvgLatencyMs = float64(latency) / float64(m.Cases)
+	}
+	return m
+}
+
+func overlap(expected, predicted []string) int {
+	want := map[string]bool{}
+	for _, t := range expected {
+		want[t] = true
+	}
+	n := 0
+	for _, t := range predicted {
+		if want[t] {
+			n++
+			delete(want, t)
+		}
+	}
+	return n
+}
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
+
+// tagSet formats tags for comparison and display: sorted, comma-separated.
+func tagSet(tags []string) string {
+	sorted := append([]string(nil), tags...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
@@ -0,0 +1,75 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// WriteReport prints a run's metrics and confusion matrices.
+func WriteReport(w io.Writer, run *Run) {
+	m := run.Metrics
+	fmt.Fprintf(w, "Run %s: %d cases, %d errors, avg latency %.0fms\n\n", run.Name, m.Cases, m.Errors, m.AvgLatencyMs)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "metric\tvalue\tscored")
+	fmt.Fprintf(tw, "priority accuracy\t%.3f\t%d\n", m.PriorityAccuracy, m.PriorityScored)
+	fmt.Fprintf(tw, "category accuracy\t%.3f\t%d\n", m.CategoryAccuracy, m.CategoryScored)
+	fmt.Fprintf(tw, "tag precision\t%.3f\t%d\n", m.TagPrecision, m.TagsScored)
+	fmt.Fprintf(tw, "tag recall\t%.3f\t%d\n", m.TagRecall, m.TagsScored)
+	fmt.Fprintf(tw, "tag f1\t%.3f\t%d\n", m.TagF1, m.TagsScored)
+	tw.Flush()
+
+	writeConfusion(w, "Priority", m.PriorityConfusion)
+	writeConfusion(w, "Category", m.CategoryConfusion)
+}
+
+// writeConfusion prints a matrix with expected values as rows and
+// predictions as columns.
+func writeConfusion(w io.Writer, title string, c Confusion) {
+	if len(c) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s confusion (rows: expected, columns: predicted)\n", title)
+	labels := c.Labels()
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "\t")
+	for _, l := range labels {
+		fmt.Fprintf(tw, "%s\t", l)
+	}
+	fmt.Fprintln(tw)
+	for _, expected := range labels {
+		if c[expected] == nil {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t", expected)
+		for _, predicted := range labels {
+			fmt.Fprintf(tw, "%d\t", c[expected][predicted])
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+}
+
+// WriteDiff prints how a candidate run compares with a baseline.
+func WriteDiff(w io.Writer, d Diff) {
+	fmt.Fprintf(w, "Baseline %s vs candidate %s: %d fixed, %d regressed\n\n", d.Baseline, d.Candidate, d.Fixed, d.Regressed)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "metric\tbaseline\tcandidate\tdelta")
+	for _, m := range d.Metrics {
+		fmt.Fprintf(tw, "%s\t%.3f\t%.3f\t%+.3f\n", m.Metric, m.Baseline, m.Candidate, m.Delta)
+	}
+	tw.Flush()
+
+	if len(d.Changes) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "case\tfield\texpected\tbefore\tafter\tverdict")
+	for _, c := range d.Changes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", c.CaseID, c.Field, c.Expected, c.Before, c.After, c.Verdict)
+	}
+	tw.Flush()
+}
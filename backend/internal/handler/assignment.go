@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/assignment"
+)
+
+// SuggestAssignee ranks users as assignees for a task, with the reasons
+// behind each score. ?limit= (default 5, max 50) bounds the results. The
+// task is unchanged; the client assigns with UpdateTask.
+func SuggestAssignee(w http.ResponseWriter, r *http.Request) {
+	limit := 5
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 50 {
+			Error(w, r, http.StatusBadRequest, "limit must be between 1 and 50", nil, 0)
+			return
+		}
+		limit = n
+	}
+
+	suggestions, err := assignment.Suggest(r.Context(), chi.URLParam(r, "id"), limit)
+	if errors.Is(err, assignment.ErrTaskNotFound) {
+		Error(w, r, http.StatusNotFound, "task not found", nil, 0)
+		return
+	}
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to suggest assignees", err, 0)
+		return
+	}
+	if err := JSON(w, http.StatusOK, suggestions); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode assignee suggestions", err, 0)
+	}
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/classification"
+	"github.com/KemenyStudio/task-manager/internal/middleware"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+// CreateClassificationBatch re-classifies the tasks matching a filter in the
+// background. With "dry_run": true it only records what would change.
+func CreateClassificationBatch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Unclassified     bool    `json:"unclassified"`
+		ClassifiedBefore *string `json:"classified_before"` // date (YYYY-MM-DD) or RFC 3339 time
+		Category         *string `json:"category"`
+		Limit            int     `json:"limit"`
+		DryRun           bool    `json:"dry_run"`
+		Force            bool    `json:"force"`
+		Concurrency      int     `json:"concurrency"`
+		RatePerMinute    int     `json:"rate_per_minute"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, r, http.StatusBadRequest, "invalid request body", err, 0)
+		return
+	}
+	f := model.ClassificationBatchFilter{Unclassified: req.Unclassified, Category: req.Category, Limit: req.Limit}
+	if req.ClassifiedBefore != nil {
+		t, err := time.Parse(time.RFC3339, *req.ClassifiedBefore)
+		if err != nil {
+			if t, err = time.Parse("2006-01-02", *req.ClassifiedBefore); err != nil {
+				Error(w, r, http.StatusBadRequest, "classified_before must be a date (YYYY-MM-DD) or RFC 3339 time", err, 0)
+				return
+			}
+		}
+		f.ClassifiedBefore = &t
+	}
+
+	batch, err := classification.CreateBatch(r.Context(), middleware.GetUserID(r), f, classification.BatchOptions{
+		DryRun: req.DryRun, Force: req.Force, Concurrency: req.Concurrency, RatePerMinute: req.RatePerMinute,
+	})
+	if !writeBatchError(w, r, err) {
+		return
+	}
+	w.Header().Set("Location", "/api/admin/classification-batches/"+batch.ID)
+	if err := JSON(w, http.StatusAccepted, batch); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode classification batch", err, 0)
+	}
+}
+
+// ListClassificationBatches returns the most recent batches with their progress.
+func ListClassificationBatches(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			Error(w, r, http.StatusBadRequest, "limit must be between 1 and 200", nil, 0)
+			return
+		}
+		limit = n
+	}
+	batches, err := classification.ListBatches(r.Context(), limit)
+	if !writeBatchError(w, r, err) {
+		return
+	}
+	if err := JSON(w, http.StatusOK, batches); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode classification batches", err, 0)
+	}
+}
+
+// GetClassificationBatch returns a batch and its progress.
+func GetClassificationBatch(w http.ResponseWriter, r *http.Request) {
+	batch, err := classification.GetBatch(r.Context(), chi.URLParam(r, "id"))
+	writeBatch(w, r, batch, err)
+}
+
+// ListClassificationBatchItems returns the tasks of a batch and what their
+// classification changes. ?changed=true keeps only tasks that would change,
+// ?status= filters by item status.
+func ListClassificationBatchItems(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	items, err := classification.BatchItems(r.Context(), chi.URLParam(r, "id"), q.Get("status"), q.Get("changed") == "true")
+	if !writeBatchError(w, r, err) {
+		return
+	}
+	if err := JSON(w, http.StatusOK, items); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode classification batch items", err, 0)
+	}
+}
+
+// CancelClassificationBatch stops a running batch.
+func CancelClassificationBatch(w http.ResponseWriter, r *http.Request) {
+	batch, err := classification.CancelBatch(r.Context(), chi.URLParam(r, "id"))
+	writeBatch(w, r, batch, err)
+}
+
+// ResumeClassificationBatch restarts a cancelled or failed batch, retrying
+// the tasks that failed.
+func ResumeClassificationBatch(w http.ResponseWriter, r *http.Request) {
+	batch, err := classification.ResumeBatch(r.Context(), chi.URLParam(r, "id"))
+	writeBatch(w, r, batch, err)
+}
+
+func writeBatch(w http.ResponseWriter, r *http.Request, batch *model.ClassificationBatch, err error) {
+	if !writeBatchError(w, r, err) {
+		return
+	}
+	if err := JSON(w, http.StatusOK, batch); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode classification batch", err, 0)
+	}
+}
+
+// writeBatchError maps batch errors to responses. It reports whether err was
+// nil and the caller should continue.
+func writeBatchError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, classification.ErrBatchNotFound):
+		Error(w, r, http.StatusNotFound, err.Error(), nil, 0)
+	case errors.Is(err, classification.ErrInvalidBatch):
+		Error(w, r, http.StatusBadRequest, err.Error(), nil, 0)
+	case errors.Is(err, classification.ErrBatchState):
+		Error(w, r, http.StatusConflict, err.Error(), nil, 0)
+	default:
+		Error(w, r, http.StatusInternalServerError, "failed to manage classification batch", err, 0)
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/duplicates"
+	"github.com/KemenyStudio/task-manager/internal/llm"
+	"github.com/KemenyStudio/task-manager/internal/middleware"
+)
+
+var embedder llm.Embedder
+
+// SetEmbedder wires the embedding client used to find duplicate tasks; nil
+// disables duplicate detection.
+func SetEmbedder(e llm.Embedder) {
+	embedder = e
+}
+
+// ListSimilarTasks returns the tasks most similar to a task. ?limit= (default
+// 10, max 50) and ?min_score= (default 0.5) bound the results.
+func ListSimilarTasks(w http.ResponseWriter, r *http.Request) {
+	if embedder == nil {
+		Error(w, r, http.StatusNotImplemented, "embeddings are disabled", nil, 0)
+		return
+	}
+	q := r.URL.Query()
+	limit := 10
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 50 {
+			Error(w, r, http.StatusBadRequest, "limit must be between 1 and 50", nil, 0)
+			return
+		}
+		limit = n
+	}
+	minScore := 0.5
+	if v := q.Get("min_score"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < -1 || f > 1 {
+			Error(w, r, http.StatusBadRequest, "min_score must be between -1 and 1", nil, 0)
+			return
+		}
+		minScore = f
+	}
+
+	similar, err := duplicates.Similar(r.Context(), embedder, chi.URLParam(r, "id"), limit, minScore)
+	if !writeDuplicatesError(w, r, err) {
+		return
+	}
+	if err := JSON(w, http.StatusOK, similar); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode similar tasks", err, 0)
+	}
+}
+
+// MergeDuplicateTasks merges the tasks in {"duplicate_ids": [...]} into the
+// task and returns it.
+func MergeDuplicateTasks(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		Error(w, r, http.StatusUnauthorized, "unauthorized", nil, 0)
+		return
+	}
+	var req struct {
+		DuplicateIDs []string `json:"duplicate_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, r, http.StatusBadRequest, "invalid request body", err, 0)
+		return
+	}
+	err := duplicates.Merge(r.Context(), chi.URLParam(r, "id"), req.DuplicateIDs, userID)
+	if !writeDuplicatesError(w, r, err) {
+		return
+	}
+	GetTask(w, r)
+}
+
+// writeDuplicatesError maps duplicate detection errors to responses. It
+// reports whether err was nil and the caller should continue.
+func writeDuplicatesError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, duplicates.ErrTaskNotFound):
+		Error(w, r, http.StatusNotFound, err.Error(), nil, 0)
+	case errors.Is(err, duplicates.ErrInvalidMerge):
+		Error(w, r, http.StatusBadRequest, err.Error(), nil, 0)
+	case errors.Is(err, llm.ErrBudgetExceeded):
+		Error(w, r, http.StatusTooManyRequests, "monthly llm token budget exceeded", err, 0)
+	default:
+		Error(w, r, http.StatusInternalServerError, "failed to manage duplicate tasks", err, 0)
+	}
+	return false
+}
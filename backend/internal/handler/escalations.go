@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/escalation"
+	"github.com/KemenyStudio/task-manager/internal/middleware"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+// ListTaskEscalations returns a task's escalation log, newest first.
+func ListTaskEscalations(w http.ResponseWriter, r *http.Request) {
+	entries, err := escalation.ListTaskEscalations(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to get escalations", err, 0)
+		return
+	}
+	if err := JSON(w, http.StatusOK, entries); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode escalations", err, 0)
+	}
+}
+
+// AcknowledgeEscalation stops further notifications for an escalation.
+func AcknowledgeEscalation(w http.ResponseWriter, r *http.Request) {
+	err := escalation.Acknowledge(r.Context(), chi.URLParam(r, "id"), middleware.GetUserID(r))
+	if errors.Is(err, escalation.ErrNotFound) {
+		Error(w, r, http.StatusNotFound, "escalation not found", nil, 0)
+		return
+	}
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to acknowledge escalation", err, 0)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListEscalationRules returns every escalation rule.
+func ListEscalationRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := escalation.ListRules(r.Context(), false)
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to get escalation rules", err, 0)
+		return
+	}
+	if err := JSON(w, http.StatusOK, rules); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode escalation rules", err, 0)
+	}
+}
+
+// CreateEscalationRule validates and stores a new rule.
+func CreateEscalationRule(w http.ResponseWriter, r *http.Request) {
+	rule, ok := decodeEscalationRule(w, r)
+	if !ok {
+		return
+	}
+	created, err := escalation.CreateRule(r.Context(), rule)
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to create escalation rule", err, 0)
+		return
+	}
+	if err := JSON(w, http.StatusCreated, created); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode escalation rule", err, 0)
+	}
+}
+
+// UpdateEscalationRule replaces a rule's settings.
+func UpdateEscalationRule(w http.ResponseWriter, r *http.Request) {
+	rule, ok := decodeEscalationRule(w, r)
+	if !ok {
+		return
+	}
+	updated, err := escalation.UpdateRule(r.Context(), chi.URLParam(r, "id"), rule)
+	if errors.Is(err, escalation.ErrNotFound) {
+		Error(w, r, http.StatusNotFound, "escalation rule not found", nil, 0)
+		return
+	}
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to update escalation rule", err, 0)
+		return
+	}
+	if err := JSON(w, http.StatusOK, updated); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode escalation rule", err, 0)
+	}
+}
+
+// DeleteEscalationRule removes a rule and its escalation log.
+func DeleteEscalationRule(w http.ResponseWriter, r *http.Request) {
+	err := escalation.DeleteRule(r.Context(), chi.URLParam(r, "id"))
+	if errors.Is(err, escalation.ErrNotFound) {
+		Error(w, r, http.StatusNotFound, "escalation rule not found", nil, 0)
+		return
+	}
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to delete escalation rule", err, 0)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeEscalationRule reads a rule from the body; enabled defaults to true.
+func decodeEscalationRule(w http.ResponseWriter, r *http.Request) (model.EscalationRule, bool) {
+	rule := model.EscalationRule{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		Error(w, r, http.StatusBadRequest, "invalid request body", err, 0)
+		return rule, false
+	}
+	if err := escalation.ValidateRule(rule); err != nil {
+		Error(w, r, http.StatusBadRequest, err.Error(), nil, 0)
+		return rule, false
+	}
+	return rule, true
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/estimation"
+	"github.com/KemenyStudio/task-manager/internal/llm"
+	"github.com/KemenyStudio/task-manager/internal/middleware"
+)
+
+// EstimateTask suggests estimated_hours for a task from similar completed
+// tasks and stores the estimate. ?refine=true has the LLM adjust it. The
+// task is unchanged; the client applies the estimate with UpdateTask.
+func EstimateTask(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		Error(w, r, http.StatusUnauthorized, "unauthorized", nil, 0)
+		return
+	}
+	e, err := estimation.ForTask(r.Context(), chi.URLParam(r, "id"), userID, estimationOptions(r.URL.Query().Get("refine") == "true"))
+	if !writeEstimationError(w, r, err) {
+		return
+	}
+	if err := JSON(w, http.StatusCreated, e); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode effort estimate", err, 0)
+	}
+}
+
+// EstimateDraft suggests estimated_hours for a task before it is created.
+// Nothing is stored.
+func EstimateDraft(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		Error(w, r, http.StatusUnauthorized, "unauthorized", nil, 0)
+		return
+	}
+	var req struct {
+		Title       string   `json:"title"`
+		Description string   `json:"description"`
+		Category    string   `json:"category"`
+		Tags        []string `json:"tags"`
+		Refine      bool     `json:"refine"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, r, http.StatusBadRequest, "invalid request body", err, 0)
+		return
+	}
+	draft := estimation.Sample{Title: req.Title, Description: req.Description, Category: req.Category, Tags: req.Tags}
+	e, err := estimation.ForDraft(r.Context(), draft, userID, estimationOptions(req.Refine))
+	if !writeEstimationError(w, r, err) {
+		return
+	}
+	if err := JSON(w, http.StatusOK, e); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode effort estimate", err, 0)
+	}
+}
+
+// ListTaskEstimates returns the estimates made for a task, newest first.
+func ListTaskEstimates(w http.ResponseWriter, r *http.Request) {
+	estimates, err := estimation.List(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to list effort estimates", err, 0)
+		return
+	}
+	if err := JSON(w, http.StatusOK, estimates); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode effort estimates", err, 0)
+	}
+}
+
+// GetEstimationAccuracy reports how far human and AI estimates were from the
+// actual hours of tasks done in the date range, per week or month.
+func GetEstimationAccuracy(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := parseDateRange(w, r)
+	if !ok {
+		return
+	}
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy == "" {
+		groupBy = "week"
+	}
+	report, err := estimation.Accuracy(r.Context(), from, to, groupBy)
+	if errors.Is(err, estimation.ErrInvalidGroupBy) {
+		Error(w, r, http.StatusBadRequest, err.Error(), nil, 0)
+		return
+	}
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to get estimation accuracy", err, 0)
+		return
+	}
+	if err := JSON(w, http.StatusOK, report); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode estimation accuracy", err, 0)
+	}
+}
+
+func estimationOptions(refine bool) estimation.Options {
+	opts := estimation.Options{Refine: refine}
+	if estimator, ok := llmClient.(llm.EffortEstimator); ok {
+		opts.Estimator = estimator
+	}
+	return opts
+}
+
+// writeEstimationError maps estimation errors to responses. It reports
+// whether err was nil and the caller should continue.
+func writeEstimationError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, estimation.ErrTaskNotFound):
+		Error(w, r, http.StatusNotFound, err.Error(), nil, 0)
+	case errors.Is(err, estimation.ErrInvalidTask):
+		Error(w, r, http.StatusBadRequest, err.Error(), nil, 0)
+	case errors.Is(err, estimation.ErrNoHistory):
+		Error(w, r, http.StatusUnprocessableEntity, err.Error()+"; try refine=true", nil, 0)
+	case errors.Is(err, llm.ErrUnsupported):
+		Error(w, r, http.StatusNotImplemented, "effort estimation is not supported by the configured llm provider", nil, 0)
+	case errors.Is(err, llm.ErrBudgetExceeded):
+		Error(w, r, http.StatusTooManyRequests, "monthly llm token budget exceeded", err, 0)
+	case errors.Is(err, estimation.ErrRefine):
+		Error(w, r, http.StatusBadGateway, "llm effort estimation failed", err, 0)
+	default:
+		Error(w, r, http.StatusInternalServerError, "failed to estimate effort", err, 0)
+	}
+	return false
+}
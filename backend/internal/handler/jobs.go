@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/scheduler"
+)
+
+var jobScheduler *scheduler.Scheduler
+
+// SetScheduler wires the background job scheduler for the admin endpoints.
+func SetScheduler(s *scheduler.Scheduler) {
+	jobScheduler = s
+}
+
+// ListJobs returns the registered background jobs and their next run time.
+func ListJobs(w http.ResponseWriter, r *http.Request) {
+	if jobScheduler == nil {
+		Error(w, r, http.StatusServiceUnavailable, "scheduler not configured", nil, 0)
+		return
+	}
+	if err := JSON(w, http.StatusOK, jobScheduler.Jobs()); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode jobs", err, 0)
+	}
+}
+
+// ListJobRuns returns the run history of a job, newest first.
+func ListJobRuns(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			Error(w, r, http.StatusBadRequest, "limit must be between 1 and 500", nil, 0)
+			return
+		}
+		limit = n
+	}
+
+	runs, err := scheduler.ListRuns(r.Context(), name, limit)
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to get job runs", err, 0)
+		return
+	}
+	if err := JSON(w, http.StatusOK, runs); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode job runs", err, 0)
+	}
+}
+
+// TriggerJob starts a manual run of a job and returns 202 with the run record.
+func TriggerJob(w http.ResponseWriter, r *http.Request) {
+	if jobScheduler == nil {
+		Error(w, r, http.StatusServiceUnavailable, "scheduler not configured", nil, 0)
+		return
+	}
+
+	run, err := jobScheduler.Trigger(r.Context(), chi.URLParam(r, "name"))
+	if errors.Is(err, scheduler.ErrJobNotFound) {
+		Error(w, r, http.StatusNotFound, "job not found", nil, 0)
+		return
+	}
+	if errors.Is(err, scheduler.ErrJobRunning) {
+		Error(w, r, http.StatusConflict, "job already running", nil, 0)
+		return
+	}
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to trigger job", err, 0)
+		return
+	}
+
+	if err := JSON(w, http.StatusAccepted, run); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode job run", err, 0)
+	}
+}
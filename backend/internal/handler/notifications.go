@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/middleware"
+	"github.com/KemenyStudio/task-manager/internal/model"
+	"github.com/KemenyStudio/task-manager/internal/notification"
+)
+
+// ListNotifications returns the authenticated user's inbox, newest first.
+// Supports ?unread=true and ?limit=N (default 50, max 200).
+func ListNotifications(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			Error(w, r, http.StatusBadRequest, "limit must be between 1 and 200", nil, 0)
+			return
+		}
+		limit = n
+	}
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	notifications, err := notification.ListNotifications(r.Context(), userID, unreadOnly, limit)
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to get notifications", err, 0)
+		return
+	}
+	if err := JSON(w, http.StatusOK, notifications); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode notifications", err, 0)
+	}
+}
+
+// GetUnreadNotificationCount returns the total and per-type unread counts.
+func GetUnreadNotificationCount(w http.ResponseWriter, r *http.Request) {
+	counts, err := notification.UnreadCounts(r.Context(), middleware.GetUserID(r))
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to count notifications", err, 0)
+		return
+	}
+
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+	if err := JSON(w, http.StatusOK, map[string]interface{}{
+		"unread":  total,
+		"by_type": counts,
+	}); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode unread count", err, 0)
+	}
+}
+
+// MarkNotificationRead marks a single notification as read.
+func MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	found, err := notification.MarkRead(r.Context(), middleware.GetUserID(r), chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to mark notification read", err, 0)
+		return
+	}
+	if !found {
+		Error(w, r, http.StatusNotFound, "notification not found", nil, 0)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MarkAllNotificationsRead marks every unread notification of the user as read.
+func MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	updated, err := notification.MarkAllRead(r.Context(), middleware.GetUserID(r))
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to mark notifications read", err, 0)
+		return
+	}
+	if err := JSON(w, http.StatusOK, map[string]int64{"updated": updated}); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode response", err, 0)
+	}
+}
+
+// GetNotificationPreferences returns the user's per-type channel preferences.
+func GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	prefs, err := notification.GetPreferences(r.Context(), middleware.GetUserID(r))
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to get notification preferences", err, 0)
+		return
+	}
+	if err := JSON(w, http.StatusOK, prefs); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode notification preferences", err, 0)
+	}
+}
+
+// UpdateNotificationPreferences upserts per-type preferences and returns the
+// full resulting set.
+func UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	var prefs []model.NotificationPreference
+	if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+		Error(w, r, http.StatusBadRequest, "invalid request body", err, 0)
+		return
+	}
+	for _, p := range prefs {
+		if !notification.IsValidType(p.Type) {
+			Error(w, r, http.StatusBadRequest, "invalid notification type: "+p.Type, nil, 0)
+			return
+		}
+	}
+
+	userID := middleware.GetUserID(r)
+	if err := notification.SetPreferences(r.Context(), userID, prefs); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to save notification preferences", err, 0)
+		return
+	}
+	GetNotificationPreferences(w, r)
+}
+
+// GetDigestSettings returns the user's digest frequency, send time and timezone.
+func GetDigestSettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := notification.GetDigestSettings(r.Context(), middleware.GetUserID(r))
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to get digest settings", err, 0)
+		return
+	}
+	if err := JSON(w, http.StatusOK, settings); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode digest settings", err, 0)
+	}
+}
+
+// UpdateDigestSettings validates and stores the user's digest settings.
+func UpdateDigestSettings(w http.ResponseWriter, r *http.Request) {
+	var req model.DigestSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, r, http.StatusBadRequest, "invalid request body", err, 0)
+		return
+	}
+
+	validFrequencies := map[string]bool{notification.DigestOff: true, notification.DigestDaily: true, notification.DigestWeekly: true}
+	if !validFrequencies[req.Frequency] {
+		Error(w, r, http.StatusBadRequest, "frequency must be off, daily or weekly", nil, 0)
+		return
+	}
+	if req.SendHour < 0 || req.SendHour > 23 {
+		Error(w, r, http.StatusBadRequest, "send_hour must be between 0 and 23", nil, 0)
+		return
+	}
+	if req.Weekday < 0 || req.Weekday > 6 {
+		Error(w, r, http.StatusBadRequest, "weekday must be between 0 (Sunday) and 6", nil, 0)
+		return
+	}
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(req.Timezone); err != nil {
+		Error(w, r, http.StatusBadRequest, "invalid timezone", err, 0)
+		return
+	}
+
+	if err := notification.SaveDigestSettings(r.Context(), middleware.GetUserID(r), req); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to save digest settings", err, 0)
+		return
+	}
+	GetDigestSettings(w, r)
+}
+
+// PreviewDigest builds the user's next digest without sending it.
+// Supports ?format=text|html to return the rendered email body.
+func PreviewDigest(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	settings, err := notification.GetDigestSettings(r.Context(), userID)
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to get digest settings", err, 0)
+		return
+	}
+	frequency := settings.Frequency
+	if frequency == notification.DigestOff {
+		frequency = notification.DigestDaily
+	}
+
+	now := time.Now()
+	since := now.Add(-notification.DigestPeriod(frequency))
+	if settings.LastSentAt != nil {
+		since = *settings.LastSentAt
+	}
+	digest, err := notification.BuildDigest(r.Context(), userID, frequency, since, now)
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to build digest", err, 0)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "text" || format == "html" {
+		loc, err := time.LoadLocation(settings.Timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		msg, err := notification.RenderDigestEmail(digest, loc)
+		if err != nil {
+			Error(w, r, http.StatusInternalServerError, "failed to render digest", err, 0)
+			return
+		}
+		if format == "html" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(msg.HTML))
+		} else {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Write([]byte(msg.Text))
+		}
+		return
+	}
+
+	if err := JSON(w, http.StatusOK, digest); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode digest", err, 0)
+	}
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/middleware"
+	"github.com/KemenyStudio/task-manager/internal/model"
+	"github.com/KemenyStudio/task-manager/internal/prompts"
+)
+
+// ListPromptTemplates returns every version of a prompt template, newest first.
+func ListPromptTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := prompts.List(r.Context(), chi.URLParam(r, "name"))
+	if !writePromptError(w, r, err) {
+		return
+	}
+	if err := JSON(w, http.StatusOK, templates); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode prompt templates", err, 0)
+	}
+}
+
+// CreatePromptTemplate stores a new version of a prompt template and, with
+// "activate": true, switches to it.
+func CreatePromptTemplate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Body            string  `json:"body"`
+		FewShotExamples *int    `json:"few_shot_examples"`
+		Notes           *string `json:"notes"`
+		Activate        bool    `json:"activate"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, r, http.StatusBadRequest, "invalid request body", err, 0)
+		return
+	}
+	t := model.PromptTemplate{Body: req.Body, FewShotExamples: 3, Notes: req.Notes}
+	if req.FewShotExamples != nil {
+		t.FewShotExamples = *req.FewShotExamples
+	}
+
+	created, err := prompts.Create(r.Context(), chi.URLParam(r, "name"), t, middleware.GetUserID(r), req.Activate)
+	if !writePromptError(w, r, err) {
+		return
+	}
+	if err := JSON(w, http.StatusCreated, created); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode prompt template", err, 0)
+	}
+}
+
+// ActivatePromptTemplate pins a version of a prompt template, e.g. to roll
+// back to an earlier one.
+func ActivatePromptTemplate(w http.ResponseWriter, r *http.Request) {
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		Error(w, r, http.StatusBadRequest, "version must be a number", err, 0)
+		return
+	}
+	t, err := prompts.Activate(r.Context(), chi.URLParam(r, "name"), version)
+	if !writePromptError(w, r, err) {
+		return
+	}
+	if err := JSON(w, http.StatusOK, t); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode prompt template", err, 0)
+	}
+}
+
+// writePromptError maps prompt template errors to responses. It reports
+// whether err was nil and the caller should continue.
+func writePromptError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, prompts.ErrNotFound), errors.Is(err, prompts.ErrUnknownName):
+		Error(w, r, http.StatusNotFound, err.Error(), nil, 0)
+	case errors.Is(err, prompts.ErrInvalidTemplate):
+		Error(w, r, http.StatusBadRequest, err.Error(), nil, 0)
+	default:
+		Error(w, r, http.StatusInternalServerError, "failed to manage prompt template", err, 0)
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/classification"
+	"github.com/KemenyStudio/task-manager/internal/llm"
+	"github.com/KemenyStudio/task-manager/internal/middleware"
+)
+
+// ClassifyTaskStream classifies a task like ClassifyTask, relaying the
+// model's output as server-sent events while it is generated:
+//
+//	event: delta   data: {"text": "..."}  raw output, in order
+//	event: result  data: the stored suggestion, once the answer is validated
+//	event: error   data: {"error": "...", "status": 502}
+//
+// Nothing is stored unless the stream completes. Errors before the first
+// event are plain JSON responses. ?force=true bypasses the cache.
+func ClassifyTaskStream(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "id")
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		Error(w, r, http.StatusUnauthorized, "unauthorized", nil, 0)
+		return
+	}
+	if llmClient == nil {
+		Error(w, r, http.StatusInternalServerError, "LLM client not configured", nil, 0)
+		return
+	}
+	ctx := r.Context()
+	if r.URL.Query().Get("force") == "true" {
+		ctx = llm.WithForceRefresh(ctx)
+	}
+
+	events := &eventStream{w: w, rc: http.NewResponseController(w)}
+	s, err := classification.ClassifyStream(ctx, llmClient, taskID, userID, func(text string) {
+		events.send("delta", map[string]string{"text": text})
+	})
+	if err != nil {
+		status, msg := classificationErrorStatus(err)
+		if !events.started {
+			Error(w, r, status, msg, err, 0)
+			return
+		}
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+		events.send("error", map[string]any{"error": msg, "status": status})
+		return
+	}
+	events.send("result", s)
+}
+
+// classificationErrorStatus maps a classification error to the status and
+// message ClassifyTask answers with.
+func classificationErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, classification.ErrTaskNotFound):
+		return http.StatusNotFound, "task not found"
+	case errors.Is(err, llm.ErrBudgetExceeded):
+		return http.StatusTooManyRequests, "monthly llm token budget exceeded"
+	default:
+		return http.StatusBadGateway, "llm classification failed"
+	}
+}
+
+// eventStream writes server-sent events, flushing each one. The response
+// only starts with the first event, so errors before it can still get a
+// regular status. Once a write fails, later events are dropped.
+type eventStream struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	started bool
+	err     error
+}
+
+func (s *eventStream) send(event string, data any) {
+	if s.err != nil {
+		return
+	}
+	if !s.started {
+		h := s.w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("X-Accel-Buffering", "no") // keep proxies such as nginx from buffering
+		s.w.WriteHeader(http.StatusOK)
+		s.started = true
+	}
+	b, err := json.Marshal(data)
+	if err == nil {
+		_, err = fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, b)
+	}
+	// Without flushing the events still arrive, only all at once.
+	if err == nil {
+		if err = s.rc.Flush(); errors.Is(err, http.ErrNotSupported) {
+			err = nil
+		}
+	}
+	if err != nil {
+		log.Printf("failed to write %s event: %v", event, err)
+		s.err = err
+	}
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/classification"
+	"github.com/KemenyStudio/task-manager/internal/middleware"
+)
+
+// ListTaskSuggestions returns every AI suggestion made for a task, with the
+// per-field decisions taken on them.
+func ListTaskSuggestions(w http.ResponseWriter, r *http.Request) {
+	suggestions, err := classification.ListSuggestions(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to get suggestions", err, 0)
+		return
+	}
+	if err := JSON(w, http.StatusOK, suggestions); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode suggestions", err, 0)
+	}
+}
+
+// AcceptSuggestion applies a pending suggestion to its task. The optional body
+// {"fields": ["priority", "tags"]} accepts only those fields.
+func AcceptSuggestion(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Fields []string `json:"fields"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		Error(w, r, http.StatusBadRequest, "invalid request body", err, 0)
+		return
+	}
+
+	s, err := classification.Accept(r.Context(), chi.URLParam(r, "id"), middleware.GetUserID(r), req.Fields)
+	if !writeSuggestionError(w, r, err) {
+		return
+	}
+	if err := JSON(w, http.StatusOK, s); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode suggestion", err, 0)
+	}
+}
+
+// RejectSuggestion discards a pending suggestion. The body must give a reason:
+// {"reason": "priority is too high"}.
+func RejectSuggestion(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, r, http.StatusBadRequest, "invalid request body", err, 0)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		Error(w, r, http.StatusBadRequest, "reason is required", nil, 0)
+		return
+	}
+
+	s, err := classification.Reject(r.Context(), chi.URLParam(r, "id"), middleware.GetUserID(r), req.Reason)
+	if !writeSuggestionError(w, r, err) {
+		return
+	}
+	if err := JSON(w, http.StatusOK, s); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode suggestion", err, 0)
+	}
+}
+
+// GetSuggestionStats returns how often each suggested field is accepted.
+func GetSuggestionStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := classification.Stats(r.Context())
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to get suggestion stats", err, 0)
+		return
+	}
+	if err := JSON(w, http.StatusOK, stats); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode suggestion stats", err, 0)
+	}
+}
+
+// GetClassificationCacheStats returns the size and hit rate of the classification cache.
+func GetClassificationCacheStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := classification.CacheStats(r.Context())
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to get classification cache stats", err, 0)
+		return
+	}
+	if err := JSON(w, http.StatusOK, stats); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode classification cache stats", err, 0)
+	}
+}
+
+// writeSuggestionError maps decision errors to responses. It reports whether
+// err was nil and the caller should continue.
+func writeSuggestionError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, classification.ErrSuggestionNotFound):
+		Error(w, r, http.StatusNotFound, "suggestion not found", nil, 0)
+	case errors.Is(err, classification.ErrNotPending):
+		Error(w, r, http.StatusConflict, "suggestion already decided", nil, 0)
+	case errors.Is(err, classification.ErrInvalidField):
+		Error(w, r, http.StatusBadRequest, err.Error(), nil, 0)
+	default:
+		Error(w, r, http.StatusInternalServerError, "failed to decide suggestion", err, 0)
+	}
+	return false
+}
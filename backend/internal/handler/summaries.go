@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/llm"
+	"github.com/KemenyStudio/task-manager/internal/middleware"
+	"github.com/KemenyStudio/task-manager/internal/summary"
+)
+
+// SummarizeTaskHistory narrates a task's lifecycle from its history. The
+// stored summary is returned while the history is unchanged; ?refresh=true
+// generates a new one.
+func SummarizeTaskHistory(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		Error(w, r, http.StatusUnauthorized, "unauthorized", nil, 0)
+		return
+	}
+	s, err := summary.TaskHistory(r.Context(), summarizer(), chi.URLParam(r, "id"), userID, r.URL.Query().Get("refresh") == "true")
+	if !writeSummaryError(w, r, err) {
+		return
+	}
+	if err := JSON(w, http.StatusOK, s); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode task summary", err, 0)
+	}
+}
+
+// ListWeeklyReports returns the latest weekly reports, newest first.
+// ?limit= (default 12, max 52) bounds the results.
+func ListWeeklyReports(w http.ResponseWriter, r *http.Request) {
+	limit := 12
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 52 {
+			Error(w, r, http.StatusBadRequest, "limit must be between 1 and 52", nil, 0)
+			return
+		}
+		limit = n
+	}
+	reports, err := summary.ListWeekly(r.Context(), limit)
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to list weekly reports", err, 0)
+		return
+	}
+	if err := JSON(w, http.StatusOK, reports); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode weekly reports", err, 0)
+	}
+}
+
+// GetWeeklyReport returns the report of the week containing {week}, a
+// YYYY-MM-DD date.
+func GetWeeklyReport(w http.ResponseWriter, r *http.Request) {
+	day, err := time.Parse("2006-01-02", chi.URLParam(r, "week"))
+	if err != nil {
+		Error(w, r, http.StatusBadRequest, "week must be a YYYY-MM-DD date", nil, 0)
+		return
+	}
+	report, err := summary.GetWeekly(r.Context(), day)
+	if !writeSummaryError(w, r, err) {
+		return
+	}
+	if err := JSON(w, http.StatusOK, report); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode weekly report", err, 0)
+	}
+}
+
+// GenerateWeeklyReport writes, or rewrites, the report of the week
+// containing {"week": "YYYY-MM-DD"}; by default, last week.
+func GenerateWeeklyReport(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Week string `json:"week"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			Error(w, r, http.StatusBadRequest, "invalid request body", err, 0)
+			return
+		}
+	}
+	start := summary.WeekStart(time.Now()).AddDate(0, 0, -7)
+	if req.Week != "" {
+		day, err := time.Parse("2006-01-02", req.Week)
+		if err != nil {
+			Error(w, r, http.StatusBadRequest, "week must be a YYYY-MM-DD date", nil, 0)
+			return
+		}
+		start = summary.WeekStart(day)
+	}
+	if start.After(time.Now()) {
+		Error(w, r, http.StatusBadRequest, "week has not started yet", nil, 0)
+		return
+	}
+
+	report, err := summary.GenerateWeekly(r.Context(), summarizer(), start)
+	if !writeSummaryError(w, r, err) {
+		return
+	}
+	if err := JSON(w, http.StatusCreated, report); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode weekly report", err, 0)
+	}
+}
+
+// summarizer returns the LLM client when it can summarize, nil otherwise.
+func summarizer() llm.Summarizer {
+	s, _ := llmClient.(llm.Summarizer)
+	return s
+}
+
+// writeSummaryError maps summary errors to responses. It reports whether
+// err was nil and the caller should continue.
+func writeSummaryError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, summary.ErrTaskNotFound), errors.Is(err, summary.ErrReportNotFound):
+		Error(w, r, http.StatusNotFound, err.Error(), nil, 0)
+	case errors.Is(err, llm.ErrUnsupported):
+		Error(w, r, http.StatusNotImplemented, "summaries are not supported by the configured llm provider", nil, 0)
+	case errors.Is(err, llm.ErrBudgetExceeded):
+		Error(w, r, http.StatusTooManyRequests, "monthly llm token budget exceeded", err, 0)
+	case errors.Is(err, summary.ErrSummarize):
+		Error(w, r, http.StatusBadGateway, "llm summary failed", err, 0)
+	default:
+		Error(w, r, http.StatusInternalServerError, "failed to summarize", err, 0)
+	}
+	return false
+}
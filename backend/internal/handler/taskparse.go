@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/KemenyStudio/task-manager/internal/llm"
+	"github.com/KemenyStudio/task-manager/internal/middleware"
+	"github.com/KemenyStudio/task-manager/internal/taskparse"
+)
+
+// ParseTaskText turns free text into a task draft. Nothing is created: the
+// client shows the draft and, once the user confirms, posts draft.request to
+// /api/tasks.
+func ParseTaskText(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		Error(w, r, http.StatusUnauthorized, "unauthorized", nil, 0)
+		return
+	}
+	var req struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, r, http.StatusBadRequest, "invalid request body", err, 0)
+		return
+	}
+
+	parser, ok := llmClient.(llm.TaskParser)
+	if !ok {
+		Error(w, r, http.StatusNotImplemented, "task parsing is not supported by the configured llm provider", nil, 0)
+		return
+	}
+	draft, err := taskparse.Preview(r.Context(), parser, userID, req.Text)
+	switch {
+	case errors.Is(err, taskparse.ErrInvalidText):
+		Error(w, r, http.StatusBadRequest, err.Error(), nil, 0)
+		return
+	case errors.Is(err, llm.ErrUnsupported):
+		Error(w, r, http.StatusNotImplemented, "task parsing is not supported by the configured llm provider", nil, 0)
+		return
+	case errors.Is(err, llm.ErrBudgetExceeded):
+		Error(w, r, http.StatusTooManyRequests, "monthly llm token budget exceeded", err, 0)
+		return
+	case errors.Is(err, taskparse.ErrParse):
+		Error(w, r, http.StatusBadGateway, "llm task parsing failed", err, 0)
+		return
+	case err != nil:
+		Error(w, r, http.StatusInternalServerError, "failed to parse task", err, 0)
+		return
+	}
+	if err := JSON(w, http.StatusOK, draft); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode task draft", err, 0)
+	}
+}
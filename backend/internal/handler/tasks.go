@@ -2,22 +2,26 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	// "fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
-	"context"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/jackc/pgx/v5"
 
+	"github.com/KemenyStudio/task-manager/internal/classification"
 	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/duplicates"
 	"github.com/KemenyStudio/task-manager/internal/middleware"
 	"github.com/KemenyStudio/task-manager/internal/llm"
 	"github.com/KemenyStudio/task-manager/internal/model"
+	"github.com/KemenyStudio/task-manager/internal/notification"
 )
 
 var jwtSecret []byte
@@ -142,13 +146,13 @@ func GetTask(w http.ResponseWriter, r *http.Request) {
 	err := db.Pool.QueryRow(r.Context(),
 		`SELECT id, title, description, status, priority, category, summary,
 		        creator_id, assignee_id, due_date, estimated_hours, actual_hours,
-		        created_at, updated_at
+		        created_at, updated_at, duplicate_of
 		 FROM tasks WHERE id = $1`, taskID,
 	).Scan(
 		&t.ID, &t.Title, &t.Description, &t.Status, &t.Priority,
 		&t.Category, &t.Summary, &t.CreatorID, &t.AssigneeID,
 		&t.DueDate, &t.EstimatedHours, &t.ActualHours,
-		&t.CreatedAt, &t.UpdatedAt,
+		&t.CreatedAt, &t.UpdatedAt, &t.DuplicateOf,
 	)
 
     if err == pgx.ErrNoRows {
@@ -193,145 +197,85 @@ func GetTask(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Load pending AI suggestion
+	if t.PendingSuggestion, err = classification.PendingSuggestion(r.Context(), t.ID); err != nil {
+		log.Printf("error loading suggestion of task %s: %v", t.ID, err)
+	}
+
     if err := JSON(w, http.StatusOK, t); err != nil {
         Error(w, r, http.StatusInternalServerError, "failed to encode task", err, 0)
     }
 }
 
-// ClassifyTask calls the configured LLM to classify a task and persists results.
+// ClassifyTask runs AI classification for a task. By default it classifies
+// inline and returns the task with its new pending suggestion; with ?async=true (or the header
+// "Prefer: respond-async") it queues a classification job and returns 202.
+// ?force=true skips the classification cache.
 func ClassifyTask(w http.ResponseWriter, r *http.Request) {
-    if llmClient == nil {
-        Error(w, r, http.StatusInternalServerError, "LLM client not configured", nil, 0)
-        return
-    }
-
-    taskID := chi.URLParam(r, "id")
-    userID := middleware.GetUserID(r)
-    if userID == "" {
-        Error(w, r, http.StatusUnauthorized, "unauthorized", nil, 0)
-        return
-    }
-
-    // Fetch task
-    var t model.Task
-    err := db.Pool.QueryRow(r.Context(),
-        `SELECT id, title, COALESCE(description, '') FROM tasks WHERE id = $1`, taskID,
-    ).Scan(&t.ID, &t.Title, &t.Description)
-    if err == pgx.ErrNoRows {
-        Error(w, r, http.StatusNotFound, "task not found", nil, 0)
-        return
-    }
-    if err != nil {
-        Error(w, r, http.StatusInternalServerError, "failed to get task", err, 0)
-        return
-    }
-
-    desc := ""
-    if t.Description != nil {
-        desc = *t.Description
-    }
-
-    // Call LLM with timeout
-    ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
-    defer cancel()
-
-    classification, err := llmClient.ClassifyTask(ctx, t.Title, desc)
-    if err != nil {
-        Error(w, r, http.StatusBadGateway, "llm classification failed", err, 0)
-        return
-    }
-
-    // Normalize and validate
-    // priority and category allowed values
-    validPriorities := map[string]bool{"low":true, "medium":true, "high":true, "urgent":true}
-    if !validPriorities[classification.Priority] {
-        // fallback
-        classification.Priority = "medium"
-    }
-    validCategories := map[string]bool{"bug":true, "feature":true, "improvement":true, "research":true}
-    if !validCategories[classification.Category] {
-        classification.Category = "feature"
-    }
-    if len(classification.Summary) > 140 {
-        classification.Summary = classification.Summary[:137] + "..."
-    }
-    // sanitize tags
-    tags := []string{}
-    for i, tag := range classification.Tags {
-        if i>=8 { break }
-        t2 := strings.TrimSpace(strings.ToLower(tag))
-        t2 = strings.ReplaceAll(t2, " ", "-")
-        if t2=="" { continue }
-        tags = append(tags, t2)
-    }
-
-    // Persist in transaction
-    tx, err := db.Pool.Begin(r.Context())
-    if err != nil {
-        Error(w, r, http.StatusInternalServerError, "failed to begin tx", err, 0)
-        return
-    }
-    defer tx.Rollback(r.Context())
-
-    // Update task
-    _, err = tx.Exec(r.Context(), `UPDATE tasks SET category=$1, priority=$2, summary=$3, updated_at=NOW() WHERE id=$4`,
-        classification.Category, classification.Priority, classification.Summary, taskID)
-    if err != nil {
-        Error(w, r, http.StatusInternalServerError, "failed to update task", err, 0)
-        return
-    }
-
-    // Ensure tags exist and collect ids
-    tagIDs := []string{}
-    for _, name := range tags {
-        // insert tag if not exists
-        _, _ = tx.Exec(r.Context(), `INSERT INTO tags (name, color) VALUES ($1, '#6B7280') ON CONFLICT (name) DO NOTHING`, name)
-        var id string
-        err := tx.QueryRow(r.Context(), `SELECT id FROM tags WHERE name=$1`, name).Scan(&id)
-        if err != nil {
-            Error(w, r, http.StatusInternalServerError, "failed to get tag id", err, 0)
-            return
-        }
-        tagIDs = append(tagIDs, id)
-    }
-
-    // Remove previous AI-assigned tags for this task
-    _, err = tx.Exec(r.Context(), `DELETE FROM task_tags WHERE task_id=$1 AND assigned_by='ai'`, taskID)
-    if err != nil {
-        Error(w, r, http.StatusInternalServerError, "failed to delete old task_tags", err, 0)
-        return
-    }
+	taskID := chi.URLParam(r, "id")
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		Error(w, r, http.StatusUnauthorized, "unauthorized", nil, 0)
+		return
+	}
+	force := r.URL.Query().Get("force") == "true"
 
-    // Insert new task_tags
-    for _, tid := range tagIDs {
-        _, err := tx.Exec(r.Context(), `INSERT INTO task_tags (task_id, tag_id, assigned_by) VALUES ($1,$2,'ai') ON CONFLICT DO NOTHING`, taskID, tid)
-        if err != nil {
-            Error(w, r, http.StatusInternalServerError, "failed to insert task_tag", err, 0)
-            return
-        }
-    }
+	if r.URL.Query().Get("async") == "true" || r.Header.Get("Prefer") == "respond-async" {
+		var exists bool
+		if err := db.Pool.QueryRow(r.Context(),
+			"SELECT EXISTS(SELECT 1 FROM tasks WHERE id = $1)", taskID,
+		).Scan(&exists); err != nil {
+			Error(w, r, http.StatusInternalServerError, "failed to get task", err, 0)
+			return
+		}
+		if !exists {
+			Error(w, r, http.StatusNotFound, "task not found", nil, 0)
+			return
+		}
+		job, err := classification.Enqueue(r.Context(), taskID, userID, force)
+		if err != nil {
+			Error(w, r, http.StatusInternalServerError, "failed to queue classification", err, 0)
+			return
+		}
+		w.Header().Set("Location", "/api/tasks/"+taskID+"/classification")
+		if err := JSON(w, http.StatusAccepted, job); err != nil {
+			Error(w, r, http.StatusInternalServerError, "failed to encode classification job", err, 0)
+		}
+		return
+	}
 
-    // Record edit history for fields that changed
-    // Fetch old values to compare
-    var oldPriority, oldCategory, oldSummary *string
-    _ = tx.QueryRow(r.Context(), `SELECT priority, category, summary FROM tasks WHERE id=$1`, taskID).Scan(&oldPriority, &oldCategory, &oldSummary)
-    if oldPriority == nil || *oldPriority != classification.Priority {
-        _, _ = tx.Exec(r.Context(), `INSERT INTO edit_history (task_id, user_id, field_name, old_value, new_value) VALUES ($1,$2,'priority',$3,$4)`, taskID, userID, oldPriority, classification.Priority+" (AI suggestion)")
-    }
-    if oldCategory == nil || *oldCategory != classification.Category {
-        _, _ = tx.Exec(r.Context(), `INSERT INTO edit_history (task_id, user_id, field_name, old_value, new_value) VALUES ($1,$2,'category',$3,$4)`, taskID, userID, oldCategory, classification.Category+" (AI suggestion)")
-    }
-    if oldSummary == nil || *oldSummary != classification.Summary {
-        _, _ = tx.Exec(r.Context(), `INSERT INTO edit_history (task_id, user_id, field_name, old_value, new_value) VALUES ($1,$2,'summary',$3,$4)`, taskID, userID, oldSummary, classification.Summary+" (AI suggestion)")
-    }
+	if llmClient == nil {
+		Error(w, r, http.StatusInternalServerError, "LLM client not configured", nil, 0)
+		return
+	}
+	ctx := r.Context()
+	if force {
+		ctx = llm.WithForceRefresh(ctx)
+	}
+	if _, err := classification.Classify(ctx, llmClient, taskID, userID); err != nil {
+		status, msg := classificationErrorStatus(err)
+		Error(w, r, status, msg, err, 0)
+		return
+	}
 
-    if err := tx.Commit(r.Context()); err != nil {
-        Error(w, r, http.StatusInternalServerError, "failed to commit tx", err, 0)
-        return
-    }
+	// Return updated task (reuse GetTask logic by calling DB again)
+	GetTask(w, r)
+}
 
-    // Return updated task (reuse GetTask logic by calling DB again)
-    GetTask(w, r)
+// GetTaskClassification returns the status of the task's latest classification job.
+func GetTaskClassification(w http.ResponseWriter, r *http.Request) {
+	job, err := classification.LatestJob(r.Context(), chi.URLParam(r, "id"))
+	if errors.Is(err, classification.ErrNoJob) {
+		Error(w, r, http.StatusNotFound, "task has no classification job", nil, 0)
+		return
+	}
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to get classification job", err, 0)
+		return
+	}
+	if err := JSON(w, http.StatusOK, job); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode classification job", err, 0)
+	}
 }
 
 // CreateTask creates a new task.
@@ -418,6 +362,26 @@ func CreateTask(w http.ResponseWriter, r *http.Request) {
         return
     }
 
+	if err := notification.AutoWatch(r.Context(), task.ID, task.CreatorID); err != nil {
+		log.Printf("error subscribing creator to task %s: %v", task.ID, err)
+	}
+	if task.AssigneeID != nil {
+		if err := notification.TaskAssigned(r.Context(), task.ID, task.Title, *task.AssigneeID, userID); err != nil {
+			log.Printf("error notifying assignee of task %s: %v", task.ID, err)
+		}
+	}
+	if err := notification.TaskMentioned(r.Context(), task.ID, task.Title, "", deref(task.Description), userID); err != nil {
+		log.Printf("error notifying mentions in task %s: %v", task.ID, err)
+	}
+	if _, err := classification.Enqueue(r.Context(), task.ID, userID, false); err != nil {
+		log.Printf("error queueing classification of task %s: %v", task.ID, err)
+	}
+	if embedder != nil {
+		if task.PossibleDuplicates, err = duplicates.Candidates(r.Context(), embedder, task.ID); err != nil {
+			log.Printf("error finding duplicates of task %s: %v", task.ID, err)
+		}
+	}
+
     if err := JSON(w, http.StatusCreated, task); err != nil {
         Error(w, r, http.StatusInternalServerError, "failed to encode created task", err, 0)
     }
@@ -454,6 +418,8 @@ func UpdateTask(w http.ResponseWriter, r *http.Request) {
         return
     }
 
+	before := existing
+
 	// Build update fields
 	if req.Title != nil {
 		existing.Title = *req.Title
@@ -502,9 +468,58 @@ func UpdateTask(w http.ResponseWriter, r *http.Request) {
 		_, _ = db.Pool.Exec(r.Context(),
 			`INSERT INTO edit_history (task_id, user_id, field_name, old_value, new_value)
 			 VALUES ($1, $2, 'status', $3, $4)`,
-			taskID, userID, existing.Status, *req.Status,
+			taskID, userID, before.Status, *req.Status,
 		)
 	}
+	if deref(before.AssigneeID) != deref(existing.AssigneeID) {
+		_, _ = db.Pool.Exec(r.Context(),
+			`INSERT INTO edit_history (task_id, user_id, field_name, old_value, new_value)
+			 VALUES ($1, $2, 'assignee_id', $3, $4)`,
+			taskID, userID, before.AssigneeID, existing.AssigneeID,
+		)
+	}
+	// Hours are kept in the history so task summaries can tell when time was logged
+	for _, h := range []struct {
+		field         string
+		before, after *float64
+	}{
+		{"estimated_hours", before.EstimatedHours, existing.EstimatedHours},
+		{"actual_hours", before.ActualHours, existing.ActualHours},
+	} {
+		if !equalHours(h.before, h.after) {
+			_, _ = db.Pool.Exec(r.Context(),
+				`INSERT INTO edit_history (task_id, user_id, field_name, old_value, new_value)
+				 VALUES ($1, $2, $3, $4, $5)`,
+				taskID, userID, h.field, formatHours(h.before), formatHours(h.after),
+			)
+		}
+	}
+
+	// Notify the new assignee, mentioned users and watchers. The new
+	// assignee already hears about the change through the assignment.
+	var assigned []string
+	if newAssigneeID := deref(existing.AssigneeID); newAssigneeID != "" && newAssigneeID != deref(before.AssigneeID) {
+		if err := notification.TaskAssigned(r.Context(), taskID, existing.Title, newAssigneeID, userID); err != nil {
+			log.Printf("error notifying assignee of task %s: %v", taskID, err)
+		}
+		assigned = append(assigned, newAssigneeID)
+	}
+	if req.Description != nil {
+		if err := notification.TaskMentioned(r.Context(), taskID, existing.Title, deref(before.Description), *req.Description, userID); err != nil {
+			log.Printf("error notifying mentions in task %s: %v", taskID, err)
+		}
+	}
+	if err := notification.TaskUpdated(r.Context(), taskID, existing.Title, before.Status, existing.Status,
+		changedTaskFields(before, existing), userID, assigned...); err != nil {
+		log.Printf("error notifying watchers of task %s: %v", taskID, err)
+	}
+
+	// Reclassify when the text the classification is based on changed
+	if before.Title != existing.Title || deref(before.Description) != deref(existing.Description) {
+		if _, err := classification.Enqueue(r.Context(), taskID, userID, false); err != nil {
+			log.Printf("error queueing classification of task %s: %v", taskID, err)
+		}
+	}
 
 	// Return updated task
 	var updated model.Task
@@ -535,6 +550,14 @@ func UpdateTask(w http.ResponseWriter, r *http.Request) {
 func DeleteTask(w http.ResponseWriter, r *http.Request) {
 	taskID := chi.URLParam(r, "id")
 
+	// Capture title and watchers before the cascade removes them
+	var title string
+	_ = db.Pool.QueryRow(r.Context(), "SELECT title FROM tasks WHERE id = $1", taskID).Scan(&title)
+	watchers, err := notification.ListWatchers(r.Context(), taskID)
+	if err != nil {
+		log.Printf("error loading watchers of task %s: %v", taskID, err)
+	}
+
 	result, err := db.Pool.Exec(r.Context(),
 		"DELETE FROM tasks WHERE id = $1", taskID,
 	)
@@ -549,6 +572,10 @@ func DeleteTask(w http.ResponseWriter, r *http.Request) {
         return
     }
 
+	if err := notification.TaskDeleted(r.Context(), taskID, title, middleware.GetUserID(r), watchers); err != nil {
+		log.Printf("error notifying watchers of deleted task %s: %v", taskID, err)
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -733,3 +760,52 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
         Error(w, r, http.StatusInternalServerError, "failed to encode token response", err, 0)
     }
 }
+
+// deref returns the value of an optional string, or "" when nil.
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// changedTaskFields lists the user-editable fields, other than status, that
+// differ between two versions of a task.
+func changedTaskFields(before, after model.Task) []string {
+	var fields []string
+	if before.Title != after.Title {
+		fields = append(fields, "title")
+	}
+	if deref(before.Description) != deref(after.Description) {
+		fields = append(fields, "description")
+	}
+	if before.Priority != after.Priority {
+		fields = append(fields, "priority")
+	}
+	if deref(before.AssigneeID) != deref(after.AssigneeID) {
+		fields = append(fields, "assignee")
+	}
+	if !equalHours(before.EstimatedHours, after.EstimatedHours) {
+		fields = append(fields, "estimated_hours")
+	}
+	if !equalHours(before.ActualHours, after.ActualHours) {
+		fields = append(fields, "actual_hours")
+	}
+	return fields
+}
+
+func equalHours(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// formatHours returns hours as stored in edit_history, or nil when unset.
+func formatHours(h *float64) *string {
+	if h == nil {
+		return nil
+	}
+	s := strconv.FormatFloat(*h, 'f', -1, 64)
+	return &s
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/usage"
+)
+
+// GetLLMUsage aggregates LLM calls of this deployment's workspace by day,
+// user or model. from and to are inclusive dates (YYYY-MM-DD, UTC) and
+// default to the last 30 days.
+func GetLLMUsage(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := parseDateRange(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	groupBy := q.Get("group_by")
+	if groupBy == "" {
+		groupBy = usage.GroupByDay
+	}
+	workspace := q.Get("workspace")
+	if workspace == "" {
+		workspace = usage.Workspace()
+	}
+
+	report, err := usage.Report(r.Context(), workspace, from, to, groupBy)
+	if errors.Is(err, usage.ErrInvalidGroupBy) {
+		Error(w, r, http.StatusBadRequest, err.Error(), nil, 0)
+		return
+	}
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to get llm usage", err, 0)
+		return
+	}
+	if err := JSON(w, http.StatusOK, report); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode llm usage", err, 0)
+	}
+}
+
+// GetLLMRedactions reports what was redacted from task text before it was
+// sent to LLM providers, by kind, over the same date range as GetLLMUsage.
+func GetLLMRedactions(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := parseDateRange(w, r)
+	if !ok {
+		return
+	}
+	workspace := r.URL.Query().Get("workspace")
+	if workspace == "" {
+		workspace = usage.Workspace()
+	}
+
+	report, err := usage.RedactionReport(r.Context(), workspace, from, to)
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to get llm redactions", err, 0)
+		return
+	}
+	if err := JSON(w, http.StatusOK, report); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode llm redactions", err, 0)
+	}
+}
+
+// parseDateRange reads the inclusive from and to dates (YYYY-MM-DD, UTC) of
+// a report, defaulting to the last 30 days, and returns them as [from, to).
+// It writes the error response and returns false when they are invalid.
+func parseDateRange(w http.ResponseWriter, r *http.Request) (from, to time.Time, ok bool) {
+	q := r.URL.Query()
+	to = time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	if v := q.Get("to"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			Error(w, r, http.StatusBadRequest, "to must be a date (YYYY-MM-DD)", err, 0)
+			return from, to, false
+		}
+		to = d.AddDate(0, 0, 1)
+	}
+	from = to.AddDate(0, 0, -30)
+	if v := q.Get("from"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			Error(w, r, http.StatusBadRequest, "from must be a date (YYYY-MM-DD)", err, 0)
+			return from, to, false
+		}
+		from = d
+	}
+	if !from.Before(to) {
+		Error(w, r, http.StatusBadRequest, "from must not be after to", nil, 0)
+		return from, to, false
+	}
+	return from, to, true
+}
+
+// ListLLMBudgets returns each workspace's monthly token budget and usage.
+func ListLLMBudgets(w http.ResponseWriter, r *http.Request) {
+	budgets, err := usage.ListBudgets(r.Context())
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to list llm budgets", err, 0)
+		return
+	}
+	if err := JSON(w, http.StatusOK, budgets); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode llm budgets", err, 0)
+	}
+}
+
+// SetLLMBudget sets a workspace's monthly token budget. A null
+// monthly_token_limit removes the budget.
+func SetLLMBudget(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		MonthlyTokenLimit *int64 `json:"monthly_token_limit"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, r, http.StatusBadRequest, "invalid request body", err, 0)
+		return
+	}
+	if req.MonthlyTokenLimit != nil && *req.MonthlyTokenLimit < 0 {
+		Error(w, r, http.StatusBadRequest, "monthly_token_limit must not be negative", nil, 0)
+		return
+	}
+
+	budget, err := usage.SetBudget(r.Context(), chi.URLParam(r, "workspace"), req.MonthlyTokenLimit)
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to set llm budget", err, 0)
+		return
+	}
+	if err := JSON(w, http.StatusOK, budget); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode llm budget", err, 0)
+	}
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/middleware"
+	"github.com/KemenyStudio/task-manager/internal/notification"
+)
+
+// ListTaskWatchers returns the users watching a task.
+func ListTaskWatchers(w http.ResponseWriter, r *http.Request) {
+	watchers, err := notification.ListWatchers(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to get watchers", err, 0)
+		return
+	}
+	if err := JSON(w, http.StatusOK, watchers); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to encode watchers", err, 0)
+	}
+}
+
+// WatchTask subscribes the authenticated user to a task. The optional body
+// {"in_app": bool, "email": bool} sets delivery preferences (default: in-app only).
+// Calling it again updates the preferences.
+func WatchTask(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "id")
+	userID := middleware.GetUserID(r)
+
+	req := struct {
+		InApp *bool `json:"in_app"`
+		Email *bool `json:"email"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		Error(w, r, http.StatusBadRequest, "invalid request body", err, 0)
+		return
+	}
+	inApp, email := true, false
+	if req.InApp != nil {
+		inApp = *req.InApp
+	}
+	if req.Email != nil {
+		email = *req.Email
+	}
+
+	var exists bool
+	if err := db.Pool.QueryRow(r.Context(),
+		"SELECT EXISTS(SELECT 1 FROM tasks WHERE id = $1)", taskID,
+	).Scan(&exists); err != nil || !exists {
+		Error(w, r, http.StatusNotFound, "task not found", err, 0)
+		return
+	}
+
+	if err := notification.Watch(r.Context(), taskID, userID, inApp, email); err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to watch task", err, 0)
+		return
+	}
+	ListTaskWatchers(w, r)
+}
+
+// UnwatchTask removes the authenticated user's subscription to a task.
+func UnwatchTask(w http.ResponseWriter, r *http.Request) {
+	found, err := notification.Unwatch(r.Context(), chi.URLParam(r, "id"), middleware.GetUserID(r))
+	if err != nil {
+		Error(w, r, http.StatusInternalServerError, "failed to unwatch task", err, 0)
+		return
+	}
+	if !found {
+		Error(w, r, http.StatusNotFound, "not watching this task", nil, 0)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -28,13 +28,70 @@ func NewAnthropicClient() (*AnthropicClient, error) {
     if model == "" {
         model = "claude-2.1"
     }
-    cli := anthropic.NewClient(option.WithAPIKey(key))
+    // retries are handled by ResilientClient
+    opts := []option.RequestOption{option.WithAPIKey(key), option.WithMaxRetries(0)}
+    if httpClient != nil {
+        opts = append(opts, option.WithHTTPClient(httpClient))
+    }
+    cli := anthropic.NewClient(opts...)
     return &AnthropicClient{client: cli, model: model}, nil
 }
 
 func (c *AnthropicClient) ClassifyTask(ctx context.Context, title, description string) (*TaskClassification, error) {
-    prompt := buildPrompt(title, description)
+    if !supportsToolUse(c.model) {
+        out, err := c.Complete(ctx, buildPrompt(ctx, title, description))
+        if err != nil {
+            return nil, err
+        }
+        return parseClassification("anthropic", c.model, out)
+    }
+
+    msg, err := c.client.Messages.New(ctx, c.classifyParams(ctx, title, description))
+    if err != nil {
+        return nil, fmt.Errorf("anthropic sdk error: %w", err)
+    }
+    reportUsage(ctx, msg.Usage.InputTokens, msg.Usage.OutputTokens)
+    return c.classificationFrom(msg)
+}
+
+// classifyParams builds a request that forces a call to a tool whose input
+// schema is the classification, so the answer comes back as structured JSON.
+func (c *AnthropicClient) classifyParams(ctx context.Context, title, description string) anthropic.MessageNewParams {
+    return anthropic.MessageNewParams{
+        MaxTokens: 500,
+        Messages: []anthropic.MessageParam{
+            anthropic.NewUserMessage(anthropic.NewTextBlock(buildPrompt(ctx, title, description))),
+        },
+        Model: anthropic.Model(c.model),
+        Tools: []anthropic.ToolUnionParam{anthropic.ToolUnionParamOfTool(anthropic.ToolInputSchemaParam{
+            Properties: ClassificationSchema["properties"],
+            Required:   ClassificationSchema["required"].([]string),
+        }, classifyToolName)},
+        ToolChoice: anthropic.ToolChoiceParamOfTool(classifyToolName),
+    }
+}
+
+// classificationFrom parses the input of the classification tool call in msg.
+func (c *AnthropicClient) classificationFrom(msg *anthropic.Message) (*TaskClassification, error) {
+    for _, block := range msg.Content {
+        if block.Type == "tool_use" && block.Name == classifyToolName {
+            return parseClassification("anthropic", c.model, string(block.Input))
+        }
+    }
+    return nil, &MalformedResponseError{Provider: "anthropic", Model: c.model, Output: contentToString(msg.Content),
+        Err: errors.New("no classification tool call in response")}
+}
+
+const classifyToolName = "record_classification"
 
+// supportsToolUse reports whether model can be forced to call a tool; legacy
+// Claude 2 models only get the schema in the prompt.
+func supportsToolUse(model string) bool {
+    return !strings.HasPrefix(model, "claude-2") && !strings.HasPrefix(model, "claude-instant")
+}
+
+// Complete sends a free-form prompt and returns the model's text output.
+func (c *AnthropicClient) Complete(ctx context.Context, prompt string) (string, error) {
     msg, err := c.client.Messages.New(ctx, anthropic.MessageNewParams{
         MaxTokens: 300,
         Messages: []anthropic.MessageParam{
@@ -43,29 +100,26 @@ func (c *AnthropicClient) ClassifyTask(ctx context.Context, title, description s
         Model: anthropic.Model(c.model),
     })
     if err != nil {
-        return nil, fmt.Errorf("anthropic sdk error: %w", err)
+        return "", fmt.Errorf("anthropic sdk error: %w", err)
     }
+    reportUsage(ctx, msg.Usage.InputTokens, msg.Usage.OutputTokens)
     out := contentToString(msg.Content)
     if strings.TrimSpace(out) == "" {
-        return nil, errors.New("empty response from anthropic")
-    }
-
-    var tc TaskClassification
-    if err := parseJSONFromString(out, &tc); err != nil {
-        return nil, fmt.Errorf("failed parsing anthropic output: %w", err)
+        return "", errors.New("empty response from anthropic")
     }
-    return &tc, nil
+    return out, nil
 }
 
-// contentToString converts the SDK's ContentBlockUnion slice into a plain string
-// by concatenating each element's default string representation. This is a
-// conservative approach that extracts visible text regardless of the union
-// internals; if you prefer a stricter extraction (e.g. only text blocks) we
-// can refine it after inspecting the SDK types.
+// contentToString returns the text of the response's text blocks; other
+// blocks (tool calls) are included as their raw JSON.
 func contentToString(content []anthropic.ContentBlockUnion) string {
     var parts []string
     for _, cb := range content {
-        parts = append(parts, fmt.Sprintf("%v", cb))
+        if cb.Type == "text" {
+            parts = append(parts, cb.Text)
+        } else {
+            parts = append(parts, cb.RawJSON())
+        }
     }
     return strings.Join(parts, " ")
 }
@@ -0,0 +1,149 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// CacheStore persists classifications by cache key.
+type CacheStore interface {
+	// Get returns the unexpired classification stored under key, or nil.
+	Get(ctx context.Context, key string) (*TaskClassification, error)
+	Put(ctx context.Context, key string, tc *TaskClassification, ttl time.Duration) error
+}
+
+// CacheStats counts cache lookups made by this process since it started.
+type CacheStats struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+var cacheHits, cacheMisses atomic.Int64
+
+// CacheCounters returns the hit and miss counters shared by all caching clients.
+func CacheCounters() CacheStats {
+	s := CacheStats{Hits: cacheHits.Load(), Misses: cacheMisses.Load()}
+	if total := s.Hits + s.Misses; total > 0 {
+		s.HitRate = float64(s.Hits) / float64(total)
+	}
+	return s
+}
+
+type forceRefreshKey struct{}
+
+// WithForceRefresh makes caching clients skip the lookup and call the
+// provider; the fresh answer still replaces the cached one.
+func WithForceRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceRefreshKey{}, true)
+}
+
+func forceRefresh(ctx context.Context) bool {
+	force, _ := ctx.Value(forceRefreshKey{}).(bool)
+	return force
+}
+
+// CacheKey identifies a classification request: the same provider, model,
+// prompt version and task text always get the same key.
+func CacheKey(provider, model, promptVersion, title, description string) string {
+	h := sha256.New()
+	for _, part := range []string{provider, model, promptVersion, title, description} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// CachingClient answers repeated classifications of unchanged tasks from a
+// CacheStore instead of calling the provider again. Store errors are logged
+// and treated as misses.
+type CachingClient struct {
+	next     LLMClient
+	provider string
+	model    string
+	store    CacheStore
+	ttl      time.Duration
+}
+
+// NewCachingClient wraps next, which must always answer with provider/model.
+func NewCachingClient(next LLMClient, provider, model string, store CacheStore, ttl time.Duration) *CachingClient {
+	return &CachingClient{next: next, provider: provider, model: model, store: store, ttl: ttl}
+}
+
+func (c *CachingClient) ClassifyTask(ctx context.Context, title, description string) (*TaskClassification, error) {
+	return c.classify(ctx, title, description, nil, func() (*TaskClassification, error) {
+		return c.next.ClassifyTask(ctx, title, description)
+	})
+}
+
+// classify answers from the store, passing hits to onHit if set, or else
+// with call, storing its answer.
+func (c *CachingClient) classify(ctx context.Context, title, description string, onHit func(*TaskClassification), call func() (*TaskClassification, error)) (*TaskClassification, error) {
+	key := CacheKey(c.provider, c.model, PromptTemplateFrom(ctx).Version, title, description)
+	if !forceRefresh(ctx) {
+		tc, err := c.store.Get(ctx, key)
+		if err != nil {
+			log.Printf("llm cache lookup failed: %v", err)
+		}
+		if tc != nil {
+			cacheHits.Add(1)
+			if onHit != nil {
+				onHit(tc)
+			}
+			return tc, nil
+		}
+		cacheMisses.Add(1)
+	}
+
+	tc, err := call()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.store.Put(ctx, key, tc, c.ttl); err != nil {
+		log.Printf("llm cache store failed: %v", err)
+	}
+	return tc, nil
+}
+
+// ParseTask isn't cached: free text is rarely parsed twice.
+func (c *CachingClient) ParseTask(ctx context.Context, text string, opts ParseOptions) (*ParsedTask, error) {
+	return capability(c.next, func(parser TaskParser) (*ParsedTask, error) {
+		return parser.ParseTask(ctx, text, opts)
+	})
+}
+
+// EstimateEffort isn't cached: the examples change as tasks are completed.
+func (c *CachingClient) EstimateEffort(ctx context.Context, req EffortRequest) (*EffortEstimate, error) {
+	return capability(c.next, func(estimator EffortEstimator) (*EffortEstimate, error) {
+		return estimator.EstimateEffort(ctx, req)
+	})
+}
+
+// Embed isn't cached here: callers store the vectors they need.
+func (c *CachingClient) Embed(ctx context.Context, texts []string) (*Embeddings, error) {
+	return capability(c.next, func(embedder Embedder) (*Embeddings, error) {
+		return embedder.Embed(ctx, texts)
+	})
+}
+
+// Summarize isn't cached: callers store the summaries they need.
+func (c *CachingClient) Summarize(ctx context.Context, req SummaryRequest) (*Summary, error) {
+	return capability(c.next, func(summarizer Summarizer) (*Summary, error) {
+		return summarizer.Summarize(ctx, req)
+	})
+}
+
+// ClassifyTaskStream answers from the cache like ClassifyTask, passing a hit
+// on as a single piece, and stores what it streamed otherwise.
+func (c *CachingClient) ClassifyTaskStream(ctx context.Context, title, description string, onDelta func(string)) (*TaskClassification, error) {
+	return capability(c.next, func(streamer StreamingLLMClient) (*TaskClassification, error) {
+		onHit := func(tc *TaskClassification) { onDelta(answerJSON(tc)) }
+		return c.classify(ctx, title, description, onHit, func() (*TaskClassification, error) {
+			return streamer.ClassifyTaskStream(ctx, title, description, onDelta)
+		})
+	})
+}
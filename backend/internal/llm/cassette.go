@@ -0,0 +1,245 @@
+package llm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"sync"
+)
+
+// ErrCassetteMiss is returned by a strict Cassette for a request it has no
+// recording of.
+var ErrCassetteMiss = errors.New("no recorded llm response for request")
+
+// Interaction is one recorded request/response pair. Request headers are
+// not recorded, so API keys never end up in fixtures.
+type Interaction struct {
+	Key      string           `json:"-"`
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode int               `json:"status_code"`
+	Header     map[string]string `json:"header,omitempty"`
+	Body       json.RawMessage   `json:"body"`
+}
+
+// Cassette is an http.RoundTripper that replays recorded provider responses,
+// matched by a hash of the request method, path and JSON body (which holds
+// the model and prompt). The host is ignored so base URL overrides replay
+// too. Requests it has no recording of are sent to the live transport and
+// recorded, or fail with ErrCassetteMiss in strict mode.
+//
+// Hand it to SetHTTPClient before creating provider clients.
+type Cassette struct {
+	path   string
+	strict bool
+	live   http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	byKey        map[string]int
+	dirty        bool
+}
+
+// LoadCassette replays the fixture at path. A missing file is an empty
+// cassette. In strict mode nothing is sent to the network.
+func LoadCassette(path string, strict bool) (*Cassette, error) {
+	c := &Cassette{path: path, strict: strict, live: http.DefaultTransport, byKey: map[string]int{}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Interactions []Interaction `json:"interactions"`
+	}
+	if err := json.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	for _, in := range file.Interactions {
+		// Keys are computed on load so fixtures can be edited by hand.
+		u, err := neturl.Parse(in.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+		}
+		key, err := requestKey(in.Request.Method, u.Path, rawBody(in.Request.Body))
+		if err != nil {
+			return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+		}
+		in.Key = key
+		c.add(in)
+	}
+	return c, nil
+}
+
+// SetLiveTransport replaces the transport used for requests that aren't
+// recorded yet (http.DefaultTransport by default).
+func (c *Cassette) SetLiveTransport(rt http.RoundTripper) {
+	c.live = rt
+}
+
+// HTTPClient returns an http.Client that goes through the cassette.
+func (c *Cassette) HTTPClient() *http.Client {
+	return &http.Client{Transport: c}
+}
+
+func (c *Cassette) add(in Interaction) {
+	if i, ok := c.byKey[in.Key]; ok {
+		c.interactions[i] = in
+		return
+	}
+	c.byKey[in.Key] = len(c.interactions)
+	c.interactions = append(c.interactions, in)
+}
+
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+	url := req.URL.Scheme + "://" + req.URL.Host + req.URL.Path
+	key, err := requestKey(req.Method, req.URL.Path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	i, ok := c.byKey[key]
+	var in Interaction
+	if ok {
+		in = c.interactions[i]
+	}
+	c.mu.Unlock()
+	if ok {
+		return in.Response.toHTTP(req), nil
+	}
+	if c.strict {
+		return nil, fmt.Errorf("%w: %s %s", ErrCassetteMiss, req.Method, url)
+	}
+
+	live := req.Clone(req.Context())
+	live.Body = io.NopCloser(bytes.NewReader(body))
+	resp, err := c.live.RoundTrip(live)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	in = Interaction{
+		Key:      key,
+		Request:  RecordedRequest{Method: req.Method, URL: url, Body: asJSON(body)},
+		Response: RecordedResponse{StatusCode: resp.StatusCode, Body: asJSON(respBody)},
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		in.Response.Header = map[string]string{"Content-Type": ct}
+	}
+	c.mu.Lock()
+	c.add(in)
+	c.dirty = true
+	c.mu.Unlock()
+	return in.Response.toHTTP(req), nil
+}
+
+// Save writes the cassette back to its file if anything was recorded.
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+	b, err := json.MarshalIndent(struct {
+		Interactions []Interaction `json:"interactions"`
+	}{c.interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.path, append(b, '\n'), 0o644); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+func (r RecordedResponse) toHTTP(req *http.Request) *http.Response {
+	body := rawBody(r.Body)
+	resp := &http.Response{
+		StatusCode:    r.StatusCode,
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+	for k, v := range r.Header {
+		resp.Header.Set(k, v)
+	}
+	if resp.Header.Get("Content-Type") == "" {
+		resp.Header.Set("Content-Type", "application/json")
+	}
+	return resp
+}
+
+// requestKey hashes a request. JSON bodies are re-encoded first so key
+// order and whitespace don't matter.
+func requestKey(method, path string, body []byte) (string, error) {
+	if len(body) > 0 && json.Valid(body) {
+		var v any
+		if err := json.Unmarshal(body, &v); err != nil {
+			return "", err
+		}
+		body, _ = json.Marshal(v)
+	}
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\x00"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// rawBody reverses asJSON.
+func rawBody(m json.RawMessage) []byte {
+	var s string
+	if json.Unmarshal(m, &s) == nil {
+		return []byte(s)
+	}
+	return m
+}
+
+// asJSON stores b as-is when it's JSON and as a JSON string otherwise.
+func asJSON(b []byte) json.RawMessage {
+	if len(b) == 0 {
+		return nil
+	}
+	if json.Valid(b) {
+		return json.RawMessage(b)
+	}
+	s, _ := json.Marshal(string(b))
+	return s
+}
@@ -1,17 +1,42 @@
 package llm
 
-import "context"
+import (
+	"context"
+	"net/http"
+)
 
 // TaskClassification represents the result of an LLM classifying a task.
 type TaskClassification struct {
 	Tags     []string `json:"tags"`
-	Priority string   `json:"priority"` // "high", "medium", "low"
-	Category string   `json:"category"` // "bug", "feature", "improvement", "research"
+	Priority string   `json:"priority"` // one of Priorities
+	Category string   `json:"category"` // one of Categories
 	Summary  string   `json:"summary"`  // One-line summary
+
+	// Provider and Model identify who answered, e.g. "openai" / "gpt-4o-mini".
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+	// PromptVersion is the prompt template version the task was classified with.
+	PromptVersion string `json:"prompt_version,omitempty"`
 }
 
 // LLMClient defines the interface for AI-powered task classification.
-// Implementations should handle timeouts, retries, and malformed responses.
+// Provider clients make a single attempt; wrap them in ResilientClient for
+// timeouts, retries and malformed-response handling.
 type LLMClient interface {
 	ClassifyTask(ctx context.Context, title string, description string) (*TaskClassification, error)
 }
+
+// Completer is implemented by clients that can answer a free-form prompt.
+// ResilientClient uses it to ask the model to repair malformed output.
+type Completer interface {
+	Complete(ctx context.Context, prompt string) (string, error)
+}
+
+// httpClient, when set, replaces the default HTTP client of provider clients.
+var httpClient *http.Client
+
+// SetHTTPClient makes provider clients created afterwards send their requests
+// through c, e.g. a Cassette's client. nil restores the defaults.
+func SetHTTPClient(c *http.Client) {
+	httpClient = c
+}
@@ -0,0 +1,187 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RouteSpec is one LLM_PROVIDERS entry, written provider[:model][=weight],
+// e.g. "openai:gpt-4o-mini=80".
+type RouteSpec struct {
+	Provider string
+	Model    string
+	Weight   int
+}
+
+func (s RouteSpec) String() string {
+	if s.Model == "" {
+		return s.Provider
+	}
+	return s.Provider + ":" + s.Model
+}
+
+// ParseRoutes parses a comma-separated LLM_PROVIDERS value.
+func ParseRoutes(spec string) ([]RouteSpec, error) {
+	var routes []RouteSpec
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		var r RouteSpec
+		if name, weight, ok := strings.Cut(entry, "="); ok {
+			w, err := strconv.Atoi(strings.TrimSpace(weight))
+			if err != nil || w < 0 {
+				return nil, fmt.Errorf("invalid weight in llm route %q", entry)
+			}
+			r.Weight, entry = w, strings.TrimSpace(name)
+		}
+		r.Provider, r.Model, _ = strings.Cut(entry, ":")
+		if r.Provider == "" {
+			return nil, fmt.Errorf("missing provider in llm route %q", entry)
+		}
+		routes = append(routes, r)
+	}
+	if len(routes) == 0 {
+		return nil, errors.New("no llm routes configured")
+	}
+	return routes, nil
+}
+
+// NewProvider constructs a provider client by name. A non-empty model
+// overrides the provider's default model.
+func NewProvider(name, model string) (LLMClient, error) {
+	switch name {
+	case "openai":
+		c, err := NewOpenAIClient()
+		if err != nil {
+			return nil, err
+		}
+		if model != "" {
+			c.model = model
+		}
+		return c, nil
+	case "anthropic":
+		c, err := NewAnthropicClient()
+		if err != nil {
+			return nil, err
+		}
+		if model != "" {
+			c.model = model
+		}
+		return c, nil
+	case FlavorOpenAI, FlavorOllama:
+		c, err := NewLocalClientFromEnv(name)
+		if err != nil {
+			return nil, err
+		}
+		if model != "" {
+			c.model = model
+		}
+		return c, nil
+	case "mock":
+		return NewMockClient(), nil
+	default:
+		return nil, fmt.Errorf("unknown llm provider %q", name)
+	}
+}
+
+// providerModel returns the model a provider client calls.
+func providerModel(c LLMClient) string {
+	switch c := c.(type) {
+	case *OpenAIClient:
+		return c.model
+	case *AnthropicClient:
+		return c.model
+	case *LocalClient:
+		return c.model
+	}
+	return ""
+}
+
+// NewClientFromEnv builds the classification client. LLM_PROVIDERS lists
+// routes in fallback order with optional weights; otherwise LLM_PROVIDER picks
+// a single provider, or the providers with API keys are used (OpenAI first).
+// The mock is only used when listed or when nothing else is configured.
+// When cache is non-nil, each provider's answers are cached for LLM_CACHE_TTL
+// (default 168h; 0 disables caching). When usage is non-nil, every provider
+// call is recorded with it and subject to its token budget. Task text is
+// redacted as configured by NewRedactorFromEnv before it reaches any
+// provider, and what was redacted is reported to audit when non-nil.
+func NewClientFromEnv(cache CacheStore, usage UsageRecorder, audit RedactionAuditor) (LLMClient, error) {
+	ttl := 7 * 24 * time.Hour
+	if v := os.Getenv("LLM_CACHE_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid LLM_CACHE_TTL %q", v)
+		}
+		ttl = d
+	}
+	redactor, err := NewRedactorFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	var specs []RouteSpec
+	switch {
+	case os.Getenv("LLM_PROVIDERS") != "":
+		var err error
+		if specs, err = ParseRoutes(os.Getenv("LLM_PROVIDERS")); err != nil {
+			return nil, err
+		}
+	case os.Getenv("LLM_PROVIDER") != "":
+		specs = []RouteSpec{{Provider: os.Getenv("LLM_PROVIDER")}}
+	default:
+		if os.Getenv("OPENAI_API_KEY") != "" {
+			specs = append(specs, RouteSpec{Provider: "openai"})
+		}
+		if os.Getenv("ANTHROPIC_API_KEY") != "" {
+			specs = append(specs, RouteSpec{Provider: "anthropic"})
+		}
+		if len(specs) == 0 {
+			log.Printf("No LLM provider configured, using the mock classifier")
+			specs = []RouteSpec{{Provider: "mock"}}
+		}
+	}
+
+	var routes []Route
+	for _, spec := range specs {
+		c, err := NewProvider(spec.Provider, spec.Model)
+		if err != nil {
+			log.Printf("Skipping LLM provider %s: %v", spec, err)
+			continue
+		}
+		if _, isMock := c.(*MockClient); !isMock {
+			model := providerModel(c)
+			if usage != nil {
+				c = NewMeteringClient(c, spec.Provider, model, usage)
+			}
+			c = NewResilientClient(c, ResilientConfig{})
+			if cache != nil && ttl > 0 {
+				c = NewCachingClient(c, spec.Provider, model, cache, ttl)
+			}
+			if redactor != nil {
+				c = NewRedactingClient(c, redactor, audit)
+			}
+		}
+		routes = append(routes, Route{Name: spec.String(), Client: c, Weight: spec.Weight})
+	}
+	if len(routes) == 0 {
+		return nil, errors.New("none of the configured llm providers could be initialized")
+	}
+
+	names := make([]string, len(routes))
+	for i, r := range routes {
+		names[i] = r.Name
+	}
+	log.Printf("LLM providers: %s", strings.Join(names, ", "))
+	if len(routes) == 1 {
+		return routes[0].Client, nil
+	}
+	return NewRoutingClient(routes...)
+}
@@ -0,0 +1,258 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// Default embedding models.
+const (
+	DefaultOpenAIEmbeddingModel = openai.EmbeddingModelTextEmbedding3Small
+	DefaultOllamaEmbeddingModel = "nomic-embed-text"
+	mockEmbeddingDims           = 256
+)
+
+// Embeddings are vectors for a batch of texts, in input order. Vectors made
+// by different models can't be compared, so they carry the model's name.
+type Embeddings struct {
+	Model   string // provider:model, e.g. "openai:text-embedding-3-small"
+	Vectors [][]float32
+}
+
+// Embedder is implemented by clients configured to embed text, e.g. to find
+// duplicate tasks. Vectors are normalized to unit length, so their dot
+// product is their cosine similarity.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) (*Embeddings, error)
+}
+
+// NewEmbedderFromEnv builds the embedding client. EMBEDDING_PROVIDER picks
+// openai, local, ollama or mock, and "off" disables embeddings (nil is
+// returned). By default OpenAI is used when OPENAI_API_KEY is set and the
+// mock otherwise. EMBEDDING_MODEL overrides the provider's model. Unlike
+// classification there is a single provider: falling back to another model
+// would make vectors incomparable. Calls are metered, retried and redacted
+// like classification.
+func NewEmbedderFromEnv(usage UsageRecorder, audit RedactionAuditor) (Embedder, error) {
+	provider := os.Getenv("EMBEDDING_PROVIDER")
+	if provider == "" {
+		provider = "mock"
+		if os.Getenv("OPENAI_API_KEY") != "" {
+			provider = "openai"
+		}
+	}
+	model := os.Getenv("EMBEDDING_MODEL")
+
+	var c LLMClient
+	switch provider {
+	case "off":
+		log.Printf("Embeddings disabled")
+		return nil, nil
+	case "mock":
+		log.Printf("Embeddings: mock")
+		return NewMockClient(), nil
+	case "openai":
+		oc, err := NewOpenAIClient()
+		if err != nil {
+			return nil, err
+		}
+		oc.embeddingModel = model
+		if oc.embeddingModel == "" {
+			oc.embeddingModel = DefaultOpenAIEmbeddingModel
+		}
+		c, model = oc, oc.embeddingModel
+	case FlavorOpenAI, FlavorOllama:
+		lc, err := NewLocalClientFromEnv(provider)
+		if err != nil {
+			return nil, err
+		}
+		lc.embeddingModel = model
+		if lc.embeddingModel == "" {
+			if provider != FlavorOllama {
+				return nil, errors.New("EMBEDDING_MODEL is required for local embeddings")
+			}
+			lc.embeddingModel = DefaultOllamaEmbeddingModel
+		}
+		c, model = lc, lc.embeddingModel
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q", provider)
+	}
+
+	redactor, err := NewRedactorFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if usage != nil {
+		c = NewMeteringClient(c, provider, model, usage)
+	}
+	c = NewResilientClient(c, ResilientConfig{})
+	if redactor != nil {
+		c = NewRedactingClient(c, redactor, audit)
+	}
+	log.Printf("Embeddings: %s:%s", provider, model)
+	return c.(Embedder), nil
+}
+
+// Normalize scales v to unit length in place; zero vectors are left as is.
+func Normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= norm
+	}
+	return v
+}
+
+// Cosine returns the cosine similarity of two vectors of the same length.
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+func checkEmbeddings(provider string, texts []string, vectors [][]float32) error {
+	if len(vectors) != len(texts) {
+		return fmt.Errorf("%s returned %d embeddings for %d texts", provider, len(vectors), len(texts))
+	}
+	for _, v := range vectors {
+		if len(v) == 0 || len(v) != len(vectors[0]) {
+			return fmt.Errorf("%s returned embeddings of inconsistent dimensions", provider)
+		}
+	}
+	return nil
+}
+
+func (c *OpenAIClient) Embed(ctx context.Context, texts []string) (*Embeddings, error) {
+	if c.embeddingModel == "" {
+		return nil, ErrUnsupported
+	}
+	resp, err := c.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Model: c.embeddingModel,
+		Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: texts},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("openai sdk error: %w", err)
+	}
+	reportUsage(ctx, resp.Usage.PromptTokens, 0)
+
+	vectors := make([][]float32, len(resp.Data))
+	for _, d := range resp.Data {
+		if d.Index < 0 || int(d.Index) >= len(vectors) {
+			return nil, fmt.Errorf("openai returned an embedding for unknown input %d", d.Index)
+		}
+		v := make([]float32, len(d.Embedding))
+		for i, x := range d.Embedding {
+			v[i] = float32(x)
+		}
+		vectors[d.Index] = Normalize(v)
+	}
+	if err := checkEmbeddings("openai", texts, vectors); err != nil {
+		return nil, err
+	}
+	return &Embeddings{Model: "openai:" + c.embeddingModel, Vectors: vectors}, nil
+}
+
+func (c *LocalClient) Embed(ctx context.Context, texts []string) (*Embeddings, error) {
+	if c.embeddingModel == "" {
+		return nil, ErrUnsupported
+	}
+	var vectors [][]float32
+	if c.flavor == FlavorOllama {
+		raw, err := c.post(ctx, c.baseURL+"/api/embed", map[string]any{"model": c.embeddingModel, "input": texts})
+		if err != nil {
+			return nil, err
+		}
+		var r struct {
+			Embeddings      [][]float32 `json:"embeddings"`
+			PromptEvalCount int64       `json:"prompt_eval_count"`
+		}
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return nil, fmt.Errorf("invalid ollama embed response: %w", err)
+		}
+		reportUsage(ctx, r.PromptEvalCount, 0)
+		vectors = r.Embeddings
+	} else {
+		raw, err := c.post(ctx, c.baseURL+"/embeddings", map[string]any{"model": c.embeddingModel, "input": texts})
+		if err != nil {
+			return nil, err
+		}
+		var r struct {
+			Data []struct {
+				Embedding []float32 `json:"embedding"`
+				Index     int       `json:"index"`
+			} `json:"data"`
+			Usage struct {
+				PromptTokens int64 `json:"prompt_tokens"`
+			} `json:"usage"`
+		}
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return nil, fmt.Errorf("invalid embeddings response: %w", err)
+		}
+		reportUsage(ctx, r.Usage.PromptTokens, 0)
+		sort.SliceStable(r.Data, func(i, j int) bool { return r.Data[i].Index < r.Data[j].Index })
+		for _, d := range r.Data {
+			vectors = append(vectors, d.Embedding)
+		}
+	}
+	if err := checkEmbeddings(c.flavor, texts, vectors); err != nil {
+		return nil, err
+	}
+	for _, v := range vectors {
+		Normalize(v)
+	}
+	return &Embeddings{Model: c.flavor + ":" + c.embeddingModel, Vectors: vectors}, nil
+}
+
+// Embed hashes each text's words and character trigrams into a fixed-size
+// vector. It is deterministic, needs no model, and texts sharing most of
+// their words score close to 1.
+func (m *MockClient) Embed(ctx context.Context, texts []string) (*Embeddings, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, mockEmbeddingDims)
+		for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			v[mockBucket(w)] += 2
+			runes := []rune(" " + w + " ")
+			for j := 0; j+3 <= len(runes); j++ {
+				v[mockBucket(string(runes[j:j+3]))]++
+			}
+		}
+		vectors[i] = Normalize(v)
+	}
+	return &Embeddings{Model: "mock", Vectors: vectors}, nil
+}
+
+func mockBucket(s string) int {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return int(h.Sum32() % mockEmbeddingDims)
+}
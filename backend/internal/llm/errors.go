@@ -0,0 +1,77 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go/v3"
+)
+
+// MalformedResponseError is returned when a provider answered but its output
+// could not be parsed into a classification. Output keeps the raw answer so
+// callers can ask the model to repair it.
+type MalformedResponseError struct {
+	Provider string
+	Model    string
+	Output   string
+	Err      error
+}
+
+func (e *MalformedResponseError) Error() string {
+	return fmt.Sprintf("failed parsing %s output: %v", e.Provider, e.Err)
+}
+
+func (e *MalformedResponseError) Unwrap() error { return e.Err }
+
+// HTTPStatusError is returned by HTTP-based clients for non-2xx responses.
+type HTTPStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("llm http status %d: %s", e.StatusCode, e.Body)
+}
+
+// StatusCode extracts the HTTP status of a provider error, or 0 if err does
+// not carry one.
+func StatusCode(err error) int {
+	var oe *openai.Error
+	if errors.As(err, &oe) {
+		return oe.StatusCode
+	}
+	var ae *anthropic.Error
+	if errors.As(err, &ae) {
+		return ae.StatusCode
+	}
+	var he *HTTPStatusError
+	if errors.As(err, &he) {
+		return he.StatusCode
+	}
+	return 0
+}
+
+// IsTransient reports whether err is worth retrying: rate limits, server
+// errors, timeouts and dropped connections.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch code := StatusCode(err); {
+	case code == 408, code == 409, code == 429, code >= 500:
+		return true
+	case code != 0:
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
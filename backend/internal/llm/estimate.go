@@ -0,0 +1,165 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// MaxEffortHours is the largest estimate the tasks table can store.
+const MaxEffortHours = 999.99
+
+// EffortExample is a completed task the model can compare against.
+type EffortExample struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Category    string   `json:"category,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	ActualHours float64  `json:"actual_hours"`
+}
+
+// EffortRequest is a task to estimate along with similar completed tasks
+// and, when there are any, the estimate derived from them.
+type EffortRequest struct {
+	Title       string
+	Description string
+	Category    string
+	Tags        []string
+	Examples    []EffortExample
+	Baseline    *float64 // hours estimated from Examples
+}
+
+// EffortEstimate is the model's estimate in hours, with an interval it
+// expects the actual effort to fall in.
+type EffortEstimate struct {
+	Hours     float64 `json:"hours"`
+	Low       float64 `json:"low"`
+	High      float64 `json:"high"`
+	Rationale string  `json:"rationale"`
+
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+}
+
+// EffortEstimator is implemented by clients that can estimate the effort a
+// task takes.
+type EffortEstimator interface {
+	EstimateEffort(ctx context.Context, req EffortRequest) (*EffortEstimate, error)
+}
+
+func buildEffortPrompt(req EffortRequest) string {
+	var b strings.Builder
+	b.WriteString(`Estimate how many hours of work the task below takes.
+Reply with only a JSON object of the form
+{"hours": 0, "low": 0, "high": 0, "rationale": "..."}
+
+- hours: your best estimate.
+- low, high: a range you are 80% sure the actual effort falls in, with low <= hours <= high.
+- rationale: one sentence explaining the estimate.
+`)
+	if len(req.Examples) > 0 {
+		b.WriteString("\nSimilar completed tasks and the hours they actually took:\n")
+		for _, ex := range req.Examples {
+			line, _ := json.Marshal(ex)
+			b.Write(line)
+			b.WriteByte('\n')
+		}
+	}
+	if req.Baseline != nil {
+		fmt.Fprintf(&b, "\nThose tasks suggest about %.1f hours; adjust it only for clear differences in scope.\n", *req.Baseline)
+	}
+	fmt.Fprintf(&b, "\nTask:\nTitle: %s\nDescription: %s\n", req.Title, req.Description)
+	if req.Category != "" {
+		fmt.Fprintf(&b, "Category: %s\n", req.Category)
+	}
+	if len(req.Tags) > 0 {
+		fmt.Fprintf(&b, "Tags: %s\n", strings.Join(req.Tags, ", "))
+	}
+	return b.String()
+}
+
+// estimateEffortWith asks completer for an estimate; provider clients
+// implement EffortEstimator with it.
+func estimateEffortWith(ctx context.Context, completer Completer, provider, model string, req EffortRequest) (*EffortEstimate, error) {
+	out, err := completer.Complete(ctx, buildEffortPrompt(req))
+	if err != nil {
+		return nil, err
+	}
+	var e EffortEstimate
+	if err := decodeStrict(out, &e); err != nil {
+		return nil, &MalformedResponseError{Provider: provider, Model: model, Output: out, Err: err}
+	}
+	e.Provider, e.Model = provider, model
+	if err := ValidateEffortEstimate(&e); err != nil {
+		return nil, &MalformedResponseError{Provider: provider, Model: model, Output: out, Err: err}
+	}
+	return &e, nil
+}
+
+// ValidateEffortEstimate rounds e to hundredths of an hour and checks that
+// the interval is positive, ordered and storable.
+func ValidateEffortEstimate(e *EffortEstimate) error {
+	e.Hours, e.Low, e.High = roundHours(e.Hours), roundHours(e.Low), roundHours(e.High)
+	e.Rationale = strings.TrimSpace(e.Rationale)
+
+	if e.Hours <= 0 || e.Hours > MaxEffortHours {
+		return &ValidationError{Field: "hours", Value: fmt.Sprint(e.Hours), Reason: fmt.Sprintf("must be between 0 and %v", MaxEffortHours)}
+	}
+	if e.Low <= 0 || e.Low > e.Hours {
+		return &ValidationError{Field: "low", Value: fmt.Sprint(e.Low), Reason: "must be positive and at most hours"}
+	}
+	if e.High < e.Hours || e.High > MaxEffortHours {
+		return &ValidationError{Field: "high", Value: fmt.Sprint(e.High), Reason: fmt.Sprintf("must be between hours and %v", MaxEffortHours)}
+	}
+	return nil
+}
+
+func roundHours(h float64) float64 {
+	return math.Round(h*100) / 100
+}
+
+func (c *OpenAIClient) EstimateEffort(ctx context.Context, req EffortRequest) (*EffortEstimate, error) {
+	return estimateEffortWith(ctx, c, "openai", c.model, req)
+}
+
+func (c *AnthropicClient) EstimateEffort(ctx context.Context, req EffortRequest) (*EffortEstimate, error) {
+	return estimateEffortWith(ctx, c, "anthropic", c.model, req)
+}
+
+func (c *LocalClient) EstimateEffort(ctx context.Context, req EffortRequest) (*EffortEstimate, error) {
+	return estimateEffortWith(ctx, c, c.flavor, c.model, req)
+}
+
+// EstimateEffort takes the median of the examples' actual hours, or else
+// guesses from the category, with an interval of half to twice that.
+func (m *MockClient) EstimateEffort(ctx context.Context, req EffortRequest) (*EffortEstimate, error) {
+	e := &EffortEstimate{Provider: "mock"}
+	switch {
+	case req.Baseline != nil:
+		e.Hours = *req.Baseline
+		e.Rationale = "Kept the estimate from similar tasks."
+	case len(req.Examples) > 0:
+		hours := make([]float64, len(req.Examples))
+		for i, ex := range req.Examples {
+			hours[i] = ex.ActualHours
+		}
+		sort.Float64s(hours)
+		e.Hours = hours[len(hours)/2]
+		e.Rationale = "Median of similar tasks."
+	default:
+		e.Hours = map[string]float64{"bug": 3, "improvement": 4, "research": 6, "feature": 8}[req.Category]
+		if e.Hours == 0 {
+			e.Hours = 4
+		}
+		e.Rationale = "Typical effort for the category."
+	}
+	e.Hours = math.Min(math.Max(e.Hours, 0.25), MaxEffortHours/2)
+	e.Low, e.High = e.Hours/2, e.Hours*2
+	if err := ValidateEffortEstimate(e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
@@ -0,0 +1,200 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Local API flavors.
+const (
+	FlavorOpenAI = "local"  // OpenAI-compatible /chat/completions (vLLM, llama.cpp, LM Studio...)
+	FlavorOllama = "ollama" // Ollama's native /api/chat
+)
+
+// LocalClient talks to a self-hosted model over HTTP so task text never
+// leaves our infrastructure. No API key is required.
+type LocalClient struct {
+	flavor         string
+	baseURL        string
+	model          string
+	embeddingModel string
+	apiKey         string
+	http           *http.Client
+}
+
+// NewLocalClient returns a client for the given flavor. baseURL is the server
+// root for Ollama (e.g. http://localhost:11434) and the API root for
+// OpenAI-compatible servers (e.g. http://localhost:8000/v1).
+func NewLocalClient(flavor, baseURL, model string) (*LocalClient, error) {
+	if flavor != FlavorOpenAI && flavor != FlavorOllama {
+		return nil, fmt.Errorf("unknown local llm flavor %q", flavor)
+	}
+	if baseURL == "" {
+		return nil, errors.New("local llm base URL not set")
+	}
+	if flavor == FlavorOllama && model == "" {
+		return nil, errors.New("ollama requires a model")
+	}
+	c := &LocalClient{
+		flavor:  flavor,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		model:   model,
+		http:    &http.Client{Timeout: 2 * time.Minute},
+	}
+	if httpClient != nil {
+		c.http = httpClient
+	}
+	return c, nil
+}
+
+// NewLocalClientFromEnv configures a local client from LOCAL_LLM_BASE_URL,
+// LOCAL_LLM_MODEL and the optional LOCAL_LLM_API_KEY. Ollama defaults to
+// http://localhost:11434 and llama3.1.
+func NewLocalClientFromEnv(flavor string) (*LocalClient, error) {
+	baseURL := os.Getenv("LOCAL_LLM_BASE_URL")
+	model := os.Getenv("LOCAL_LLM_MODEL")
+	if flavor == FlavorOllama {
+		if baseURL == "" {
+			baseURL = "http://localhost:11434"
+		}
+		if model == "" {
+			model = "llama3.1"
+		}
+	}
+	c, err := NewLocalClient(flavor, baseURL, model)
+	if err != nil {
+		return nil, err
+	}
+	c.apiKey = os.Getenv("LOCAL_LLM_API_KEY")
+	return c, nil
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+func (c *LocalClient) ClassifyTask(ctx context.Context, title, description string) (*TaskClassification, error) {
+	out, err := c.chat(ctx, buildPrompt(ctx, title, description), ClassificationSchema)
+	if err != nil {
+		return nil, err
+	}
+	return parseClassification(c.flavor, c.model, out)
+}
+
+// Complete sends a single-message chat and returns the model's reply.
+func (c *LocalClient) Complete(ctx context.Context, prompt string) (string, error) {
+	return c.chat(ctx, prompt, nil)
+}
+
+// chat sends prompt and returns the reply, constrained to schema when given.
+func (c *LocalClient) chat(ctx context.Context, prompt string, schema map[string]any) (string, error) {
+	messages := []chatMessage{{Role: "user", Content: prompt}}
+
+	var url string
+	var body interface{}
+	if c.flavor == FlavorOllama {
+		url = c.baseURL + "/api/chat"
+		req := map[string]interface{}{
+			"model":    c.model,
+			"messages": messages,
+			"stream":   false,
+			"options":  map[string]interface{}{"temperature": 0},
+		}
+		if schema != nil {
+			req["format"] = schema
+		}
+		body = req
+	} else {
+		url = c.baseURL + "/chat/completions"
+		req := map[string]interface{}{"messages": messages, "temperature": 0}
+		if c.model != "" {
+			req["model"] = c.model
+		}
+		if schema != nil {
+			req["response_format"] = map[string]interface{}{
+				"type":        "json_schema",
+				"json_schema": map[string]interface{}{"name": "task_classification", "schema": schema, "strict": true},
+			}
+		}
+		body = req
+	}
+
+	raw, err := c.post(ctx, url, body)
+	if err != nil {
+		return "", err
+	}
+
+	var out string
+	if c.flavor == FlavorOllama {
+		var r struct {
+			Message         chatMessage `json:"message"`
+			PromptEvalCount int64       `json:"prompt_eval_count"`
+			EvalCount       int64       `json:"eval_count"`
+		}
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return "", fmt.Errorf("invalid ollama response: %w", err)
+		}
+		reportUsage(ctx, r.PromptEvalCount, r.EvalCount)
+		out = r.Message.Content
+	} else {
+		var r struct {
+			Choices []struct {
+				Message chatMessage `json:"message"`
+			} `json:"choices"`
+			Usage struct {
+				PromptTokens     int64 `json:"prompt_tokens"`
+				CompletionTokens int64 `json:"completion_tokens"`
+			} `json:"usage"`
+		}
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return "", fmt.Errorf("invalid chat completions response: %w", err)
+		}
+		reportUsage(ctx, r.Usage.PromptTokens, r.Usage.CompletionTokens)
+		if len(r.Choices) > 0 {
+			out = r.Choices[0].Message.Content
+		}
+	}
+	if strings.TrimSpace(out) == "" {
+		return "", fmt.Errorf("empty response from %s", c.flavor)
+	}
+	return out, nil
+}
+
+// post sends body as JSON to url and returns the response body of a 2xx
+// response.
+func (c *LocalClient) post(ctx context.Context, url string, body any) ([]byte, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytesReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request failed: %w", c.flavor, err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%s response read failed: %w", c.flavor, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &HTTPStatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(raw))}
+	}
+	return raw, nil
+}
@@ -2,7 +2,11 @@ package llm
 
 import (
 	"context"
+	"regexp"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 // MockClient provides predictable responses for testing without an API key.
@@ -21,6 +25,7 @@ func (m *MockClient) ClassifyTask(ctx context.Context, title, description string
 		Priority: "medium",
 		Category: "feature",
 		Summary:  "Task: " + title,
+		Provider: "mock",
 	}
 
     // Determine category based on keywords. Check for improvements first so
@@ -84,3 +89,70 @@ func containsAny(s string, keywords ...string) bool {
 	}
 	return false
 }
+
+var (
+	mockPriorities = []struct {
+		priority string
+		pattern  *regexp.Regexp
+	}{
+		{"urgent", regexp.MustCompile(`(?i)\b(?:urgent(?:ly)?|asap|immediately)\b`)},
+		{"high", regexp.MustCompile(`(?i)\b(?:high priority|important)\b`)},
+		{"low", regexp.MustCompile(`(?i)\b(?:low priority|nice to have|eventually)\b`)},
+	}
+	mockDue      = regexp.MustCompile(`(?i)\b(?:(?:by|on|before|due)\s+)?(today|tomorrow|monday|tuesday|wednesday|thursday|friday|saturday|sunday|\d{4}-\d{2}-\d{2})\b`)
+	mockAssignee = regexp.MustCompile(`^(\p{Lu}[\p{L}'-]*)\s+(?:should|must|needs to|has to|will|can)\s+`)
+	mockMention  = regexp.MustCompile(`(?i)(?:\bassign(?:ed)? to\s+|@)([\p{L}][\p{L}.'-]*(?:@[\w.-]+)?)`)
+)
+
+// ParseTask understands a few fixed phrasings: "<Name> should ...",
+// "@name" or "assign to <name>", "by <weekday|today|tomorrow|date>" and
+// priority words such as "urgent" or "low priority".
+func (m *MockClient) ParseTask(ctx context.Context, text string, opts ParseOptions) (*ParsedTask, error) {
+	p := &ParsedTask{Provider: "mock"}
+	rest := strings.TrimSpace(text)
+
+	for _, mp := range mockPriorities {
+		if mp.pattern.MatchString(rest) {
+			p.Priority = mp.priority
+			rest = mp.pattern.ReplaceAllString(rest, "")
+			break
+		}
+	}
+	if match := mockDue.FindStringSubmatch(rest); match != nil {
+		p.DueDate = mockResolveDay(strings.ToLower(match[1]), opts.Now)
+		rest = strings.Replace(rest, match[0], "", 1)
+	}
+	if match := mockAssignee.FindStringSubmatch(rest); match != nil {
+		p.Assignee = match[1]
+		rest = rest[len(match[0]):]
+	} else if match := mockMention.FindStringSubmatch(rest); match != nil {
+		p.Assignee = match[1]
+		rest = strings.Replace(rest, match[0], "", 1)
+	}
+
+	title := strings.Trim(strings.Join(strings.Fields(rest), " "), " ,.;:-")
+	title = strings.ReplaceAll(title, " ,", ",")
+	if r, size := utf8.DecodeRuneInString(title); size > 0 {
+		title = string(unicode.ToUpper(r)) + title[size:]
+	}
+	p.Title = title
+	if err := ValidateParsedTask(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func mockResolveDay(day string, now time.Time) string {
+	switch day {
+	case "today":
+		return now.Format("2006-01-02")
+	case "tomorrow":
+		return now.AddDate(0, 0, 1).Format("2006-01-02")
+	}
+	for d := 0; d < 7; d++ {
+		if next := now.AddDate(0, 0, d); strings.ToLower(next.Weekday().String()) == day {
+			return next.Format("2006-01-02")
+		}
+	}
+	return day // already a date
+}
@@ -2,10 +2,10 @@ package llm
 
 import (
     "context"
-    "encoding/json"
     "errors"
     "fmt"
     "os"
+    "strings"
 
     "github.com/openai/openai-go/v3"
     "github.com/openai/openai-go/v3/option"
@@ -14,8 +14,9 @@ import (
 
 // OpenAIClient uses the official openai-go SDK.
 type OpenAIClient struct {
-    client openai.Client
-    model  string
+    client         openai.Client
+    model          string
+    embeddingModel string
 }
 
 // NewOpenAIClient returns a configured OpenAI client or error if API key missing.
@@ -29,65 +30,91 @@ func NewOpenAIClient() (*OpenAIClient, error) {
     if model == "" {
         model = "gpt-3.5-turbo"
     }
-    cli := openai.NewClient(option.WithAPIKey(key))
+    // retries are handled by ResilientClient
+    opts := []option.RequestOption{option.WithAPIKey(key), option.WithMaxRetries(0)}
+    if httpClient != nil {
+        opts = append(opts, option.WithHTTPClient(httpClient))
+    }
+    cli := openai.NewClient(opts...)
     return &OpenAIClient{client: cli, model: model}, nil
 }
 
 func (c *OpenAIClient) ClassifyTask(ctx context.Context, title, description string) (*TaskClassification, error) {
-    prompt := buildPrompt(title, description)
+    out, err := c.respond(ctx, c.classifyParams(ctx, title, description))
+    if err != nil {
+        return nil, err
+    }
+    return parseClassification("openai", c.model, out)
+}
+
+// classifyParams builds the classification request, constrained to the
+// classification schema where the model supports it.
+func (c *OpenAIClient) classifyParams(ctx context.Context, title, description string) responses.ResponseNewParams {
+    params := c.params(buildPrompt(ctx, title, description))
+    if supportsStructuredOutput(c.model) {
+        params.Text = responses.ResponseTextConfigParam{
+            Format: responses.ResponseFormatTextConfigUnionParam{
+                OfJSONSchema: &responses.ResponseFormatTextJSONSchemaConfigParam{
+                    Name:   "task_classification",
+                    Schema: ClassificationSchema,
+                    Strict: openai.Bool(true),
+                },
+            },
+        }
+    }
+    return params
+}
+
+// Complete sends a free-form prompt and returns the model's text output.
+func (c *OpenAIClient) Complete(ctx context.Context, prompt string) (string, error) {
+    return c.respond(ctx, c.params(prompt))
+}
 
-    resp, err := c.client.Responses.New(ctx, responses.ResponseNewParams{
+func (c *OpenAIClient) params(prompt string) responses.ResponseNewParams {
+    return responses.ResponseNewParams{
         Input: responses.ResponseNewParamsInputUnion{OfString: openai.String(prompt)},
         Model: c.model,
-    })
+    }
+}
+
+func (c *OpenAIClient) respond(ctx context.Context, params responses.ResponseNewParams) (string, error) {
+    resp, err := c.client.Responses.New(ctx, params)
     if err != nil {
-        return nil, fmt.Errorf("openai sdk error: %w", err)
+        return "", fmt.Errorf("openai sdk error: %w", err)
     }
+    reportUsage(ctx, resp.Usage.InputTokens, resp.Usage.OutputTokens)
 
     out := resp.OutputText()
     if out == "" {
-        return nil, errors.New("empty response from openai")
-    }
-
-    var tc TaskClassification
-    if err := parseJSONFromString(out, &tc); err != nil {
-        return nil, fmt.Errorf("failed parsing openai output: %w", err)
+        return "", errors.New("empty response from openai")
     }
-    return &tc, nil
+    return out, nil
 }
 
-// Helper: build prompt
-func buildPrompt(title, description string) string {
-    return fmt.Sprintf(`Classify the following task. Reply with JSON only.
-{
-  "tags": ["tag1","tag2"],
-  "priority": "low|medium|high|urgent",
-  "category": "bug|feature|improvement|research",
-  "summary": "one-line summary"
+// supportsStructuredOutput reports whether model accepts a JSON schema
+// response format; older models only get the schema in the prompt.
+func supportsStructuredOutput(model string) bool {
+    return !strings.HasPrefix(model, "gpt-3.5") && !strings.HasPrefix(model, "gpt-4-") && model != "gpt-4"
 }
 
-Title: %s
+// buildRepairPrompt asks the model to turn a malformed answer into valid JSON.
+func buildRepairPrompt(output string) string {
+    return fmt.Sprintf(`Your previous answer could not be parsed as JSON. Reply again with only a JSON object of the form
+{"tags": ["tag1","tag2"], "priority": "low|medium|high|urgent", "category": "bug|feature|improvement|research", "summary": "one-line summary"}
 
-Description: %s
-`, title, description)
+Previous answer:
+%s
+`, output)
 }
 
-// parseJSONFromString finds the first JSON object in s and unmarshals it into v.
-func parseJSONFromString(s string, v interface{}) error {
-    // naive approach: find first '{' and last '}'
-    start := -1
-    end := -1
-    for i, ch := range s {
-        if ch == '{' && start == -1 {
-            start = i
-        }
-        if ch == '}' {
-            end = i
-        }
-    }
-    if start == -1 || end == -1 || end <= start {
-        return errors.New("no JSON object found in response")
+// parseClassification parses a provider's answer into a classification
+// attributed to provider and model. Answers that aren't a valid
+// classification are returned as *MalformedResponseError.
+func parseClassification(provider, model, out string) (*TaskClassification, error) {
+    tc, err := decodeClassification(out)
+    if err != nil {
+        return nil, &MalformedResponseError{Provider: provider, Model: model, Output: out, Err: err}
     }
-    sub := s[start:end+1]
-    return json.Unmarshal([]byte(sub), v)
+    tc.Provider, tc.Model = provider, model
+    return tc, nil
 }
@@ -0,0 +1,119 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrUnsupported is returned by decorators when the client they wrap lacks
+// the requested capability, e.g. a provider that can't parse tasks.
+var ErrUnsupported = errors.New("not supported by the configured llm provider")
+
+// capability calls call with next's implementation of C, or returns
+// ErrUnsupported when next has none.
+func capability[C, R any](next LLMClient, call func(C) (R, error)) (R, error) {
+	impl, ok := next.(C)
+	if !ok {
+		var zero R
+		return zero, ErrUnsupported
+	}
+	return call(impl)
+}
+
+// MaxTitleLen is the longest task title, in bytes, the API accepts.
+const MaxTitleLen = 500
+
+// ParsedTask is a task described in free text, as understood by the model.
+// Nothing in it is resolved yet: the assignee is the name as written and the
+// due date is local to the user.
+type ParsedTask struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Assignee    string `json:"assignee"` // name or email as written, "" when nobody is named
+	DueDate     string `json:"due_date"` // YYYY-MM-DD or YYYY-MM-DDTHH:MM in the user's timezone, "" when none
+	Priority    string `json:"priority"` // "" when the text doesn't say
+
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+}
+
+// ParseOptions gives the model what it needs to resolve relative dates.
+type ParseOptions struct {
+	Now time.Time // in the user's location
+}
+
+// TaskParser is implemented by clients that can turn free text such as
+// "Lucía should fix the date filter by Friday, urgent" into a task.
+type TaskParser interface {
+	ParseTask(ctx context.Context, text string, opts ParseOptions) (*ParsedTask, error)
+}
+
+func buildParsePrompt(text string, opts ParseOptions) string {
+	return fmt.Sprintf(`Turn the request below into a task. Today is %s (timezone %s).
+Reply with only a JSON object of the form
+{"title": "...", "description": "...", "assignee": "...", "due_date": "...", "priority": "..."}
+
+- title: a short imperative title, without the assignee, deadline or priority.
+- description: any further detail given in the request, otherwise "".
+- assignee: the person who should do the task, exactly as written (name or email), otherwise "".
+- due_date: the deadline resolved against today's date, as YYYY-MM-DD, or YYYY-MM-DDTHH:MM when a time is given, otherwise "". A weekday means the next one on or after today.
+- priority: low, medium, high or urgent when the request states or clearly implies one, otherwise "".
+
+Request:
+%s
+`, opts.Now.Format("Monday, 2006-01-02 15:04"), opts.Now.Location(), text)
+}
+
+// parseTaskWith asks completer to parse text; provider clients implement
+// TaskParser with it.
+func parseTaskWith(ctx context.Context, completer Completer, provider, model, text string, opts ParseOptions) (*ParsedTask, error) {
+	out, err := completer.Complete(ctx, buildParsePrompt(text, opts))
+	if err != nil {
+		return nil, err
+	}
+	var p ParsedTask
+	if err := decodeStrict(out, &p); err != nil {
+		return nil, &MalformedResponseError{Provider: provider, Model: model, Output: out, Err: err}
+	}
+	p.Provider, p.Model = provider, model
+	if err := ValidateParsedTask(&p); err != nil {
+		return nil, &MalformedResponseError{Provider: provider, Model: model, Output: out, Err: err}
+	}
+	return &p, nil
+}
+
+// ValidateParsedTask trims p and checks that it has a usable title and, if
+// any, a known priority.
+func ValidateParsedTask(p *ParsedTask) error {
+	p.Title = strings.Join(strings.Fields(p.Title), " ")
+	p.Description = strings.TrimSpace(p.Description)
+	p.Assignee = strings.TrimPrefix(strings.TrimSpace(p.Assignee), "@")
+	p.DueDate = strings.TrimSpace(p.DueDate)
+	p.Priority = strings.ToLower(strings.TrimSpace(p.Priority))
+
+	if p.Title == "" {
+		return &ValidationError{Field: "title", Reason: "is required"}
+	}
+	if len(p.Title) > MaxTitleLen {
+		return &ValidationError{Field: "title", Value: p.Title, Reason: fmt.Sprintf("must be at most %d characters", MaxTitleLen)}
+	}
+	if p.Priority != "" && !contains(Priorities, p.Priority) {
+		return &ValidationError{Field: "priority", Value: p.Priority, Reason: "must be one of " + strings.Join(Priorities, ", ")}
+	}
+	return nil
+}
+
+func (c *OpenAIClient) ParseTask(ctx context.Context, text string, opts ParseOptions) (*ParsedTask, error) {
+	return parseTaskWith(ctx, c, "openai", c.model, text, opts)
+}
+
+func (c *AnthropicClient) ParseTask(ctx context.Context, text string, opts ParseOptions) (*ParsedTask, error) {
+	return parseTaskWith(ctx, c, "anthropic", c.model, text, opts)
+}
+
+func (c *LocalClient) ParseTask(ctx context.Context, text string, opts ParseOptions) (*ParsedTask, error) {
+	return parseTaskWith(ctx, c, c.flavor, c.model, text, opts)
+}
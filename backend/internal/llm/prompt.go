@@ -0,0 +1,112 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"text/template"
+)
+
+// PromptVersion is the version of DefaultPromptTemplate, used when no
+// template version is active.
+const PromptVersion = "builtin"
+
+// DefaultPromptTemplate is the built-in classification prompt. Templates are
+// rendered with PromptData; the json function formats a classification.
+const DefaultPromptTemplate = `Classify the following task. Reply with JSON only.
+{
+  "tags": ["tag1","tag2"],
+  "priority": "low|medium|high|urgent",
+  "category": "bug|feature|improvement|research",
+  "summary": "one-line summary"
+}
+{{range .Examples}}
+Example:
+Title: {{.Title}}
+Description: {{.Description}}
+Answer: {{json .Classification}}
+{{end}}
+Title: {{.Title}}
+
+Description: {{.Description}}
+`
+
+// PromptExample is a past classification shown to the model as a few-shot example.
+type PromptExample struct {
+	Title          string
+	Description    string
+	Classification TaskClassification
+}
+
+// PromptData is what classification templates are rendered with.
+type PromptData struct {
+	Title       string
+	Description string
+	Examples    []PromptExample
+}
+
+// PromptTemplate is a parsed template version with the few-shot examples
+// to render it with.
+type PromptTemplate struct {
+	Version  string
+	Examples []PromptExample
+	tmpl     *template.Template
+}
+
+var promptFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// NewPromptTemplate parses text as a text/template and checks that it renders.
+func NewPromptTemplate(version, text string, examples []PromptExample) (*PromptTemplate, error) {
+	tmpl, err := template.New(version).Funcs(promptFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &PromptTemplate{Version: version, Examples: examples, tmpl: tmpl}
+	if _, err := p.Render("Example title", "Example description"); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Render renders the prompt for a task.
+func (p *PromptTemplate) Render(title, description string) (string, error) {
+	var b strings.Builder
+	err := p.tmpl.Execute(&b, PromptData{Title: title, Description: description, Examples: p.Examples})
+	return b.String(), err
+}
+
+var defaultPrompt, _ = NewPromptTemplate(PromptVersion, DefaultPromptTemplate, nil)
+
+type promptKey struct{}
+
+// WithPromptTemplate makes the clients called with ctx classify using p
+// instead of the built-in prompt.
+func WithPromptTemplate(ctx context.Context, p *PromptTemplate) context.Context {
+	return context.WithValue(ctx, promptKey{}, p)
+}
+
+// PromptTemplateFrom returns the template set on ctx, or the built-in one.
+func PromptTemplateFrom(ctx context.Context) *PromptTemplate {
+	if p, ok := ctx.Value(promptKey{}).(*PromptTemplate); ok && p != nil {
+		return p
+	}
+	return defaultPrompt
+}
+
+// buildPrompt renders the classification prompt for ctx, falling back to
+// the built-in template if the configured one fails to render.
+func buildPrompt(ctx context.Context, title, description string) string {
+	p := PromptTemplateFrom(ctx)
+	out, err := p.Render(title, description)
+	if err != nil {
+		log.Printf("prompt template %s failed to render, using the built-in prompt: %v", p.Version, err)
+		out, _ = defaultPrompt.Render(title, description)
+	}
+	return out
+}
@@ -0,0 +1,414 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Built-in detector kinds, in the order they run.
+const (
+	RedactToken = "token"
+	RedactEmail = "email"
+	RedactCard  = "card"
+	RedactPhone = "phone"
+	RedactTerm  = "term"
+)
+
+// DefaultRedaction lists the detectors used when LLM_REDACTION is unset.
+var DefaultRedaction = []string{RedactToken, RedactEmail, RedactCard, RedactPhone}
+
+// Detector finds one kind of sensitive value. When Pattern has a capture
+// group only the group is redacted, so "password: hunter2" keeps its label.
+type Detector struct {
+	Kind    string
+	Pattern *regexp.Regexp
+	// Valid, when set, filters out matches that aren't really of this kind.
+	Valid func(match string) bool
+}
+
+var builtinDetectors = map[string]Detector{
+	RedactToken: {Kind: RedactToken, Pattern: regexp.MustCompile(
+		`\b(?:sk|pk|rk)[-_](?:live[-_]|test[-_]|proj[-_]|ant[-_])?[A-Za-z0-9_-]{16,}` +
+			`|\bgh[pousr]_[A-Za-z0-9]{20,}` +
+			`|\bxox[abpr]-[A-Za-z0-9-]{10,}` +
+			`|\bAKIA[0-9A-Z]{16}\b` +
+			`|\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+` +
+			`|(?i:bearer)\s+[A-Za-z0-9._~+/-]{16,}=*` +
+			`|(?i:password|passwd|secret|token|api[_-]?key)\s*[:=]\s*([^\s"',;]+)`)},
+	RedactEmail: {Kind: RedactEmail, Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	RedactCard:  {Kind: RedactCard, Pattern: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), Valid: luhn},
+	RedactPhone: {Kind: RedactPhone, Pattern: regexp.MustCompile(
+		// International numbers need their +; local ones the usual separators.
+		`\+\d{1,3}[\s.-]?\(?\d{1,4}\)?(?:[\s.-]?\d{2,4}){2,4}\b` +
+			`|\(\d{2,4}\)\s?\d{3,4}[\s.-]?\d{4}\b` +
+			`|\b\d{3}[.-]\d{3}[.-]\d{4}\b`), Valid: phoneDigits},
+}
+
+// NewDictionaryDetector matches the given terms as whole words, ignoring
+// case, e.g. customer or project names. It returns nil for no terms.
+func NewDictionaryDetector(kind string, terms []string) *Detector {
+	var quoted []string
+	for _, t := range terms {
+		if t = strings.TrimSpace(t); t != "" {
+			quoted = append(quoted, regexp.QuoteMeta(t))
+		}
+	}
+	if len(quoted) == 0 {
+		return nil
+	}
+	// Longest first so "Acme Corp" wins over "Acme".
+	sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+	return &Detector{Kind: kind, Pattern: regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)}
+}
+
+// Redactor replaces sensitive values with placeholders such as [EMAIL_1].
+type Redactor struct {
+	detectors []Detector
+}
+
+// NewRedactor runs detectors in order; values found by an earlier detector
+// are no longer visible to later ones.
+func NewRedactor(detectors ...Detector) *Redactor {
+	return &Redactor{detectors: detectors}
+}
+
+// NewRedactorFromEnv builds the redactor from LLM_REDACTION (comma-separated
+// built-in detectors, default token,email,card,phone; "off" disables) and the
+// dictionary terms in LLM_REDACT_TERMS (comma-separated) and
+// LLM_REDACT_TERMS_FILE (one per line). It returns nil when nothing is enabled.
+func NewRedactorFromEnv() (*Redactor, error) {
+	kinds := DefaultRedaction
+	if v := strings.TrimSpace(os.Getenv("LLM_REDACTION")); v != "" {
+		kinds = nil
+		if v != "off" {
+			for _, k := range strings.Split(v, ",") {
+				if k = strings.TrimSpace(k); k != "" {
+					kinds = append(kinds, k)
+				}
+			}
+		}
+	}
+	var detectors []Detector
+	for _, k := range kinds {
+		d, ok := builtinDetectors[k]
+		if !ok {
+			return nil, fmt.Errorf("unknown redaction detector %q in LLM_REDACTION", k)
+		}
+		detectors = append(detectors, d)
+	}
+
+	terms := strings.Split(os.Getenv("LLM_REDACT_TERMS"), ",")
+	if path := os.Getenv("LLM_REDACT_TERMS_FILE"); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read LLM_REDACT_TERMS_FILE: %w", err)
+		}
+		defer f.Close()
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			if line := strings.TrimSpace(sc.Text()); line != "" && !strings.HasPrefix(line, "#") {
+				terms = append(terms, line)
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read LLM_REDACT_TERMS_FILE: %w", err)
+		}
+	}
+	if d := NewDictionaryDetector(RedactTerm, terms); d != nil {
+		detectors = append(detectors, *d)
+	}
+
+	if len(detectors) == 0 {
+		return nil, nil
+	}
+	return NewRedactor(detectors...), nil
+}
+
+// Redaction holds the placeholders of one request so the answer can be
+// re-hydrated. The same value always gets the same placeholder.
+type Redaction struct {
+	originals    map[string]string // placeholder -> value
+	placeholders map[string]string // kind + value -> placeholder
+	Counts       map[string]int    // values redacted per kind
+}
+
+// NewRedaction starts the placeholders of one request.
+func NewRedaction() *Redaction {
+	return &Redaction{originals: map[string]string{}, placeholders: map[string]string{}, Counts: map[string]int{}}
+}
+
+// Redact returns s with every detected value replaced by a placeholder.
+func (r *Redactor) Redact(rd *Redaction, s string) string {
+	for _, d := range r.detectors {
+		s = d.replace(rd, s)
+	}
+	return s
+}
+
+func (d Detector) replace(rd *Redaction, s string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range d.Pattern.FindAllStringSubmatchIndex(s, -1) {
+		start, end := m[0], m[1]
+		// Redact only the first capture group when it matched.
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		value := s[start:end]
+		if d.Valid != nil && !d.Valid(value) {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(rd.placeholder(d.Kind, value))
+		last = end
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+func (rd *Redaction) placeholder(kind, value string) string {
+	if p, ok := rd.placeholders[kind+"\x00"+value]; ok {
+		return p
+	}
+	rd.Counts[kind]++
+	p := fmt.Sprintf("[%s_%d]", strings.ToUpper(kind), rd.Counts[kind])
+	rd.placeholders[kind+"\x00"+value] = p
+	rd.originals[p] = value
+	return p
+}
+
+// Restore puts the original values back in place of placeholders.
+func (rd *Redaction) Restore(s string) string {
+	if len(rd.originals) == 0 || !strings.Contains(s, "[") {
+		return s
+	}
+	pairs := make([]string, 0, 2*len(rd.originals))
+	for p, v := range rd.originals {
+		pairs = append(pairs, p, v)
+	}
+	return strings.NewReplacer(pairs...).Replace(s)
+}
+
+// RedactionRecord is the audit entry of one request that had values redacted.
+// It holds counts only, never the values.
+type RedactionRecord struct {
+	Operation string
+	Counts    map[string]int
+	TaskID    string // from WithCaller, empty when unknown
+	UserID    string
+}
+
+// RedactionAuditor stores what was redacted.
+type RedactionAuditor interface {
+	RecordRedaction(ctx context.Context, rec RedactionRecord)
+}
+
+// RedactingClient redacts task text, and the examples of the prompt
+// template in ctx, before it reaches next, and re-hydrates the summary of
+// the answer. Caches behind it only ever see placeholders.
+type RedactingClient struct {
+	next     LLMClient
+	redactor *Redactor
+	auditor  RedactionAuditor
+}
+
+// NewRedactingClient wraps next. auditor may be nil.
+func NewRedactingClient(next LLMClient, redactor *Redactor, auditor RedactionAuditor) *RedactingClient {
+	return &RedactingClient{next: next, redactor: redactor, auditor: auditor}
+}
+
+func (c *RedactingClient) ClassifyTask(ctx context.Context, title, description string) (*TaskClassification, error) {
+	return redacted(ctx, c, "classify",
+		func(rd *Redaction) { ctx, title, description = c.redactTask(ctx, rd, title, description) },
+		func(next LLMClient) (*TaskClassification, error) { return next.ClassifyTask(ctx, title, description) },
+		restoreSummary)
+}
+
+// ParseTask redacts the text before the wrapped client parses it, and
+// re-hydrates the parsed fields.
+func (c *RedactingClient) ParseTask(ctx context.Context, text string, opts ParseOptions) (*ParsedTask, error) {
+	return redacted(ctx, c, "parse",
+		func(rd *Redaction) { text = c.redactor.Redact(rd, text) },
+		func(parser TaskParser) (*ParsedTask, error) { return parser.ParseTask(ctx, text, opts) },
+		func(rd *Redaction, p *ParsedTask) *ParsedTask {
+			restored := *p
+			restored.Title = rd.Restore(p.Title)
+			restored.Description = rd.Restore(p.Description)
+			restored.Assignee = rd.Restore(p.Assignee)
+			return &restored
+		})
+}
+
+// EstimateEffort redacts the task and its examples before the wrapped client
+// estimates it, and re-hydrates the rationale.
+func (c *RedactingClient) EstimateEffort(ctx context.Context, req EffortRequest) (*EffortEstimate, error) {
+	return redacted(ctx, c, "estimate",
+		func(rd *Redaction) {
+			req.Title = c.redactor.Redact(rd, req.Title)
+			req.Description = c.redactor.Redact(rd, req.Description)
+			examples := make([]EffortExample, len(req.Examples))
+			for i, ex := range req.Examples {
+				ex.Title = c.redactor.Redact(rd, ex.Title)
+				ex.Description = c.redactor.Redact(rd, ex.Description)
+				examples[i] = ex
+			}
+			req.Examples = examples
+		},
+		func(estimator EffortEstimator) (*EffortEstimate, error) { return estimator.EstimateEffort(ctx, req) },
+		func(rd *Redaction, e *EffortEstimate) *EffortEstimate {
+			restored := *e
+			restored.Rationale = rd.Restore(e.Rationale)
+			return &restored
+		})
+}
+
+// Embed redacts texts before the wrapped client embeds them.
+func (c *RedactingClient) Embed(ctx context.Context, texts []string) (*Embeddings, error) {
+	return redacted(ctx, c, "embed",
+		func(rd *Redaction) { texts = c.redactAll(rd, texts) },
+		func(embedder Embedder) (*Embeddings, error) { return embedder.Embed(ctx, texts) },
+		nil)
+}
+
+// Summarize redacts the subject and facts before the wrapped client
+// summarizes them, and re-hydrates the summary.
+func (c *RedactingClient) Summarize(ctx context.Context, req SummaryRequest) (*Summary, error) {
+	return redacted(ctx, c, "summarize",
+		func(rd *Redaction) {
+			req.Subject = c.redactor.Redact(rd, req.Subject)
+			req.Facts = c.redactAll(rd, req.Facts)
+		},
+		func(summarizer Summarizer) (*Summary, error) { return summarizer.Summarize(ctx, req) },
+		func(rd *Redaction, s *Summary) *Summary {
+			restored := *s
+			restored.Text = rd.Restore(s.Text)
+			return &restored
+		})
+}
+
+// ClassifyTaskStream redacts like ClassifyTask and re-hydrates the streamed
+// pieces as well as the summary of the answer.
+func (c *RedactingClient) ClassifyTaskStream(ctx context.Context, title, description string, onDelta func(string)) (*TaskClassification, error) {
+	var out *restorer
+	return redacted(ctx, c, "classify",
+		func(rd *Redaction) {
+			ctx, title, description = c.redactTask(ctx, rd, title, description)
+			out = &restorer{rd: rd, onDelta: onDelta}
+		},
+		func(streamer StreamingLLMClient) (*TaskClassification, error) {
+			defer out.flush()
+			return streamer.ClassifyTaskStream(ctx, title, description, out.write)
+		},
+		restoreSummary)
+}
+
+// redacted makes call with next's implementation of C once redact has
+// replaced the sensitive values of the request, and re-hydrates the answer
+// with restore, if any. Nothing is redacted or audited when next lacks C.
+func redacted[C, R any](ctx context.Context, c *RedactingClient, operation string,
+	redact func(*Redaction), call func(C) (R, error), restore func(*Redaction, R) R) (R, error) {
+	return capability(c.next, func(impl C) (R, error) {
+		rd := NewRedaction()
+		redact(rd)
+		c.audit(ctx, operation, rd)
+
+		v, err := call(impl)
+		if err != nil || restore == nil {
+			return v, err
+		}
+		return restore(rd, v), nil
+	})
+}
+
+// redactTask redacts the text of a task to classify, and the examples of the
+// prompt template in ctx.
+func (c *RedactingClient) redactTask(ctx context.Context, rd *Redaction, title, description string) (context.Context, string, string) {
+	if p := PromptTemplateFrom(ctx); len(p.Examples) > 0 {
+		ctx = WithPromptTemplate(ctx, c.redactExamples(rd, p))
+	}
+	return ctx, c.redactor.Redact(rd, title), c.redactor.Redact(rd, description)
+}
+
+// restoreSummary re-hydrates the summary of a classification.
+func restoreSummary(rd *Redaction, tc *TaskClassification) *TaskClassification {
+	restored := *tc
+	restored.Summary = rd.Restore(tc.Summary)
+	return &restored
+}
+
+// redactAll returns a copy of ss with every string redacted.
+func (c *RedactingClient) redactAll(rd *Redaction, ss []string) []string {
+	out := make([]string, len(ss))
+	for i, s := range ss {
+		out[i] = c.redactor.Redact(rd, s)
+	}
+	return out
+}
+
+// redactExamples returns a copy of p whose few-shot examples are redacted.
+func (c *RedactingClient) redactExamples(rd *Redaction, p *PromptTemplate) *PromptTemplate {
+	cp := *p
+	cp.Examples = make([]PromptExample, len(p.Examples))
+	for i, ex := range p.Examples {
+		ex.Title = c.redactor.Redact(rd, ex.Title)
+		ex.Description = c.redactor.Redact(rd, ex.Description)
+		ex.Classification.Summary = c.redactor.Redact(rd, ex.Classification.Summary)
+		cp.Examples[i] = ex
+	}
+	return &cp
+}
+
+func (c *RedactingClient) audit(ctx context.Context, operation string, rd *Redaction) {
+	if len(rd.Counts) == 0 {
+		return
+	}
+	rec := RedactionRecord{Operation: operation, Counts: rd.Counts}
+	if who, ok := ctx.Value(callerKey{}).(caller); ok {
+		rec.TaskID, rec.UserID = who.taskID, who.userID
+	}
+	if c.auditor != nil {
+		c.auditor.RecordRedaction(ctx, rec)
+		return
+	}
+	log.Printf("Redacted %v from llm %s request", rd.Counts, operation)
+}
+
+// luhn reports whether the digits of s pass the card number checksum.
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+// phoneDigits rejects matches too short or too long to be phone numbers.
+func phoneDigits(s string) bool {
+	n := 0
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			n++
+		}
+	}
+	return n >= 9 && n <= 15
+}
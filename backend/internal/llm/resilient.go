@@ -0,0 +1,263 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the provider while the circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("llm circuit breaker open")
+
+// ResilientConfig tunes a ResilientClient. Zero values take the defaults.
+type ResilientConfig struct {
+	Timeout          time.Duration // deadline per attempt (default 10s)
+	MaxRetries       int           // retries after the first attempt on transient errors (default 2)
+	BaseBackoff      time.Duration // first retry delay before jitter, doubled per retry (default 500ms)
+	MaxBackoff       time.Duration // cap on the retry delay (default 5s)
+	FailureThreshold int           // consecutive failed calls that open the breaker (default 5)
+	OpenDuration     time.Duration // how long the breaker stays open (default 30s)
+}
+
+func (c *ResilientConfig) setDefaults() {
+	if c.Timeout <= 0 {
+		c.Timeout = 10 * time.Second
+	}
+	if c.MaxRetries < 0 {
+		c.MaxRetries = 0
+	} else if c.MaxRetries == 0 {
+		c.MaxRetries = 2
+	}
+	if c.BaseBackoff <= 0 {
+		c.BaseBackoff = 500 * time.Millisecond
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 5 * time.Second
+	}
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = 5
+	}
+	if c.OpenDuration <= 0 {
+		c.OpenDuration = 30 * time.Second
+	}
+}
+
+// ResilientClient decorates an LLMClient with per-attempt deadlines, retries
+// with jittered exponential backoff on transient errors, a circuit breaker,
+// and one repair round-trip when the provider returns unparseable JSON.
+// The other capabilities of the wrapped client get the same deadlines,
+// retries and breaker, without the repair.
+type ResilientClient struct {
+	next LLMClient
+	cfg  ResilientConfig
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool // a half-open trial call is in flight
+}
+
+// NewResilientClient wraps next. Set cfg.MaxRetries to -1 to disable retries.
+func NewResilientClient(next LLMClient, cfg ResilientConfig) *ResilientClient {
+	cfg.setDefaults()
+	return &ResilientClient{next: next, cfg: cfg}
+}
+
+func (c *ResilientClient) ClassifyTask(ctx context.Context, title, description string) (*TaskClassification, error) {
+	if err := c.allow(); err != nil {
+		return nil, err
+	}
+
+	tc, err := c.classify(ctx, title, description)
+	if mErr := (*MalformedResponseError)(nil); errors.As(err, &mErr) {
+		tc, err = c.repair(ctx, title, description, mErr)
+	}
+	c.record(ctx, err)
+	return tc, err
+}
+
+// classify calls the provider, retrying transient failures.
+func (c *ResilientClient) classify(ctx context.Context, title, description string) (*TaskClassification, error) {
+	return withRetries(ctx, c, func(ctx context.Context) (*TaskClassification, error) {
+		return c.next.ClassifyTask(ctx, title, description)
+	})
+}
+
+func (c *ResilientClient) ParseTask(ctx context.Context, text string, opts ParseOptions) (*ParsedTask, error) {
+	return resilientCall(ctx, c, func(ctx context.Context, parser TaskParser) (*ParsedTask, error) {
+		return parser.ParseTask(ctx, text, opts)
+	})
+}
+
+func (c *ResilientClient) EstimateEffort(ctx context.Context, req EffortRequest) (*EffortEstimate, error) {
+	return resilientCall(ctx, c, func(ctx context.Context, estimator EffortEstimator) (*EffortEstimate, error) {
+		return estimator.EstimateEffort(ctx, req)
+	})
+}
+
+func (c *ResilientClient) Embed(ctx context.Context, texts []string) (*Embeddings, error) {
+	return resilientCall(ctx, c, func(ctx context.Context, embedder Embedder) (*Embeddings, error) {
+		return embedder.Embed(ctx, texts)
+	})
+}
+
+func (c *ResilientClient) Summarize(ctx context.Context, req SummaryRequest) (*Summary, error) {
+	return resilientCall(ctx, c, func(ctx context.Context, summarizer Summarizer) (*Summary, error) {
+		return summarizer.Summarize(ctx, req)
+	})
+}
+
+// ClassifyTaskStream streams with the wrapped client under the circuit
+// breaker. The timeout applies to silence between pieces rather than to the
+// whole answer, and a failed stream is only retried while nothing has been
+// passed on yet. Malformed answers are repaired like in ClassifyTask; the
+// repaired answer is not streamed.
+func (c *ResilientClient) ClassifyTaskStream(ctx context.Context, title, description string, onDelta func(string)) (*TaskClassification, error) {
+	return capability(c.next, func(streamer StreamingLLMClient) (*TaskClassification, error) {
+		if err := c.allow(); err != nil {
+			return nil, err
+		}
+
+		streamed := false
+		retryable := func(err error) bool { return !streamed && IsTransient(err) }
+		tc, err := retrying(ctx, c, retryable, func(ctx context.Context) (*TaskClassification, error) {
+			return withIdleTimeout(ctx, c.cfg.Timeout, func(ctx context.Context, touch func()) (*TaskClassification, error) {
+				return streamer.ClassifyTaskStream(ctx, title, description, func(s string) {
+					streamed = true
+					touch()
+					onDelta(s)
+				})
+			})
+		})
+		if mErr := (*MalformedResponseError)(nil); errors.As(err, &mErr) {
+			tc, err = c.repair(ctx, title, description, mErr)
+		}
+		c.record(ctx, err)
+		return tc, err
+	})
+}
+
+// resilientCall makes call with the wrapped client's implementation of C
+// under the circuit breaker, retrying like withRetries.
+func resilientCall[C, R any](ctx context.Context, c *ResilientClient, call func(context.Context, C) (R, error)) (R, error) {
+	return capability(c.next, func(impl C) (R, error) {
+		if err := c.allow(); err != nil {
+			var zero R
+			return zero, err
+		}
+		v, err := withRetries(ctx, c, func(ctx context.Context) (R, error) {
+			return call(ctx, impl)
+		})
+		c.record(ctx, err)
+		return v, err
+	})
+}
+
+// withRetries makes call with a deadline per attempt, retrying transient
+// failures with backoff.
+func withRetries[T any](ctx context.Context, c *ResilientClient, call func(context.Context) (T, error)) (T, error) {
+	return retrying(ctx, c, IsTransient, func(ctx context.Context) (T, error) {
+		return withTimeout(ctx, c.cfg.Timeout, call)
+	})
+}
+
+// retrying makes call, retrying the failures retryable accepts with backoff.
+func retrying[T any](ctx context.Context, c *ResilientClient, retryable func(error) bool, call func(context.Context) (T, error)) (T, error) {
+	for attempt := 0; ; attempt++ {
+		v, err := call(ctx)
+		if err == nil || attempt >= c.cfg.MaxRetries || !retryable(err) || ctx.Err() != nil {
+			return v, err
+		}
+
+		delay := c.backoff(attempt)
+		log.Printf("llm call failed (attempt %d/%d), retrying in %v: %v", attempt+1, c.cfg.MaxRetries+1, delay, err)
+		select {
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// repair asks the model once to fix its malformed answer. Providers that
+// can't take free-form prompts are simply asked to classify again.
+func (c *ResilientClient) repair(ctx context.Context, title, description string, mErr *MalformedResponseError) (*TaskClassification, error) {
+	log.Printf("llm returned malformed output, re-prompting once: %v", mErr)
+	completer, ok := c.next.(Completer)
+	if !ok {
+		return withTimeout(ctx, c.cfg.Timeout, func(ctx context.Context) (*TaskClassification, error) {
+			return c.next.ClassifyTask(ctx, title, description)
+		})
+	}
+	return withTimeout(ctx, c.cfg.Timeout, func(ctx context.Context) (*TaskClassification, error) {
+		out, err := completer.Complete(ctx, buildRepairPrompt(mErr.Output))
+		if err != nil {
+			return nil, err
+		}
+		return parseClassification(mErr.Provider, mErr.Model, out)
+	})
+}
+
+func withTimeout[T any](ctx context.Context, timeout time.Duration, call func(context.Context) (T, error)) (T, error) {
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return call(attemptCtx)
+}
+
+// backoff returns the full-jitter delay before retry number attempt+1.
+func (c *ResilientClient) backoff(attempt int) time.Duration {
+	d := c.cfg.BaseBackoff << attempt
+	if d <= 0 || d > c.cfg.MaxBackoff {
+		d = c.cfg.MaxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(d)) + 1)
+}
+
+// allow rejects calls while the breaker is open. Once OpenDuration passed, a
+// single trial call is let through (half-open); its outcome closes or
+// re-opens the breaker.
+func (c *ResilientClient) allow() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failures < c.cfg.FailureThreshold {
+		return nil
+	}
+	if time.Now().Before(c.openUntil) || c.probing {
+		return ErrCircuitOpen
+	}
+	c.probing = true
+	return nil
+}
+
+func (c *ResilientClient) record(ctx context.Context, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.probing = false
+
+	// A cancelled caller, a spent budget or a request the provider refused
+	// (400, 401, 404…) says nothing about the provider's health; malformed
+	// output means the provider is up.
+	if errors.Is(ctx.Err(), context.Canceled) || errors.Is(err, ErrBudgetExceeded) || isCallerError(err) {
+		return
+	}
+	if mErr := (*MalformedResponseError)(nil); err == nil || errors.As(err, &mErr) {
+		c.failures = 0
+		return
+	}
+	c.failures++
+	if c.failures >= c.cfg.FailureThreshold {
+		c.openUntil = time.Now().Add(c.cfg.OpenDuration)
+		log.Printf("llm circuit breaker open for %v after %d consecutive failures", c.cfg.OpenDuration, c.failures)
+	}
+}
+
+// isCallerError reports whether err is a 4xx refusal that retrying can't fix.
+func isCallerError(err error) bool {
+	code := StatusCode(err)
+	return code >= 400 && code < 500 && !IsTransient(err)
+}
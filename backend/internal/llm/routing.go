@@ -0,0 +1,184 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+)
+
+// Route is one provider/model a RoutingClient can send calls to.
+type Route struct {
+	Name   string // e.g. "openai:gpt-4o-mini", used in logs and results
+	Client LLMClient
+	Weight int // share of traffic routed here first; 0 makes it fallback-only
+}
+
+// RoutingClient spreads calls across routes by weight (for A/B splits between
+// models) and, when the chosen route fails, falls back to the remaining routes
+// in their configured order.
+type RoutingClient struct {
+	routes []Route
+	total  int
+}
+
+// NewRoutingClient builds a client over routes. If no route has a weight,
+// the first route takes all traffic and the rest form a fallback chain.
+func NewRoutingClient(routes ...Route) (*RoutingClient, error) {
+	if len(routes) == 0 {
+		return nil, errors.New("at least one llm route is required")
+	}
+	c := &RoutingClient{routes: routes}
+	for _, r := range routes {
+		if r.Weight < 0 {
+			return nil, fmt.Errorf("route %s has a negative weight", r.Name)
+		}
+		c.total += r.Weight
+	}
+	return c, nil
+}
+
+// Order returns the routes in the order they are tried, given a number n in
+// [0, total weight) that selects the primary route.
+func (c *RoutingClient) Order(n int) []Route {
+	primary := 0
+	for i, r := range c.routes {
+		if n < r.Weight {
+			primary = i
+			break
+		}
+		n -= r.Weight
+	}
+	order := make([]Route, 0, len(c.routes))
+	order = append(order, c.routes[primary])
+	for i, r := range c.routes {
+		if i != primary {
+			order = append(order, r)
+		}
+	}
+	return order
+}
+
+func (c *RoutingClient) ClassifyTask(ctx context.Context, title, description string) (*TaskClassification, error) {
+	n := 0
+	if c.total > 0 {
+		n = rand.Intn(c.total)
+	}
+
+	var errs []error
+	for i, route := range c.Order(n) {
+		start := time.Now()
+		tc, err := route.Client.ClassifyTask(ctx, title, description)
+		if err == nil {
+			if tc.Provider == "" {
+				tc.Provider = route.Name
+			}
+			log.Printf("llm classification answered by %s in %v (fallbacks: %d)", route.Name, time.Since(start).Round(time.Millisecond), i)
+			return tc, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", route.Name, err))
+		// The budget is shared by every route.
+		if ctx.Err() != nil || errors.Is(err, ErrBudgetExceeded) {
+			break
+		}
+		log.Printf("llm provider %s failed, trying next: %v", route.Name, err)
+	}
+	return nil, fmt.Errorf("all llm providers failed: %w", errors.Join(errs...))
+}
+
+// ParseTask tries the routes like ClassifyTask, skipping the ones that can't
+// parse tasks.
+func (c *RoutingClient) ParseTask(ctx context.Context, text string, opts ParseOptions) (*ParsedTask, error) {
+	v, _, err := routeCapability(ctx, c, "parse task", nil, func(parser TaskParser) (*ParsedTask, error) {
+		return parser.ParseTask(ctx, text, opts)
+	})
+	return v, err
+}
+
+// EstimateEffort tries the routes like ClassifyTask, skipping the ones that
+// can't estimate effort.
+func (c *RoutingClient) EstimateEffort(ctx context.Context, req EffortRequest) (*EffortEstimate, error) {
+	v, _, err := routeCapability(ctx, c, "estimate effort", nil, func(estimator EffortEstimator) (*EffortEstimate, error) {
+		return estimator.EstimateEffort(ctx, req)
+	})
+	return v, err
+}
+
+// Embed tries the routes like ClassifyTask, skipping the ones that can't
+// embed. Callers must compare only vectors of the same Embeddings.Model.
+func (c *RoutingClient) Embed(ctx context.Context, texts []string) (*Embeddings, error) {
+	v, _, err := routeCapability(ctx, c, "embed", nil, func(embedder Embedder) (*Embeddings, error) {
+		return embedder.Embed(ctx, texts)
+	})
+	return v, err
+}
+
+// Summarize tries the routes like ClassifyTask, skipping the ones that can't
+// summarize.
+func (c *RoutingClient) Summarize(ctx context.Context, req SummaryRequest) (*Summary, error) {
+	v, _, err := routeCapability(ctx, c, "summarize", nil, func(summarizer Summarizer) (*Summary, error) {
+		return summarizer.Summarize(ctx, req)
+	})
+	return v, err
+}
+
+// ClassifyTaskStream tries the routes that can stream like ClassifyTask, but
+// only falls back while nothing has been passed on: the caller can't take
+// back what a failed route already streamed.
+func (c *RoutingClient) ClassifyTaskStream(ctx context.Context, title, description string, onDelta func(string)) (*TaskClassification, error) {
+	streamed := false
+	stop := func(error) bool { return streamed }
+	tc, route, err := routeCapability(ctx, c, "stream the classification", stop, func(streamer StreamingLLMClient) (*TaskClassification, error) {
+		return streamer.ClassifyTaskStream(ctx, title, description, func(s string) {
+			streamed = true
+			onDelta(s)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if tc.Provider == "" {
+		tc.Provider = route
+	}
+	return tc, nil
+}
+
+// routeCapability calls the routes implementing capability C in order until
+// one succeeds, falling back unless stop, when set, reports true for the
+// failure. It returns the name of the route that answered, or
+// ErrUnsupported when none of them implements C.
+func routeCapability[C any, R any](ctx context.Context, c *RoutingClient, what string, stop func(error) bool, call func(C) (R, error)) (R, string, error) {
+	n := 0
+	if c.total > 0 {
+		n = rand.Intn(c.total)
+	}
+
+	var zero R
+	var errs []error
+	for i, route := range c.Order(n) {
+		client, ok := route.Client.(C)
+		if !ok {
+			continue
+		}
+		start := time.Now()
+		v, err := call(client)
+		if err == nil {
+			log.Printf("llm call to %s answered by %s in %v (fallbacks: %d)", what, route.Name, time.Since(start).Round(time.Millisecond), i)
+			return v, route.Name, nil
+		}
+		if errors.Is(err, ErrUnsupported) {
+			continue
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", route.Name, err))
+		if ctx.Err() != nil || errors.Is(err, ErrBudgetExceeded) || (stop != nil && stop(err)) {
+			break
+		}
+		log.Printf("llm provider %s failed to %s, trying next: %v", route.Name, what, err)
+	}
+	if len(errs) == 0 {
+		return zero, "", ErrUnsupported
+	}
+	return zero, "", fmt.Errorf("all llm providers failed: %w", errors.Join(errs...))
+}
@@ -0,0 +1,154 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Allowed values, matching the CHECK constraints on the tasks table.
+var (
+	Priorities = []string{"low", "medium", "high", "urgent"}
+	Categories = []string{"bug", "feature", "improvement", "research"}
+)
+
+const (
+	MaxTags       = 8
+	MaxTagLength  = 50
+	MaxSummaryLen = 140
+)
+
+// ClassificationSchema is the JSON schema of TaskClassification, used for
+// provider-native structured output.
+var ClassificationSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"tags": map[string]any{
+			"type":  "array",
+			"items": map[string]any{"type": "string"},
+		},
+		"priority": map[string]any{"type": "string", "enum": Priorities},
+		"category": map[string]any{"type": "string", "enum": Categories},
+		"summary":  map[string]any{"type": "string", "description": "one-line summary, at most 140 characters"},
+	},
+	"required":             []string{"tags", "priority", "category", "summary"},
+	"additionalProperties": false,
+}
+
+// ValidationError reports a classification field that doesn't match the schema.
+type ValidationError struct {
+	Field  string
+	Value  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s %q: %s", e.Field, e.Value, e.Reason)
+}
+
+var tagInvalidChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// NormalizeTags lowercases and hyphenates tags, strips other punctuation,
+// drops empty and duplicate tags and keeps at most MaxTags.
+func NormalizeTags(tags []string) []string {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		t := strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+		t = strings.Trim(tagInvalidChars.ReplaceAllString(t, ""), "-.")
+		if len(t) > MaxTagLength {
+			t = t[:MaxTagLength]
+		}
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+		if len(out) == MaxTags {
+			break
+		}
+	}
+	return out
+}
+
+// NormalizeClassification cleans up formatting without changing meaning:
+// trims and lowercases enums, normalizes tags and shortens the summary.
+func NormalizeClassification(tc *TaskClassification) {
+	tc.Priority = strings.ToLower(strings.TrimSpace(tc.Priority))
+	tc.Category = strings.ToLower(strings.TrimSpace(tc.Category))
+	tc.Summary = strings.Join(strings.Fields(tc.Summary), " ")
+	if utf8.RuneCountInString(tc.Summary) > MaxSummaryLen {
+		runes := []rune(tc.Summary)
+		tc.Summary = string(runes[:MaxSummaryLen-3]) + "..."
+	}
+	tc.Tags = NormalizeTags(tc.Tags)
+}
+
+// ValidateClassification checks tc against the schema and returns a
+// *ValidationError for the first violation.
+func ValidateClassification(tc *TaskClassification) error {
+	if !contains(Priorities, tc.Priority) {
+		return &ValidationError{Field: "priority", Value: tc.Priority, Reason: "must be one of " + strings.Join(Priorities, ", ")}
+	}
+	if !contains(Categories, tc.Category) {
+		return &ValidationError{Field: "category", Value: tc.Category, Reason: "must be one of " + strings.Join(Categories, ", ")}
+	}
+	if tc.Summary == "" {
+		return &ValidationError{Field: "summary", Reason: "is required"}
+	}
+	return nil
+}
+
+// decodeClassification strictly decodes a model answer: a single JSON object
+// (optionally inside a Markdown code fence) with no unknown fields, then
+// normalizes and validates it.
+func decodeClassification(out string) (*TaskClassification, error) {
+	// Only the schema fields; Provider and Model are ours to set.
+	var answer struct {
+		Tags     []string `json:"tags"`
+		Priority string   `json:"priority"`
+		Category string   `json:"category"`
+		Summary  string   `json:"summary"`
+	}
+	if err := decodeStrict(out, &answer); err != nil {
+		return nil, err
+	}
+
+	tc := TaskClassification{Tags: answer.Tags, Priority: answer.Priority, Category: answer.Category, Summary: answer.Summary}
+	NormalizeClassification(&tc)
+	if err := ValidateClassification(&tc); err != nil {
+		return nil, err
+	}
+	return &tc, nil
+}
+
+// decodeStrict decodes a model's answer, optionally in a Markdown code
+// fence, into v: a single JSON object without unknown fields.
+func decodeStrict(out string, v any) error {
+	s := strings.TrimSpace(out)
+	if strings.HasPrefix(s, "```") {
+		s = strings.TrimPrefix(strings.TrimPrefix(s, "```json"), "```")
+		s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+	}
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("unexpected content after JSON object")
+	}
+	return nil
+}
+
+func contains(values []string, v string) bool {
+	for _, known := range values {
+		if v == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// StreamingLLMClient is implemented by clients that can pass the model's
+// output on while it is being generated. onDelta receives the raw answer
+// piece by piece, in order; the returned classification is parsed from the
+// complete answer, exactly as ClassifyTask would return it. onDelta is
+// called from the calling goroutine.
+type StreamingLLMClient interface {
+	LLMClient
+	ClassifyTaskStream(ctx context.Context, title, description string, onDelta func(string)) (*TaskClassification, error)
+}
+
+// ClassifyTaskStream streams the answer of the Responses API.
+func (c *OpenAIClient) ClassifyTaskStream(ctx context.Context, title, description string, onDelta func(string)) (*TaskClassification, error) {
+	stream := c.client.Responses.NewStreaming(ctx, c.classifyParams(ctx, title, description))
+	defer stream.Close()
+
+	var out strings.Builder
+	for stream.Next() {
+		ev := stream.Current()
+		switch ev.Type {
+		case "response.output_text.delta":
+			out.WriteString(ev.Delta)
+			onDelta(ev.Delta)
+		case "response.completed":
+			reportUsage(ctx, ev.Response.Usage.InputTokens, ev.Response.Usage.OutputTokens)
+		case "response.failed":
+			return nil, fmt.Errorf("openai stream failed: %s", ev.Response.Error.Message)
+		case "error":
+			return nil, fmt.Errorf("openai stream error %s: %s", ev.Code, ev.Message)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("openai sdk error: %w", err)
+	}
+	if out.Len() == 0 {
+		return nil, errors.New("empty response from openai")
+	}
+	return parseClassification("openai", c.model, out.String())
+}
+
+// ClassifyTaskStream streams the input of the classification tool call, or
+// the text answer of models without tool use.
+func (c *AnthropicClient) ClassifyTaskStream(ctx context.Context, title, description string, onDelta func(string)) (*TaskClassification, error) {
+	params := c.classifyParams(ctx, title, description)
+	if !supportsToolUse(c.model) {
+		params.MaxTokens, params.Tools, params.ToolChoice = 300, nil, anthropic.ToolChoiceUnionParam{}
+	}
+	stream := c.client.Messages.NewStreaming(ctx, params)
+	defer stream.Close()
+
+	msg := anthropic.Message{}
+	for stream.Next() {
+		event := stream.Current()
+		if err := msg.Accumulate(event); err != nil {
+			return nil, fmt.Errorf("anthropic stream error: %w", err)
+		}
+		if ev, ok := event.AsAny().(anthropic.ContentBlockDeltaEvent); ok {
+			switch delta := ev.Delta.AsAny().(type) {
+			case anthropic.TextDelta:
+				onDelta(delta.Text)
+			case anthropic.InputJSONDelta:
+				onDelta(delta.PartialJSON)
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("anthropic sdk error: %w", err)
+	}
+	reportUsage(ctx, msg.Usage.InputTokens, msg.Usage.OutputTokens)
+
+	if !supportsToolUse(c.model) {
+		out := contentToString(msg.Content)
+		if strings.TrimSpace(out) == "" {
+			return nil, errors.New("empty response from anthropic")
+		}
+		return parseClassification("anthropic", c.model, out)
+	}
+	return c.classificationFrom(&msg)
+}
+
+// mockChunkLen is the size, in bytes, of the pieces MockClient streams.
+const mockChunkLen = 16
+
+// ClassifyTaskStream streams the JSON of ClassifyTask's answer in small
+// pieces.
+func (m *MockClient) ClassifyTaskStream(ctx context.Context, title, description string, onDelta func(string)) (*TaskClassification, error) {
+	tc, err := m.ClassifyTask(ctx, title, description)
+	if err != nil {
+		return nil, err
+	}
+	out := answerJSON(tc)
+	for len(out) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		n := min(mockChunkLen, len(out))
+		for n < len(out) && !utf8.RuneStart(out[n]) {
+			n++
+		}
+		onDelta(out[:n])
+		out = out[n:]
+	}
+	return tc, nil
+}
+
+// answerJSON is tc as the model writes it, without attribution.
+func answerJSON(tc *TaskClassification) string {
+	b, _ := json.Marshal(TaskClassification{Tags: tc.Tags, Priority: tc.Priority, Category: tc.Category, Summary: tc.Summary})
+	return string(b)
+}
+
+// withIdleTimeout makes call with a deadline that is pushed back whenever
+// call reports progress through touch. Streams may take long as a whole but
+// should never go quiet for long.
+func withIdleTimeout[T any](ctx context.Context, timeout time.Duration, call func(ctx context.Context, touch func()) (T, error)) (T, error) {
+	errIdle := fmt.Errorf("no llm output for %v: %w", timeout, context.DeadlineExceeded)
+	attemptCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	timer := time.AfterFunc(timeout, func() { cancel(errIdle) })
+	defer timer.Stop()
+
+	v, err := call(attemptCtx, func() { timer.Reset(timeout) })
+	if err != nil && ctx.Err() == nil && context.Cause(attemptCtx) == errIdle {
+		err = errIdle
+	}
+	return v, err
+}
+
+// restorer re-hydrates redacted text streamed in pieces. A placeholder may
+// be split across pieces, so text from an unclosed "[" on is held back
+// until it is closed or grows too long to be a placeholder.
+type restorer struct {
+	rd      *Redaction
+	onDelta func(string)
+	pending string
+}
+
+// maxPlaceholderLen bounds how much text a restorer holds back.
+const maxPlaceholderLen = 32
+
+func (r *restorer) write(s string) {
+	r.pending += s
+	if i := strings.LastIndexByte(r.pending, '['); i >= 0 && len(r.pending)-i < maxPlaceholderLen &&
+		!strings.Contains(r.pending[i:], "]") {
+		r.emit(r.pending[:i])
+		r.pending = r.pending[i:]
+		return
+	}
+	r.emit(r.pending)
+	r.pending = ""
+}
+
+func (r *restorer) flush() {
+	r.emit(r.pending)
+	r.pending = ""
+}
+
+func (r *restorer) emit(s string) {
+	if s != "" {
+		r.onDelta(r.rd.Restore(s))
+	}
+}
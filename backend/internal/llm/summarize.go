@@ -0,0 +1,131 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Kinds of summaries.
+const (
+	SummaryTask   = "task"   // a task's lifecycle
+	SummaryReport = "report" // a weekly project report
+)
+
+// MaxNarrativeLen is the longest summary Summarize accepts, in characters.
+const MaxNarrativeLen = 4000
+
+// SummaryRequest is what to summarize: facts in plain sentences, oldest
+// first, about a subject such as a task's title or a report's period.
+type SummaryRequest struct {
+	Kind    string
+	Subject string
+	Facts   []string
+}
+
+// Summary is a short narrative of the facts.
+type Summary struct {
+	Text string `json:"summary"`
+
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+}
+
+// Summarizer is implemented by clients that can summarize task histories
+// and reports.
+type Summarizer interface {
+	Summarize(ctx context.Context, req SummaryRequest) (*Summary, error)
+}
+
+func buildSummaryPrompt(req SummaryRequest) string {
+	var b strings.Builder
+	if req.Kind == SummaryReport {
+		b.WriteString(`Write a short weekly project report from the facts below, for a team lead.
+Cover, in this order and in a few sentences each: what moved forward, what is blocked, and what is overdue.
+Name the most important tasks; don't list every one.
+`)
+	} else {
+		b.WriteString(`Summarize the history of the task below in 2 to 4 sentences, for a teammate catching up.
+Say where it stands now, who has worked on it, what changed along the way and how much time was logged.
+`)
+	}
+	b.WriteString(`Use only the facts given and don't invent any.
+Reply with only a JSON object of the form {"summary": "..."}
+`)
+	fmt.Fprintf(&b, "\n%s\n\nFacts:\n", req.Subject)
+	for _, f := range req.Facts {
+		b.WriteString("- ")
+		b.WriteString(f)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// summarizeWith asks completer for a summary; provider clients implement
+// Summarizer with it.
+func summarizeWith(ctx context.Context, completer Completer, provider, model string, req SummaryRequest) (*Summary, error) {
+	out, err := completer.Complete(ctx, buildSummaryPrompt(req))
+	if err != nil {
+		return nil, err
+	}
+	var s Summary
+	if err := decodeStrict(out, &s); err != nil {
+		return nil, &MalformedResponseError{Provider: provider, Model: model, Output: out, Err: err}
+	}
+	s.Provider, s.Model = provider, model
+	if err := ValidateSummary(&s); err != nil {
+		return nil, &MalformedResponseError{Provider: provider, Model: model, Output: out, Err: err}
+	}
+	return &s, nil
+}
+
+// ValidateSummary trims s and checks that it is neither empty nor longer
+// than MaxNarrativeLen.
+func ValidateSummary(s *Summary) error {
+	s.Text = strings.TrimSpace(s.Text)
+	if s.Text == "" {
+		return &ValidationError{Field: "summary", Value: "", Reason: "must not be empty"}
+	}
+	if n := utf8.RuneCountInString(s.Text); n > MaxNarrativeLen {
+		return &ValidationError{Field: "summary", Value: fmt.Sprintf("%d characters", n), Reason: fmt.Sprintf("must be at most %d characters", MaxNarrativeLen)}
+	}
+	return nil
+}
+
+func (c *OpenAIClient) Summarize(ctx context.Context, req SummaryRequest) (*Summary, error) {
+	return summarizeWith(ctx, c, "openai", c.model, req)
+}
+
+func (c *AnthropicClient) Summarize(ctx context.Context, req SummaryRequest) (*Summary, error) {
+	return summarizeWith(ctx, c, "anthropic", c.model, req)
+}
+
+func (c *LocalClient) Summarize(ctx context.Context, req SummaryRequest) (*Summary, error) {
+	return summarizeWith(ctx, c, c.flavor, c.model, req)
+}
+
+// Summarize counts the facts and repeats the first and the latest.
+func (m *MockClient) Summarize(ctx context.Context, req SummaryRequest) (*Summary, error) {
+	s := &Summary{Provider: "mock"}
+	switch len(req.Facts) {
+	case 0:
+		s.Text = req.Subject + ": nothing to report."
+	case 1:
+		s.Text = fmt.Sprintf("%s: %s", req.Subject, req.Facts[0])
+	default:
+		s.Text = fmt.Sprintf("%s: %d facts. First: %s Latest: %s", req.Subject, len(req.Facts),
+			sentence(req.Facts[0]), sentence(req.Facts[len(req.Facts)-1]))
+	}
+	if err := ValidateSummary(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func sentence(s string) string {
+	if strings.HasSuffix(s, ".") {
+		return s
+	}
+	return s + "."
+}
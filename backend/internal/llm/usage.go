@@ -0,0 +1,174 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExceeded is returned without calling the provider once the
+// workspace has used up its monthly token budget.
+var ErrBudgetExceeded = errors.New("llm token budget exceeded")
+
+// CallRecord describes one call made to a provider.
+type CallRecord struct {
+	Provider     string
+	Model        string
+	Operation    string // "classify", "complete", "parse", "estimate", "embed" or "summarize"
+	InputTokens  int64
+	OutputTokens int64
+	Latency      time.Duration
+	Err          error
+	TaskID       string // from WithCaller, empty when unknown
+	UserID       string
+}
+
+// UsageRecorder stores call records and enforces token budgets.
+type UsageRecorder interface {
+	// Allow returns ErrBudgetExceeded when no more calls may be made.
+	Allow(ctx context.Context) error
+	Record(ctx context.Context, rec CallRecord)
+}
+
+type callerKey struct{}
+
+type caller struct{ taskID, userID string }
+
+// WithCaller attributes the LLM calls made with ctx to a task and user.
+func WithCaller(ctx context.Context, taskID, userID string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller{taskID, userID})
+}
+
+type usageKey struct{}
+
+// tokenCount collects the tokens reported by a provider during one call.
+type tokenCount struct {
+	mu            sync.Mutex
+	input, output int64
+}
+
+// reportUsage is called by provider clients with the token counts of the
+// response; it is a no-op unless the call is metered.
+func reportUsage(ctx context.Context, input, output int64) {
+	if t, ok := ctx.Value(usageKey{}).(*tokenCount); ok {
+		t.mu.Lock()
+		t.input += input
+		t.output += output
+		t.mu.Unlock()
+	}
+}
+
+// MeteringClient records every call made to a provider with its token
+// usage and latency, and refuses calls once the token budget is spent.
+type MeteringClient struct {
+	next     LLMClient
+	provider string
+	model    string
+	recorder UsageRecorder
+}
+
+// meteringCompleter is a MeteringClient over a provider that also completes
+// free-form prompts, so ResilientClient can still ask it for repairs.
+type meteringCompleter struct {
+	*MeteringClient
+	completer Completer
+}
+
+// NewMeteringClient wraps next. The result implements Completer when next does.
+func NewMeteringClient(next LLMClient, provider, model string, recorder UsageRecorder) LLMClient {
+	m := &MeteringClient{next: next, provider: provider, model: model, recorder: recorder}
+	if completer, ok := next.(Completer); ok {
+		return &meteringCompleter{MeteringClient: m, completer: completer}
+	}
+	return m
+}
+
+func (c *MeteringClient) ClassifyTask(ctx context.Context, title, description string) (*TaskClassification, error) {
+	var tc *TaskClassification
+	err := c.meter(ctx, "classify", func(ctx context.Context) error {
+		var err error
+		tc, err = c.next.ClassifyTask(ctx, title, description)
+		return err
+	})
+	return tc, err
+}
+
+func (c *meteringCompleter) Complete(ctx context.Context, prompt string) (string, error) {
+	var out string
+	err := c.meter(ctx, "complete", func(ctx context.Context) error {
+		var err error
+		out, err = c.completer.Complete(ctx, prompt)
+		return err
+	})
+	return out, err
+}
+
+func (c *MeteringClient) ParseTask(ctx context.Context, text string, opts ParseOptions) (*ParsedTask, error) {
+	return meteredCall(ctx, c, "parse", func(ctx context.Context, parser TaskParser) (*ParsedTask, error) {
+		return parser.ParseTask(ctx, text, opts)
+	})
+}
+
+func (c *MeteringClient) EstimateEffort(ctx context.Context, req EffortRequest) (*EffortEstimate, error) {
+	return meteredCall(ctx, c, "estimate", func(ctx context.Context, estimator EffortEstimator) (*EffortEstimate, error) {
+		return estimator.EstimateEffort(ctx, req)
+	})
+}
+
+func (c *MeteringClient) Embed(ctx context.Context, texts []string) (*Embeddings, error) {
+	return meteredCall(ctx, c, "embed", func(ctx context.Context, embedder Embedder) (*Embeddings, error) {
+		return embedder.Embed(ctx, texts)
+	})
+}
+
+func (c *MeteringClient) Summarize(ctx context.Context, req SummaryRequest) (*Summary, error) {
+	return meteredCall(ctx, c, "summarize", func(ctx context.Context, summarizer Summarizer) (*Summary, error) {
+		return summarizer.Summarize(ctx, req)
+	})
+}
+
+func (c *MeteringClient) ClassifyTaskStream(ctx context.Context, title, description string, onDelta func(string)) (*TaskClassification, error) {
+	return meteredCall(ctx, c, "classify", func(ctx context.Context, streamer StreamingLLMClient) (*TaskClassification, error) {
+		return streamer.ClassifyTaskStream(ctx, title, description, onDelta)
+	})
+}
+
+// meteredCall makes call with the wrapped client's implementation of C,
+// recorded as operation.
+func meteredCall[C, R any](ctx context.Context, c *MeteringClient, operation string, call func(context.Context, C) (R, error)) (R, error) {
+	return capability(c.next, func(impl C) (R, error) {
+		var v R
+		err := c.meter(ctx, operation, func(ctx context.Context) error {
+			var err error
+			v, err = call(ctx, impl)
+			return err
+		})
+		return v, err
+	})
+}
+
+func (c *MeteringClient) meter(ctx context.Context, operation string, call func(context.Context) error) error {
+	if err := c.recorder.Allow(ctx); err != nil {
+		return err
+	}
+
+	tokens := &tokenCount{}
+	start := time.Now()
+	err := call(context.WithValue(ctx, usageKey{}, tokens))
+
+	rec := CallRecord{
+		Provider:     c.provider,
+		Model:        c.model,
+		Operation:    operation,
+		InputTokens:  tokens.input,
+		OutputTokens: tokens.output,
+		Latency:      time.Since(start),
+		Err:          err,
+	}
+	if who, ok := ctx.Value(callerKey{}).(caller); ok {
+		rec.TaskID, rec.UserID = who.taskID, who.userID
+	}
+	c.recorder.Record(ctx, rec)
+	return err
+}
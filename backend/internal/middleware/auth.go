@@ -12,6 +12,7 @@ import (
 type contextKey string
 
 const UserIDKey contextKey = "user_id"
+const RoleKey contextKey = "role"
 
 var jwtSecret []byte
 
@@ -65,6 +66,9 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		}
 
 		ctx := context.WithValue(r.Context(), UserIDKey, userID)
+		if role, ok := claims["role"].(string); ok {
+			ctx = context.WithValue(ctx, RoleKey, role)
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -74,3 +78,21 @@ func GetUserID(r *http.Request) string {
 	userID, _ := r.Context().Value(UserIDKey).(string)
 	return userID
 }
+
+// GetUserRole extracts the authenticated user's role from the request context
+func GetUserRole(r *http.Request) string {
+	role, _ := r.Context().Value(RoleKey).(string)
+	return role
+}
+
+// RequireAdmin rejects requests from users without the admin role.
+// It must run after AuthMiddleware.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if GetUserRole(r) != "admin" {
+			http.Error(w, `{"error": "admin role required"}`, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
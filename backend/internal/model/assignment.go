@@ -0,0 +1,16 @@
+package model
+
+type AssigneeSuggestion struct {
+	User         User     `json:"user"`
+	Score        float64  `json:"score"`        // 0 to 1, weighted from the three below
+	Expertise    float64  `json:"expertise"`    // 0 to 1, from similar tasks the user completed
+	Availability float64  `json:"availability"` // 0 to 1, lower with more open hours
+	Deadlines    float64  `json:"deadlines"`    // 0 to 1, lower with more tasks due soon or overdue
+	SimilarDone  int      `json:"similar_done"` // completed tasks similar to this one
+	OpenTasks    int      `json:"open_tasks"`
+	OpenHours    float64  `json:"open_hours"` // sum of estimated_hours of open tasks
+	DueSoon      int      `json:"due_soon"`
+	Overdue      int      `json:"overdue"`
+	Current      bool     `json:"current"` // already assigned to the task
+	Reasons      []string `json:"reasons"`
+}
@@ -0,0 +1,111 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type ClassificationJob struct {
+	ID          string          `json:"id"`
+	TaskID      string          `json:"task_id"`
+	RequestedBy string          `json:"requested_by"`
+	Status      string          `json:"status"` // "queued", "running", "succeeded", "failed"
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	Force       bool            `json:"force"`
+	LastError   *string         `json:"last_error"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	RunAfter    time.Time       `json:"run_after"`
+	CreatedAt   time.Time       `json:"created_at"`
+	StartedAt   *time.Time      `json:"started_at"`
+	FinishedAt  *time.Time      `json:"finished_at"`
+}
+
+type AISuggestion struct {
+	ID            string                 `json:"id"`
+	TaskID        string                 `json:"task_id"`
+	RequestedBy   string                 `json:"requested_by"`
+	Status        string                 `json:"status"` // "pending", "accepted", "partially_accepted", "rejected", "superseded"
+	Priority      string                 `json:"priority"`
+	Category      string                 `json:"category"`
+	Summary       string                 `json:"summary"`
+	Tags          []string               `json:"tags"`
+	Provider      *string                `json:"provider"`
+	Model         *string                `json:"model"`
+	PromptVersion *string                `json:"prompt_version"`
+	RejectReason  *string                `json:"reject_reason"`
+	DecidedBy     *string                `json:"decided_by"`
+	DecidedAt     *time.Time             `json:"decided_at"`
+	CreatedAt     time.Time              `json:"created_at"`
+	Decisions     []AISuggestionDecision `json:"decisions,omitempty"`
+}
+
+type AISuggestionDecision struct {
+	Field          string    `json:"field"` // "priority", "category", "summary", "tags"
+	SuggestedValue string    `json:"suggested_value"`
+	PreviousValue  *string   `json:"previous_value"`
+	Accepted       bool      `json:"accepted"`
+	Reason         *string   `json:"reason"`
+	DecidedBy      string    `json:"decided_by"`
+	DecidedAt      time.Time `json:"decided_at"`
+}
+
+type SuggestionFieldStats struct {
+	Field          string  `json:"field"`
+	Decided        int     `json:"decided"`
+	Accepted       int     `json:"accepted"`
+	Rejected       int     `json:"rejected"`
+	AcceptanceRate float64 `json:"acceptance_rate"`
+}
+
+type ClassificationCacheStats struct {
+	Entries   int     `json:"entries"`
+	TotalHits int64   `json:"total_hits"` // lookups answered from the cache by any replica
+	Hits      int64   `json:"hits"`       // counters of this replica since it started
+	Misses    int64   `json:"misses"`
+	HitRate   float64 `json:"hit_rate"`
+}
+
+type ClassificationBatchFilter struct {
+	Unclassified     bool       `json:"unclassified,omitempty"`      // no category and never classified
+	ClassifiedBefore *time.Time `json:"classified_before,omitempty"` // latest suggestion older than this
+	Category         *string    `json:"category,omitempty"`
+	Limit            int        `json:"limit,omitempty"`
+}
+
+type ClassificationBatch struct {
+	ID            string                      `json:"id"`
+	RequestedBy   string                      `json:"requested_by"`
+	Status        string                      `json:"status"` // "running", "succeeded", "failed", "cancelled"
+	Filter        ClassificationBatchFilter   `json:"filter"`
+	DryRun        bool                        `json:"dry_run"`
+	Force         bool                        `json:"force"`
+	Concurrency   int                         `json:"concurrency"`
+	RatePerMinute int                         `json:"rate_per_minute"` // 0 means unlimited
+	LastError     *string                     `json:"last_error"`
+	Progress      ClassificationBatchProgress `json:"progress"`
+	CreatedAt     time.Time                   `json:"created_at"`
+	FinishedAt    *time.Time                  `json:"finished_at"`
+}
+
+type ClassificationBatchProgress struct {
+	Total   int `json:"total"`
+	Pending int `json:"pending"` // not classified yet, including the ones in flight
+	Done    int `json:"done"`
+	Failed  int `json:"failed"`
+	Changed int `json:"changed"` // done tasks whose classification differs from the task
+}
+
+type ClassificationBatchItem struct {
+	TaskID     string                 `json:"task_id"`
+	Title      string                 `json:"title"`
+	Status     string                 `json:"status"` // "pending", "running", "done", "failed"
+	Changes    map[string]FieldChange `json:"changes,omitempty"`
+	Error      *string                `json:"error"`
+	FinishedAt *time.Time             `json:"finished_at"`
+}
+
+type FieldChange struct {
+	From *string `json:"from"`
+	To   string  `json:"to"`
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+)
+
+type EscalationRule struct {
+	ID               string    `json:"id"`
+	Name             string    `json:"name"`
+	Condition        string    `json:"condition"` // "overdue" or "stale_status"
+	Priority         *string   `json:"priority"`  // only tasks with this priority, any if null
+	Status           *string   `json:"status"`    // status a task is stuck in, for "stale_status"
+	ThresholdMinutes int       `json:"threshold_minutes"`
+	Notify           []string  `json:"notify"`         // "assignee", "creator", "watchers", "admins"
+	RepeatMinutes    *int      `json:"repeat_minutes"` // re-notify interval until acknowledged, never if null
+	Enabled          bool      `json:"enabled"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+type EscalationLog struct {
+	ID              string     `json:"id"`
+	RuleID          string     `json:"rule_id"`
+	RuleName        string     `json:"rule_name"`
+	TaskID          string     `json:"task_id"`
+	NotifiedUserIDs []string   `json:"notified_user_ids"`
+	NotifyCount     int        `json:"notify_count"`
+	TriggeredAt     time.Time  `json:"triggered_at"`
+	LastNotifiedAt  time.Time  `json:"last_notified_at"`
+	AcknowledgedAt  *time.Time `json:"acknowledged_at"`
+	AcknowledgedBy  *string    `json:"acknowledged_by"`
+	ResolvedAt      *time.Time `json:"resolved_at"`
+}
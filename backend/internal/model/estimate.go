@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+type EffortNeighbour struct {
+	TaskID      string  `json:"task_id"`
+	Title       string  `json:"title"`
+	ActualHours float64 `json:"actual_hours"`
+	Similarity  float64 `json:"similarity"` // 0 to 1
+}
+
+type EffortEstimate struct {
+	ID          string            `json:"id,omitempty"`      // empty for tasks not created yet
+	TaskID      *string           `json:"task_id,omitempty"` // likewise
+	RequestedBy string            `json:"requested_by,omitempty"`
+	Method      string            `json:"method"` // "neighbours", "llm"
+	Hours       float64           `json:"hours"`
+	Low         float64           `json:"low"` // bounds of the 80% interval
+	High        float64           `json:"high"`
+	Confidence  string            `json:"confidence"` // "low", "medium", "high"
+	Neighbours  []EffortNeighbour `json:"neighbours"`
+	Rationale   *string           `json:"rationale"`
+	Provider    *string           `json:"provider"`
+	Model       *string           `json:"model"`
+	CreatedAt   *time.Time        `json:"created_at,omitempty"`
+}
+
+type EffortAccuracyRow struct {
+	Period         time.Time `json:"period"` // start of the week or month (UTC) the tasks were done in
+	Source         string    `json:"source"` // "human" (estimated_hours), "neighbours", "llm"
+	Tasks          int       `json:"tasks"`
+	MeanAbsError   float64   `json:"mean_abs_error_hours"`
+	MeanAbsPctErr  float64   `json:"mean_abs_pct_error"` // relative to actual_hours, 0.25 = 25%
+	Bias           float64   `json:"bias_hours"`         // positive when overestimating
+	WithinInterval *float64  `json:"within_interval"`    // share of actuals inside the interval; null for humans
+}
+
+type EffortAccuracyReport struct {
+	From    time.Time           `json:"from"`
+	To      time.Time           `json:"to"`
+	GroupBy string              `json:"group_by"` // "week", "month"
+	Rows    []EffortAccuracyRow `json:"rows"`
+}
@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+)
+
+type JobRun struct {
+	ID           string     `json:"id"`
+	JobName      string     `json:"job_name"`
+	Trigger      string     `json:"trigger"` // "schedule" or "manual"
+	Status       string     `json:"status"`  // "running", "succeeded", "failed"
+	Error        *string    `json:"error"`
+	ScheduledFor *time.Time `json:"scheduled_for,omitempty"` // slot of a scheduled run
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at"`
+	DurationMS   *int64     `json:"duration_ms"`
+}
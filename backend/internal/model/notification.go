@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+)
+
+type Notification struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	Type      string     `json:"type"` // "assignment", "mention", "status_change", "deadline", "classification", "task_updated", "task_deleted"
+	TaskID    *string    `json:"task_id"`
+	ActorID   *string    `json:"actor_id"`
+	Title     string     `json:"title"`
+	Body      *string    `json:"body"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type NotificationPreference struct {
+	Type  string `json:"type"`
+	InApp bool   `json:"in_app"`
+	Email bool   `json:"email"`
+}
+
+type TaskWatcher struct {
+	TaskID    string    `json:"task_id"`
+	UserID    string    `json:"user_id"`
+	InApp     bool      `json:"in_app"`
+	Email     bool      `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Joined fields
+	User *User `json:"user,omitempty"`
+}
+
+type DigestSettings struct {
+	Frequency  string     `json:"frequency"` // "off", "daily", "weekly"
+	SendHour   int        `json:"send_hour"` // local hour of day, 0-23
+	Weekday    int        `json:"weekday"`   // 0 = Sunday, used by weekly digests
+	Timezone   string     `json:"timezone"`  // IANA name, stored on the user
+	LastSentAt *time.Time `json:"last_sent_at"`
+}
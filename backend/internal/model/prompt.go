@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+type PromptTemplate struct {
+	ID              string     `json:"id"`
+	Name            string     `json:"name"` // "classification"
+	Version         int        `json:"version"`
+	Body            string     `json:"body"` // Go text/template
+	FewShotExamples int        `json:"few_shot_examples"`
+	Notes           *string    `json:"notes"`
+	Active          bool       `json:"active"`
+	CreatedBy       *string    `json:"created_by"`
+	CreatedAt       time.Time  `json:"created_at"`
+	ActivatedAt     *time.Time `json:"activated_at"`
+}
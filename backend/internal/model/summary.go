@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+type TaskEvent struct {
+	At    time.Time `json:"at"`
+	Kind  string    `json:"kind"`  // "created", "status", "assignee", "time", "ai_suggestion", "escalation", "change"
+	Actor *string   `json:"actor"` // name of the user, null for the system
+	Text  string    `json:"text"`  // e.g. "moved the task from todo to in_progress"
+}
+
+type TaskHistorySummary struct {
+	TaskID      string      `json:"task_id"`
+	Summary     string      `json:"summary"`
+	Events      []TaskEvent `json:"events"`
+	Provider    *string     `json:"provider"`
+	Model       *string     `json:"model"`
+	GeneratedAt time.Time   `json:"generated_at"`
+	Cached      bool        `json:"cached"` // no event since it was generated
+}
+
+type ReportTask struct {
+	TaskID   string     `json:"task_id"`
+	Title    string     `json:"title"`
+	Status   string     `json:"status"`
+	Assignee *string    `json:"assignee"` // name
+	DueDate  *time.Time `json:"due_date"`
+	Note     string     `json:"note"` // e.g. "todo → done", "in review for 9 days", "3 days overdue"
+}
+
+type WeeklyReport struct {
+	ID          string       `json:"id"`
+	WeekStart   time.Time    `json:"week_start"` // Monday 00:00 UTC; the report covers the following 7 days
+	WeekEnd     time.Time    `json:"week_end"`
+	Summary     string       `json:"summary"`
+	Created     int          `json:"created"`   // tasks created during the week
+	Completed   int          `json:"completed"` // tasks moved to done during the week
+	Moved       []ReportTask `json:"moved"`     // tasks whose status changed during the week
+	Blocked     []ReportTask `json:"blocked"`
+	Overdue     []ReportTask `json:"overdue"` // as of the end of the week
+	Provider    *string      `json:"provider"`
+	Model       *string      `json:"model"`
+	GeneratedAt time.Time    `json:"generated_at"`
+}
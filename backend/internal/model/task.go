@@ -35,6 +35,14 @@ type Task struct {
 	Creator  *User  `json:"creator,omitempty"`
 	Assignee *User  `json:"assignee,omitempty"`
 	Tags     []Tag  `json:"tags,omitempty"`
+
+	// Pending AI classification awaiting a user's decision
+	PendingSuggestion *AISuggestion `json:"pending_suggestion,omitempty"`
+
+	// Task this one was merged into
+	DuplicateOf *string `json:"duplicate_of,omitempty"`
+	// Likely duplicates, returned when the task is created
+	PossibleDuplicates []SimilarTask `json:"possible_duplicates,omitempty"`
 }
 
 type EditHistory struct {
@@ -81,3 +89,21 @@ type UpdateTaskRequest struct {
 	EstimatedHours *float64 `json:"estimated_hours"`
 	ActualHours    *float64 `json:"actual_hours"`
 }
+
+type TaskDraft struct {
+	Text               string            `json:"text"`
+	Request            CreateTaskRequest `json:"request"` // POST to /api/tasks once confirmed
+	Assignee           *User             `json:"assignee"`
+	AssigneeCandidates []User            `json:"assignee_candidates,omitempty"` // when the name is ambiguous
+	Timezone           string            `json:"timezone"`                      // the due date is resolved in it
+	Warnings           []string          `json:"warnings"`
+	Provider           string            `json:"provider,omitempty"`
+	Model              string            `json:"model,omitempty"`
+}
+
+type SimilarTask struct {
+	ID     string  `json:"id"`
+	Title  string  `json:"title"`
+	Status string  `json:"status"`
+	Score  float64 `json:"score"` // cosine similarity of the tasks' embeddings, up to 1
+}
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+type LLMUsageRow struct {
+	Key              string  `json:"key"` // day (YYYY-MM-DD), user email or provider:model
+	Calls            int     `json:"calls"`
+	Failures         int     `json:"failures"`
+	InputTokens      int64   `json:"input_tokens"`
+	OutputTokens     int64   `json:"output_tokens"`
+	AvgLatencyMs     float64 `json:"avg_latency_ms"`
+	EstimatedCostUSD float64 `json:"estimated_cost_usd"` // calls to models without a price count as 0
+}
+
+type LLMUsageReport struct {
+	Workspace string        `json:"workspace"`
+	From      time.Time     `json:"from"`
+	To        time.Time     `json:"to"`
+	GroupBy   string        `json:"group_by"` // "day", "user", "model"
+	Rows      []LLMUsageRow `json:"rows"`
+	Total     LLMUsageRow   `json:"total"`
+}
+
+type LLMBudget struct {
+	Workspace         string    `json:"workspace"`
+	MonthlyTokenLimit *int64    `json:"monthly_token_limit"` // null means unlimited
+	UsedTokens        int64     `json:"used_tokens"`         // this calendar month (UTC)
+	PeriodStart       time.Time `json:"period_start"`
+}
+
+type LLMRedactionRow struct {
+	Kind     string `json:"kind"`
+	Requests int    `json:"requests"` // requests that had values of this kind
+	Values   int64  `json:"values"`
+	Tasks    int    `json:"tasks"`
+}
+
+type LLMRedactionReport struct {
+	Workspace string            `json:"workspace"`
+	From      time.Time         `json:"from"`
+	To        time.Time         `json:"to"`
+	Rows      []LLMRedactionRow `json:"rows"`
+}
@@ -0,0 +1,307 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+// Digest frequencies.
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// DigestTask is a task listed in a digest section.
+type DigestTask struct {
+	TaskID  string     `json:"task_id"`
+	Title   string     `json:"title"`
+	DueDate *time.Time `json:"due_date,omitempty"`
+	URL     string     `json:"url"`
+}
+
+// DigestStatusChange is a status change on a watched task.
+type DigestStatusChange struct {
+	TaskID    string    `json:"task_id"`
+	Title     string    `json:"title"`
+	OldStatus *string   `json:"old_status"`
+	NewStatus *string   `json:"new_status"`
+	ChangedBy string    `json:"changed_by"`
+	ChangedAt time.Time `json:"changed_at"`
+	URL       string    `json:"url"`
+}
+
+// Digest is the per-user summary of activity for one digest period.
+type Digest struct {
+	UserID        string               `json:"user_id"`
+	Name          string               `json:"name"`
+	Email         string               `json:"-"`
+	Frequency     string               `json:"frequency"`
+	Since         time.Time            `json:"since"`
+	Until         time.Time            `json:"until"`
+	DueSoon       []DigestTask         `json:"due_soon"`
+	Overdue       []DigestTask         `json:"overdue"`
+	NewlyAssigned []DigestTask         `json:"newly_assigned"`
+	StatusChanges []DigestStatusChange `json:"status_changes"`
+}
+
+// IsEmpty reports whether the digest has nothing to report.
+func (d *Digest) IsEmpty() bool {
+	return len(d.DueSoon) == 0 && len(d.Overdue) == 0 && len(d.NewlyAssigned) == 0 && len(d.StatusChanges) == 0
+}
+
+// DigestPeriod returns the period covered by a digest of the given frequency.
+func DigestPeriod(frequency string) time.Duration {
+	if frequency == DigestWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// DigestDue reports whether a digest should be sent at now: the user's local
+// time must be at the configured hour (and weekday, for weekly digests), and
+// the previous digest must be most of a period old. The scheduler runs more
+// often than hourly, so the gap check is what keeps it to one per period.
+func DigestDue(s model.DigestSettings, now time.Time) (bool, error) {
+	if s.Frequency != DigestDaily && s.Frequency != DigestWeekly {
+		return false, nil
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return false, fmt.Errorf("invalid timezone %q: %w", s.Timezone, err)
+	}
+
+	local := now.In(loc)
+	if local.Hour() != s.SendHour {
+		return false, nil
+	}
+	if s.Frequency == DigestWeekly && int(local.Weekday()) != s.Weekday {
+		return false, nil
+	}
+	minGap := DigestPeriod(s.Frequency) - 4*time.Hour
+	if s.LastSentAt != nil && now.Sub(*s.LastSentAt) < minGap {
+		return false, nil
+	}
+	return true, nil
+}
+
+// BuildDigest compiles a user's digest for activity in (since, until].
+func BuildDigest(ctx context.Context, userID, frequency string, since, until time.Time) (*Digest, error) {
+	d := &Digest{UserID: userID, Frequency: frequency, Since: since, Until: until}
+	err := db.Pool.QueryRow(ctx, `SELECT name, email FROM users WHERE id = $1`, userID).Scan(&d.Name, &d.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load digest user: %w", err)
+	}
+
+	dueSoon, err := GetUserDeadlines(ctx, userID, &until, until.Add(DigestPeriod(frequency)))
+	if err != nil {
+		return nil, err
+	}
+	d.DueSoon = digestTasks(dueSoon)
+
+	overdue, err := GetUserDeadlines(ctx, userID, nil, until)
+	if err != nil {
+		return nil, err
+	}
+	d.Overdue = digestTasks(overdue)
+
+	if d.NewlyAssigned, err = newlyAssigned(ctx, userID, since, until); err != nil {
+		return nil, err
+	}
+	if d.StatusChanges, err = watchedStatusChanges(ctx, userID, since, until); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func digestTasks(ns []TaskNotification) []DigestTask {
+	tasks := []DigestTask{}
+	for _, n := range ns {
+		due := n.DueDate
+		tasks = append(tasks, DigestTask{TaskID: n.TaskID, Title: n.TaskTitle, DueDate: &due, URL: TaskURL(n.TaskID)})
+	}
+	return tasks
+}
+
+// newlyAssigned lists tasks assigned to the user by someone else during the
+// period, either at creation or through a reassignment.
+func newlyAssigned(ctx context.Context, userID string, since, until time.Time) ([]DigestTask, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT t.id, t.title, t.due_date
+		 FROM tasks t
+		 WHERE t.assignee_id = $1
+		   AND ((t.created_at > $2 AND t.created_at <= $3 AND t.creator_id != $1)
+		     OR EXISTS (SELECT 1 FROM edit_history h
+		                WHERE h.task_id = t.id AND h.field_name = 'assignee_id'
+		                  AND h.new_value = $1::text AND h.user_id != $1
+		                  AND h.edited_at > $2 AND h.edited_at <= $3))
+		 ORDER BY t.due_date NULLS LAST`,
+		userID, since, until,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query newly assigned tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := []DigestTask{}
+	for rows.Next() {
+		var t DigestTask
+		if err := rows.Scan(&t.TaskID, &t.Title, &t.DueDate); err != nil {
+			return nil, fmt.Errorf("failed to scan assigned task: %w", err)
+		}
+		t.URL = TaskURL(t.TaskID)
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+// watchedStatusChanges lists status changes made by others on tasks the user watches.
+func watchedStatusChanges(ctx context.Context, userID string, since, until time.Time) ([]DigestStatusChange, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT t.id, t.title, h.old_value, h.new_value, u.name, h.edited_at
+		 FROM edit_history h
+		 JOIN tasks t ON t.id = h.task_id
+		 JOIN task_watchers w ON w.task_id = h.task_id AND w.user_id = $1
+		 JOIN users u ON u.id = h.user_id
+		 WHERE h.field_name = 'status'
+		   AND h.user_id != $1
+		   AND h.edited_at > $2 AND h.edited_at <= $3
+		 ORDER BY h.edited_at`,
+		userID, since, until,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query status changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []DigestStatusChange{}
+	for rows.Next() {
+		var c DigestStatusChange
+		if err := rows.Scan(&c.TaskID, &c.Title, &c.OldStatus, &c.NewStatus, &c.ChangedBy, &c.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan status change: %w", err)
+		}
+		c.URL = TaskURL(c.TaskID)
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// GetDigestSettings returns the user's digest settings, defaulting to off.
+func GetDigestSettings(ctx context.Context, userID string) (model.DigestSettings, error) {
+	s := model.DigestSettings{Frequency: DigestOff, SendHour: 8, Weekday: 1}
+	var frequency *string
+	var sendHour, weekday *int
+	err := db.Pool.QueryRow(ctx,
+		`SELECT u.timezone, d.frequency, d.send_hour, d.weekday, d.last_sent_at
+		 FROM users u
+		 LEFT JOIN digest_subscriptions d ON d.user_id = u.id
+		 WHERE u.id = $1`, userID,
+	).Scan(&s.Timezone, &frequency, &sendHour, &weekday, &s.LastSentAt)
+	if err != nil {
+		return s, fmt.Errorf("failed to get digest settings: %w", err)
+	}
+	if frequency != nil {
+		s.Frequency, s.SendHour, s.Weekday = *frequency, *sendHour, *weekday
+	}
+	return s, nil
+}
+
+// SaveDigestSettings stores the user's digest settings and timezone.
+func SaveDigestSettings(ctx context.Context, userID string, s model.DigestSettings) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `UPDATE users SET timezone = $1, updated_at = NOW() WHERE id = $2`, s.Timezone, userID); err != nil {
+		return fmt.Errorf("failed to save timezone: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO digest_subscriptions (user_id, frequency, send_hour, weekday)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (user_id) DO UPDATE
+		 SET frequency = EXCLUDED.frequency, send_hour = EXCLUDED.send_hour, weekday = EXCLUDED.weekday`,
+		userID, s.Frequency, s.SendHour, s.Weekday,
+	); err != nil {
+		return fmt.Errorf("failed to save digest settings: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// SendDigests emails the digests that are due, in each user's timezone.
+// Empty digests are not sent but still advance last_sent_at.
+func SendDigests(ctx context.Context) error {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT d.user_id, d.frequency, d.send_hour, d.weekday, d.last_sent_at, u.timezone
+		 FROM digest_subscriptions d
+		 JOIN users u ON u.id = d.user_id
+		 WHERE d.frequency != 'off' AND u.email_notifications`)
+	if err != nil {
+		return fmt.Errorf("failed to query digest subscriptions: %w", err)
+	}
+
+	type subscription struct {
+		userID string
+		model.DigestSettings
+	}
+	var subs []subscription
+	for rows.Next() {
+		var s subscription
+		if err := rows.Scan(&s.userID, &s.Frequency, &s.SendHour, &s.Weekday, &s.LastSentAt, &s.Timezone); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan digest subscription: %w", err)
+		}
+		subs = append(subs, s)
+	}
+	rows.Close()
+
+	now := time.Now()
+	sent := 0
+	for _, s := range subs {
+		due, err := DigestDue(s.DigestSettings, now)
+		if err != nil {
+			log.Printf("skipping digest for user %s: %v", s.userID, err)
+			continue
+		}
+		if !due {
+			continue
+		}
+
+		since := now.Add(-DigestPeriod(s.Frequency))
+		if s.LastSentAt != nil {
+			since = *s.LastSentAt
+		}
+		d, err := BuildDigest(ctx, s.userID, s.Frequency, since, now)
+		if err != nil {
+			log.Printf("error building digest for user %s: %v", s.userID, err)
+			continue
+		}
+		if !d.IsEmpty() {
+			loc, _ := time.LoadLocation(s.Timezone) // validated by DigestDue
+			msg, err := RenderDigestEmail(d, loc)
+			if err == nil {
+				err = notifier.Notify(ctx, msg)
+			}
+			if err != nil {
+				log.Printf("error sending digest to user %s: %v", s.userID, err)
+				continue
+			}
+			sent++
+		}
+
+		if _, err := db.Pool.Exec(ctx,
+			`UPDATE digest_subscriptions SET last_sent_at = $1 WHERE user_id = $2`, now, s.userID,
+		); err != nil {
+			log.Printf("error recording digest for user %s: %v", s.userID, err)
+		}
+	}
+
+	log.Printf("Sent %d digests", sent)
+	return nil
+}
@@ -0,0 +1,151 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+// Task event types that are only delivered to watchers.
+const (
+	TypeTaskUpdated = "task_updated"
+	TypeTaskDeleted = "task_deleted"
+)
+
+// TaskAssigned notifies the new assignee of a task and subscribes them to it.
+func TaskAssigned(ctx context.Context, taskID, title, assigneeID, actorID string) error {
+	if err := AutoWatch(ctx, taskID, assigneeID); err != nil {
+		return err
+	}
+	return Publish(ctx, model.Notification{
+		Type:    TypeAssignment,
+		TaskID:  &taskID,
+		ActorID: optional(actorID),
+		Title:   "You were assigned: " + title,
+	}, assigneeID)
+}
+
+// TaskMentioned notifies users @mentioned in newText that were not already
+// mentioned in oldText, so editing a description doesn't re-notify everyone.
+func TaskMentioned(ctx context.Context, taskID, title, oldText, newText, actorID string) error {
+	before := map[string]bool{}
+	for _, h := range MentionHandles(oldText) {
+		before[h] = true
+	}
+	var added string
+	for _, h := range MentionHandles(newText) {
+		if !before[h] {
+			added += " @" + h
+		}
+	}
+	if added == "" {
+		return nil
+	}
+
+	userIDs, err := ResolveMentions(ctx, added)
+	if err != nil {
+		return err
+	}
+	return Publish(ctx, model.Notification{
+		Type:    TypeMention,
+		TaskID:  &taskID,
+		ActorID: optional(actorID),
+		Title:   "You were mentioned in: " + title,
+	}, userIDs...)
+}
+
+// TaskUpdated notifies watchers about an edit. A status change is reported as
+// status_change; any other edit as task_updated listing the changed fields.
+// Users in skip, such as a new assignee already notified, are left out.
+func TaskUpdated(ctx context.Context, taskID, title, oldStatus, newStatus string, changedFields []string, actorID string, skip ...string) error {
+	ev := TaskEvent{TaskID: taskID, TaskTitle: title, ActorID: actorID, Skip: skip}
+	var details []string
+	if oldStatus != newStatus {
+		ev.Type = TypeStatusChange
+		ev.Title = "Status changed: " + title
+		details = append(details, fmt.Sprintf("%s → %s", oldStatus, newStatus))
+	} else {
+		ev.Type = TypeTaskUpdated
+		ev.Title = "Task updated: " + title
+	}
+	if len(changedFields) > 0 {
+		details = append(details, "Changed: "+strings.Join(changedFields, ", "))
+	}
+	if len(details) == 0 {
+		return nil
+	}
+	ev.Body = strings.Join(details, ". ")
+	return DispatchTaskEvent(ctx, ev)
+}
+
+// TaskClassified notifies watchers that an AI suggestion awaits review.
+func TaskClassified(ctx context.Context, taskID, title, category, priority, actorID string) error {
+	return DispatchTaskEvent(ctx, TaskEvent{
+		Type:      TypeClassification,
+		TaskID:    taskID,
+		TaskTitle: title,
+		ActorID:   actorID,
+		Title:     "AI suggestion ready: " + title,
+		Body:      fmt.Sprintf("Category: %s, priority: %s", category, priority),
+	})
+}
+
+// TaskDeleted notifies the watchers captured before the task was deleted.
+func TaskDeleted(ctx context.Context, taskID, title, actorID string, watchers []model.TaskWatcher) error {
+	return DispatchToWatchers(ctx, TaskEvent{
+		Type:      TypeTaskDeleted,
+		TaskID:    taskID,
+		TaskTitle: title,
+		ActorID:   actorID,
+		Title:     "Task deleted: " + title,
+		Deleted:   true,
+	}, watchers)
+}
+
+// TaskEscalated delivers an escalation to the given users, in-app and by
+// email, regardless of whether they watch the task.
+func TaskEscalated(ctx context.Context, ev TaskEvent, userIDs []string) error {
+	ev.Type = TypeEscalation
+	if err := Publish(ctx, model.Notification{
+		Type:   ev.Type,
+		TaskID: &ev.TaskID,
+		Title:  ev.Title,
+		Body:   optional(ev.Body),
+	}, userIDs...); err != nil {
+		return err
+	}
+
+	rows, err := db.Pool.Query(ctx, `SELECT id, email FROM users WHERE id = ANY($1)`, userIDs)
+	if err != nil {
+		return fmt.Errorf("failed to load escalation recipients: %w", err)
+	}
+	type recipient struct{ id, email string }
+	var recipients []recipient
+	for rows.Next() {
+		var r recipient
+		if err := rows.Scan(&r.id, &r.email); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan escalation recipient: %w", err)
+		}
+		recipients = append(recipients, r)
+	}
+	rows.Close()
+
+	for _, r := range recipients {
+		if err := emailUser(ctx, ev, r.id, r.email); err != nil {
+			log.Printf("error emailing escalation of task %s to %s: %v", ev.TaskID, r.id, err)
+		}
+	}
+	return nil
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
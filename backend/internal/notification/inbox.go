@@ -0,0 +1,249 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+// In-app notification types. Each one can be toggled per user in
+// notification_preferences.
+const (
+	TypeAssignment     = "assignment"
+	TypeMention        = "mention"
+	TypeStatusChange   = "status_change"
+	TypeDeadline       = "deadline"
+	TypeClassification = "classification"
+	TypeEscalation     = "escalation"
+)
+
+// Types lists every notification type, in display order.
+var Types = []string{
+	TypeAssignment, TypeMention, TypeStatusChange, TypeDeadline, TypeClassification,
+	TypeTaskUpdated, TypeTaskDeleted, TypeEscalation,
+}
+
+// IsValidType reports whether t is a known notification type.
+func IsValidType(t string) bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Publish stores n in each recipient's inbox. The actor (if any) is never
+// notified about their own change, and recipients that disabled in-app
+// notifications for n.Type are skipped.
+func Publish(ctx context.Context, n model.Notification, userIDs ...string) error {
+	seen := map[string]bool{}
+	for _, userID := range userIDs {
+		if userID == "" || seen[userID] || (n.ActorID != nil && *n.ActorID == userID) {
+			continue
+		}
+		seen[userID] = true
+
+		_, err := db.Pool.Exec(ctx,
+			`INSERT INTO notifications (user_id, type, task_id, actor_id, title, body)
+			 SELECT $1, $2, $3, $4, $5, $6
+			 WHERE COALESCE((SELECT in_app FROM notification_preferences WHERE user_id = $1 AND type = $2), TRUE)`,
+			userID, n.Type, n.TaskID, n.ActorID, n.Title, n.Body,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create notification for user %s: %w", userID, err)
+		}
+	}
+	return nil
+}
+
+var mentionPattern = regexp.MustCompile(`(?:^|[\s(])@([A-Za-z0-9._-]+)`)
+
+// ResolveMentions returns the IDs of users mentioned in text as @handle, where
+// the handle is the local part of the user's email (e.g. @lucia).
+func ResolveMentions(ctx context.Context, text string) ([]string, error) {
+	handles := MentionHandles(text)
+	if len(handles) == 0 {
+		return nil, nil
+	}
+
+	rows, err := db.Pool.Query(ctx,
+		`SELECT id FROM users WHERE LOWER(SPLIT_PART(email, '@', 1)) = ANY($1)`, handles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve mentions: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan mentioned user: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// MentionHandles extracts the lowercase, de-duplicated @handles from text.
+func MentionHandles(text string) []string {
+	var handles []string
+	seen := map[string]bool{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		h := strings.ToLower(strings.TrimRight(m[1], "."))
+		if h == "" || seen[h] {
+			continue
+		}
+		seen[h] = true
+		handles = append(handles, h)
+	}
+	return handles
+}
+
+// ListNotifications returns a user's notifications, newest first.
+func ListNotifications(ctx context.Context, userID string, unreadOnly bool, limit int) ([]model.Notification, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT id, user_id, type, task_id, actor_id, title, body, read_at, created_at
+		 FROM notifications
+		 WHERE user_id = $1 AND ($2 = FALSE OR read_at IS NULL)
+		 ORDER BY created_at DESC
+		 LIMIT $3`,
+		userID, unreadOnly, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []model.Notification{}
+	for rows.Next() {
+		var n model.Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.TaskID, &n.ActorID, &n.Title, &n.Body, &n.ReadAt, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// UnreadCounts returns the number of unread notifications per type.
+func UnreadCounts(ctx context.Context, userID string) (map[string]int, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT type, COUNT(*) FROM notifications
+		 WHERE user_id = $1 AND read_at IS NULL GROUP BY type`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count notifications: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var t string
+		var c int
+		if err := rows.Scan(&t, &c); err != nil {
+			return nil, fmt.Errorf("failed to scan notification count: %w", err)
+		}
+		counts[t] = c
+	}
+	return counts, rows.Err()
+}
+
+// MarkRead marks one of the user's notifications as read. It reports false if
+// the notification does not exist or belongs to someone else.
+func MarkRead(ctx context.Context, userID, id string) (bool, error) {
+	result, err := db.Pool.Exec(ctx,
+		`UPDATE notifications SET read_at = COALESCE(read_at, NOW())
+		 WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark notification read: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// MarkAllRead marks every unread notification of the user as read.
+func MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	result, err := db.Pool.Exec(ctx,
+		`UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// GetPreferences returns the user's preference for every notification type,
+// defaulting to enabled for types the user never changed.
+func GetPreferences(ctx context.Context, userID string) ([]model.NotificationPreference, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT type, in_app, email FROM notification_preferences WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notification preferences: %w", err)
+	}
+	defer rows.Close()
+
+	stored := map[string]model.NotificationPreference{}
+	for rows.Next() {
+		var p model.NotificationPreference
+		if err := rows.Scan(&p.Type, &p.InApp, &p.Email); err != nil {
+			return nil, fmt.Errorf("failed to scan notification preference: %w", err)
+		}
+		stored[p.Type] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	prefs := make([]model.NotificationPreference, 0, len(Types))
+	for _, t := range Types {
+		p, ok := stored[t]
+		if !ok {
+			p = model.NotificationPreference{Type: t, InApp: true, Email: true}
+		}
+		prefs = append(prefs, p)
+	}
+	return prefs, nil
+}
+
+// SetPreferences upserts the given per-type preferences for the user.
+func SetPreferences(ctx context.Context, userID string, prefs []model.NotificationPreference) error {
+	for _, p := range prefs {
+		_, err := db.Pool.Exec(ctx,
+			`INSERT INTO notification_preferences (user_id, type, in_app, email)
+			 VALUES ($1, $2, $3, $4)
+			 ON CONFLICT (user_id, type) DO UPDATE SET in_app = EXCLUDED.in_app, email = EXCLUDED.email`,
+			userID, p.Type, p.InApp, p.Email,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save notification preference %s: %w", p.Type, err)
+		}
+	}
+	return nil
+}
+
+// CleanupNotifications deletes inbox entries older than the retention period
+// (NOTIFICATION_RETENTION_DAYS, default 90).
+func CleanupNotifications(ctx context.Context) error {
+	days := 90
+	if v := os.Getenv("NOTIFICATION_RETENTION_DAYS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid NOTIFICATION_RETENTION_DAYS %q", v)
+		}
+		days = n
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -days)
+	result, err := db.Pool.Exec(ctx, `DELETE FROM notifications WHERE created_at < $1`, cutoff)
+	if err != nil {
+		return fmt.Errorf("failed to delete old notifications: %w", err)
+	}
+	log.Printf("Deleted %d notifications older than %d days", result.RowsAffected(), days)
+	return nil
+}
@@ -6,7 +6,10 @@ import (
 	"log"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+
 	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/model"
 )
 
 type TaskNotification struct {
@@ -15,22 +18,43 @@ type TaskNotification struct {
 	AssigneeID string
 	Email      string
 	DueDate    time.Time
+	// EmailEnabled is false when the assignee opted out of notification
+	// emails, globally or for deadline reminders.
+	EmailEnabled bool
 }
 
+// KindDeadline identifies deadline reminders in sent_notifications.
+const KindDeadline = "deadline"
+
 // GetUpcomingDeadlines finds tasks with deadlines approaching within the next 24 hours.
 func GetUpcomingDeadlines(ctx context.Context) ([]TaskNotification, error) {
 	now := time.Now()
 	tomorrow := now.Add(24 * time.Hour)
+	return queryDeadlines(ctx, "", &now, tomorrow)
+}
 
+// GetUserDeadlines finds a user's open tasks due before to, and after from
+// when it is set. A nil from includes overdue tasks.
+func GetUserDeadlines(ctx context.Context, userID string, from *time.Time, to time.Time) ([]TaskNotification, error) {
+	return queryDeadlines(ctx, userID, from, to)
+}
+
+// queryDeadlines lists open, assigned tasks due in (from, to), optionally
+// restricted to a single assignee.
+func queryDeadlines(ctx context.Context, assigneeID string, from *time.Time, to time.Time) ([]TaskNotification, error) {
 	rows, err := db.Pool.Query(ctx,
-		`SELECT t.id, t.title, t.assignee_id, u.email, t.due_date
+		`SELECT t.id, t.title, t.assignee_id, u.email, t.due_date,
+		        u.email_notifications AND COALESCE(p.email, TRUE)
 		 FROM tasks t
 		 JOIN users u ON t.assignee_id = u.id
-		 WHERE t.due_date > $1
+		 LEFT JOIN notification_preferences p ON p.user_id = u.id AND p.type = 'deadline'
+		 WHERE ($1::timestamptz IS NULL OR t.due_date > $1)
 		   AND t.due_date < $2
 		   AND t.status != 'done'
-		   AND t.assignee_id IS NOT NULL`,
-		now, tomorrow,
+		   AND t.assignee_id IS NOT NULL
+		   AND ($3 = '' OR t.assignee_id::text = $3)
+		 ORDER BY t.due_date`,
+		from, to, assigneeID,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query upcoming deadlines: %w", err)
@@ -40,7 +64,7 @@ func GetUpcomingDeadlines(ctx context.Context) ([]TaskNotification, error) {
 	var notifications []TaskNotification
 	for rows.Next() {
 		var n TaskNotification
-		if err := rows.Scan(&n.TaskID, &n.TaskTitle, &n.AssigneeID, &n.Email, &n.DueDate); err != nil {
+		if err := rows.Scan(&n.TaskID, &n.TaskTitle, &n.AssigneeID, &n.Email, &n.DueDate, &n.EmailEnabled); err != nil {
 			log.Printf("error scanning notification: %v", err)
 			continue
 		}
@@ -50,23 +74,95 @@ func GetUpcomingDeadlines(ctx context.Context) ([]TaskNotification, error) {
 	return notifications, nil
 }
 
-// SendDeadlineNotifications sends email notifications for upcoming deadlines.
-// In a real app, this would integrate with SendGrid, SES, etc.
+// SendDeadlineNotifications notifies assignees whose tasks are due within the
+// next 24 hours, both in their inbox and by email. Users who opted out of a
+// channel are skipped, and each task is notified at most once per due date
+// and channel; every delivery is recorded in sent_notifications.
 func SendDeadlineNotifications(ctx context.Context) error {
 	notifications, err := GetUpcomingDeadlines(ctx)
 	if err != nil {
 		return err
 	}
 
+	sent := 0
 	for _, n := range notifications {
-		// Simulate sending email
-		log.Printf("NOTIFICATION: Task '%s' (ID: %s) is due on %s. Notifying %s",
-			n.TaskTitle, n.TaskID, n.DueDate.Format(time.RFC3339), n.Email)
+		// The window key is the due date itself: moving the deadline opens a
+		// new window, re-running the job within the same one is a no-op.
+		window := n.DueDate.UTC().Format(time.RFC3339)
+
+		err := deliverOnce(ctx, KindDeadline, "in_app", n, window, func() error {
+			taskID := n.TaskID
+			body := "Due " + n.DueDate.UTC().Format(time.RFC1123)
+			return Publish(ctx, model.Notification{
+				Type:   TypeDeadline,
+				TaskID: &taskID,
+				Title:  "Deadline approaching: " + n.TaskTitle,
+				Body:   &body,
+			}, n.AssigneeID)
+		})
+		if err != nil {
+			log.Printf("error creating deadline notification for task %s: %v", n.TaskID, err)
+		}
 
-		// In production: send actual email via SendGrid/SES
-		// sendEmail(n.Email, "Task Deadline Approaching", ...)
+		if !n.EmailEnabled {
+			continue
+		}
+		err = deliverOnce(ctx, KindDeadline, "email", n, window, func() error {
+			msg, err := RenderDeadlineEmail(n)
+			if err != nil {
+				return err
+			}
+			return notifier.Notify(ctx, msg)
+		})
+		if err != nil {
+			log.Printf("error sending deadline notification for task %s to %s: %v", n.TaskID, n.Email, err)
+			continue
+		}
+		sent++
 	}
 
-	log.Printf("Sent %d deadline notifications", len(notifications))
+	log.Printf("Sent %d deadline notifications", sent)
 	return nil
 }
+
+// deliverOnce runs send unless a notification of this kind was already
+// recorded for the task, user, channel and window. A failed send releases the
+// record so the next run retries it.
+func deliverOnce(ctx context.Context, kind, channel string, n TaskNotification, window string, send func() error) error {
+	claimID, err := claimNotification(ctx, kind, channel, n.TaskID, n.AssigneeID, window)
+	if err != nil {
+		return fmt.Errorf("failed to record notification: %w", err)
+	}
+	if claimID == "" {
+		return nil // already sent for this window
+	}
+	if err := send(); err != nil {
+		releaseNotification(ctx, claimID)
+		return err
+	}
+	return nil
+}
+
+// claimNotification records a notification before it is sent. It returns the
+// new record's ID, or an empty string if one already exists for the window.
+func claimNotification(ctx context.Context, kind, channel, taskID, userID, window string) (string, error) {
+	var id string
+	err := db.Pool.QueryRow(ctx,
+		`INSERT INTO sent_notifications (kind, task_id, user_id, channel, window_key)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (kind, task_id, user_id, channel, window_key) DO NOTHING
+		 RETURNING id`,
+		kind, taskID, userID, channel, window,
+	).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	return id, err
+}
+
+// releaseNotification removes a claim whose delivery failed so the next run retries it.
+func releaseNotification(ctx context.Context, id string) {
+	if _, err := db.Pool.Exec(ctx, `DELETE FROM sent_notifications WHERE id = $1`, id); err != nil {
+		log.Printf("error releasing notification %s: %v", id, err)
+	}
+}
@@ -0,0 +1,182 @@
+package notification
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"os"
+	texttemplate "text/template"
+	"time"
+)
+
+const deadlineText = `Hi,
+
+The task "{{.TaskTitle}}" assigned to you is due on {{.DueDate.Format "Mon, 02 Jan 2006 15:04 MST"}}.
+
+Open it here: {{.TaskURL}}
+`
+
+const deadlineHTML = `<!DOCTYPE html>
+<html>
+  <body style="font-family: sans-serif; color: #111827;">
+    <p>Hi,</p>
+    <p>The task <strong>{{.TaskTitle}}</strong> assigned to you is due on
+      {{.DueDate.Format "Mon, 02 Jan 2006 15:04 MST"}}.</p>
+    <p><a href="{{.TaskURL}}">Open the task</a></p>
+  </body>
+</html>
+`
+
+const taskEventText = `{{.Title}}
+{{if .Body}}
+{{.Body}}
+{{end}}{{if .TaskURL}}
+Open it here: {{.TaskURL}}
+{{end}}
+{{.Reason}}
+`
+
+const taskEventHTML = `<!DOCTYPE html>
+<html>
+  <body style="font-family: sans-serif; color: #111827;">
+    <p><strong>{{.Title}}</strong></p>
+    {{if .Body}}<p>{{.Body}}</p>{{end}}
+    {{if .TaskURL}}<p><a href="{{.TaskURL}}">Open the task</a></p>{{end}}
+    <p style="color: #6B7280; font-size: 12px;">{{.Reason}}</p>
+  </body>
+</html>
+`
+
+var (
+	deadlineTextTmpl  = texttemplate.Must(texttemplate.New("deadline.txt").Parse(deadlineText))
+	deadlineHTMLTmpl  = htmltemplate.Must(htmltemplate.New("deadline.html").Parse(deadlineHTML))
+	taskEventTextTmpl = texttemplate.Must(texttemplate.New("task_event.txt").Parse(taskEventText))
+	taskEventHTMLTmpl = htmltemplate.Must(htmltemplate.New("task_event.html").Parse(taskEventHTML))
+)
+
+// TaskURL returns the frontend link for a task, based on APP_BASE_URL.
+func TaskURL(taskID string) string {
+	base := os.Getenv("APP_BASE_URL")
+	if base == "" {
+		base = "http://localhost:3000"
+	}
+	return base + "/tasks/" + taskID
+}
+
+// RenderDeadlineEmail renders the text and HTML bodies for a deadline reminder.
+func RenderDeadlineEmail(n TaskNotification) (Message, error) {
+	data := struct {
+		TaskTitle string
+		DueDate   time.Time
+		TaskURL   string
+	}{n.TaskTitle, n.DueDate.UTC(), TaskURL(n.TaskID)}
+
+	var text, html bytes.Buffer
+	if err := deadlineTextTmpl.Execute(&text, data); err != nil {
+		return Message{}, err
+	}
+	if err := deadlineHTMLTmpl.Execute(&html, data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		To:      n.Email,
+		Subject: "Task deadline approaching: " + n.TaskTitle,
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// RenderTaskEventEmail renders the email sent to a watcher about a task change.
+func RenderTaskEventEmail(to string, ev TaskEvent) (Message, error) {
+	data := struct {
+		Title   string
+		Body    string
+		TaskURL string
+		Reason  string
+	}{Title: ev.Title, Body: ev.Body, Reason: ev.Reason}
+	if data.Reason == "" {
+		data.Reason = "You receive this because you watch this task."
+	}
+	if !ev.Deleted {
+		data.TaskURL = TaskURL(ev.TaskID)
+	}
+
+	var text, html bytes.Buffer
+	if err := taskEventTextTmpl.Execute(&text, data); err != nil {
+		return Message{}, err
+	}
+	if err := taskEventHTMLTmpl.Execute(&html, data); err != nil {
+		return Message{}, err
+	}
+	return Message{To: to, Subject: ev.Title, Text: text.String(), HTML: html.String()}, nil
+}
+
+const digestText = `Hi {{.Name}},
+
+Here is your {{.Frequency}} task digest.
+{{if .Overdue}}
+Overdue:
+{{range .Overdue}}  - {{.Title}} (due {{.DueDate.Format "Mon 02 Jan 15:04"}}) {{.URL}}
+{{end}}{{end}}{{if .DueSoon}}
+Due soon:
+{{range .DueSoon}}  - {{.Title}} (due {{.DueDate.Format "Mon 02 Jan 15:04"}}) {{.URL}}
+{{end}}{{end}}{{if .NewlyAssigned}}
+Newly assigned to you:
+{{range .NewlyAssigned}}  - {{.Title}} {{.URL}}
+{{end}}{{end}}{{if .StatusChanges}}
+Status changes on tasks you watch:
+{{range .StatusChanges}}  - {{.Title}}: {{with .OldStatus}}{{.}}{{end}} → {{with .NewStatus}}{{.}}{{end}} by {{.ChangedBy}}
+{{end}}{{end}}`
+
+const digestHTML = `<!DOCTYPE html>
+<html>
+  <body style="font-family: sans-serif; color: #111827;">
+    <p>Hi {{.Name}},</p>
+    <p>Here is your {{.Frequency}} task digest.</p>
+    {{if .Overdue}}<h3 style="color: #EF4444;">Overdue</h3>
+    <ul>{{range .Overdue}}<li><a href="{{.URL}}">{{.Title}}</a> — due {{.DueDate.Format "Mon 02 Jan 15:04"}}</li>{{end}}</ul>{{end}}
+    {{if .DueSoon}}<h3>Due soon</h3>
+    <ul>{{range .DueSoon}}<li><a href="{{.URL}}">{{.Title}}</a> — due {{.DueDate.Format "Mon 02 Jan 15:04"}}</li>{{end}}</ul>{{end}}
+    {{if .NewlyAssigned}}<h3>Newly assigned to you</h3>
+    <ul>{{range .NewlyAssigned}}<li><a href="{{.URL}}">{{.Title}}</a></li>{{end}}</ul>{{end}}
+    {{if .StatusChanges}}<h3>Status changes on tasks you watch</h3>
+    <ul>{{range .StatusChanges}}<li><a href="{{.URL}}">{{.Title}}</a>: {{with .OldStatus}}{{.}}{{end}} → {{with .NewStatus}}{{.}}{{end}} by {{.ChangedBy}}</li>{{end}}</ul>{{end}}
+  </body>
+</html>
+`
+
+var (
+	digestTextTmpl = texttemplate.Must(texttemplate.New("digest.txt").Parse(digestText))
+	digestHTMLTmpl = htmltemplate.Must(htmltemplate.New("digest.html").Parse(digestHTML))
+)
+
+// RenderDigestEmail renders a digest as text and HTML. Due dates are shown in loc.
+func RenderDigestEmail(d *Digest, loc *time.Location) (Message, error) {
+	local := *d
+	local.DueSoon = inLocation(d.DueSoon, loc)
+	local.Overdue = inLocation(d.Overdue, loc)
+
+	var text, html bytes.Buffer
+	if err := digestTextTmpl.Execute(&text, local); err != nil {
+		return Message{}, err
+	}
+	if err := digestHTMLTmpl.Execute(&html, local); err != nil {
+		return Message{}, err
+	}
+	subject := "Your daily task digest"
+	if d.Frequency == DigestWeekly {
+		subject = "Your weekly task digest"
+	}
+	return Message{To: d.Email, Subject: subject, Text: text.String(), HTML: html.String()}, nil
+}
+
+func inLocation(tasks []DigestTask, loc *time.Location) []DigestTask {
+	out := make([]DigestTask, len(tasks))
+	for i, t := range tasks {
+		if t.DueDate != nil {
+			due := t.DueDate.In(loc)
+			t.DueDate = &due
+		}
+		out[i] = t
+	}
+	return out
+}
@@ -0,0 +1,168 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+// Watch subscribes a user to a task's change events, or updates the delivery
+// preferences of an existing subscription.
+func Watch(ctx context.Context, taskID, userID string, inApp, email bool) error {
+	_, err := db.Pool.Exec(ctx,
+		`INSERT INTO task_watchers (task_id, user_id, in_app, email)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (task_id, user_id) DO UPDATE SET in_app = EXCLUDED.in_app, email = EXCLUDED.email`,
+		taskID, userID, inApp, email,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to watch task: %w", err)
+	}
+	return nil
+}
+
+// AutoWatch subscribes users with default preferences, keeping existing
+// subscriptions untouched. Used for creators and assignees.
+func AutoWatch(ctx context.Context, taskID string, userIDs ...string) error {
+	for _, userID := range userIDs {
+		if userID == "" {
+			continue
+		}
+		_, err := db.Pool.Exec(ctx,
+			`INSERT INTO task_watchers (task_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+			taskID, userID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to auto-watch task: %w", err)
+		}
+	}
+	return nil
+}
+
+// Unwatch removes a user's subscription. It reports false if there was none.
+func Unwatch(ctx context.Context, taskID, userID string) (bool, error) {
+	result, err := db.Pool.Exec(ctx,
+		`DELETE FROM task_watchers WHERE task_id = $1 AND user_id = $2`, taskID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to unwatch task: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// ListWatchers returns a task's watchers along with their user record.
+func ListWatchers(ctx context.Context, taskID string) ([]model.TaskWatcher, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT w.task_id, w.user_id, w.in_app, w.email, w.created_at,
+		        u.id, u.email, u.name, u.role, u.avatar_url, u.created_at, u.updated_at
+		 FROM task_watchers w
+		 JOIN users u ON u.id = w.user_id
+		 WHERE w.task_id = $1
+		 ORDER BY w.created_at`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watchers: %w", err)
+	}
+	defer rows.Close()
+
+	watchers := []model.TaskWatcher{}
+	for rows.Next() {
+		var w model.TaskWatcher
+		var u model.User
+		if err := rows.Scan(&w.TaskID, &w.UserID, &w.InApp, &w.Email, &w.CreatedAt,
+			&u.ID, &u.Email, &u.Name, &u.Role, &u.AvatarURL, &u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan watcher: %w", err)
+		}
+		w.User = &u
+		watchers = append(watchers, w)
+	}
+	return watchers, rows.Err()
+}
+
+// TaskEvent is a change to a task that is fanned out to its watchers.
+type TaskEvent struct {
+	Type      string
+	TaskID    string
+	TaskTitle string
+	ActorID   string
+	Title     string
+	Body      string
+	// Deleted marks events about tasks that no longer exist; their
+	// notifications are stored without a task reference.
+	Deleted bool
+	// Reason overrides the email footer explaining why it was received.
+	Reason string
+	// Skip lists users already told about this change by another
+	// notification, e.g. the new assignee of an updated task.
+	Skip []string
+}
+
+// DispatchToWatchers delivers ev to every watcher except the actor and
+// ev.Skip, honouring each watcher's in_app/email subscription and their
+// per-type preferences.
+func DispatchToWatchers(ctx context.Context, ev TaskEvent, watchers []model.TaskWatcher) error {
+	n := model.Notification{
+		Type:    ev.Type,
+		ActorID: optional(ev.ActorID),
+		Title:   ev.Title,
+		Body:    optional(ev.Body),
+	}
+	if !ev.Deleted {
+		n.TaskID = &ev.TaskID
+	}
+
+	var inApp []string
+	for _, w := range Recipients(ev, watchers) {
+		if w.InApp {
+			inApp = append(inApp, w.UserID)
+		}
+		if w.Email && w.User != nil {
+			if err := emailUser(ctx, ev, w.UserID, w.User.Email); err != nil {
+				log.Printf("error emailing watcher %s of task %s: %v", w.UserID, ev.TaskID, err)
+			}
+		}
+	}
+	return Publish(ctx, n, inApp...)
+}
+
+// Recipients returns the watchers ev goes to: all but the actor and ev.Skip.
+func Recipients(ev TaskEvent, watchers []model.TaskWatcher) []model.TaskWatcher {
+	var recipients []model.TaskWatcher
+	for _, w := range watchers {
+		if w.UserID != ev.ActorID && !slices.Contains(ev.Skip, w.UserID) {
+			recipients = append(recipients, w)
+		}
+	}
+	return recipients
+}
+
+// DispatchTaskEvent loads the task's watchers and dispatches ev to them.
+func DispatchTaskEvent(ctx context.Context, ev TaskEvent) error {
+	watchers, err := ListWatchers(ctx, ev.TaskID)
+	if err != nil {
+		return err
+	}
+	return DispatchToWatchers(ctx, ev, watchers)
+}
+
+// emailUser sends ev to a user unless they disabled email globally or for ev.Type.
+func emailUser(ctx context.Context, ev TaskEvent, userID, email string) error {
+	var enabled bool
+	err := db.Pool.QueryRow(ctx,
+		`SELECT u.email_notifications AND COALESCE(
+		     (SELECT email FROM notification_preferences WHERE user_id = u.id AND type = $2), TRUE)
+		 FROM users u WHERE u.id = $1`,
+		userID, ev.Type,
+	).Scan(&enabled)
+	if err != nil || !enabled {
+		return err
+	}
+
+	msg, err := RenderTaskEventEmail(email, ev)
+	if err != nil {
+		return err
+	}
+	return notifier.Notify(ctx, msg)
+}
@@ -0,0 +1,239 @@
+// Package prompts stores versioned LLM prompt templates. Exactly one version
+// of each template is active; activating an older one rolls back to it.
+package prompts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/llm"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+// Classification is the template used to classify tasks.
+const Classification = "classification"
+
+// Names lists the templates that can be managed.
+var Names = []string{Classification}
+
+const maxFewShotExamples = 20
+
+var (
+	ErrNotFound        = errors.New("prompt template not found")
+	ErrUnknownName     = errors.New("unknown prompt template")
+	ErrInvalidTemplate = errors.New("invalid prompt template")
+)
+
+const templateColumns = `id, name, version, body, few_shot_examples, notes, active, created_by, created_at, activated_at`
+
+func scanTemplate(row pgx.Row) (*model.PromptTemplate, error) {
+	var t model.PromptTemplate
+	err := row.Scan(&t.ID, &t.Name, &t.Version, &t.Body, &t.FewShotExamples, &t.Notes, &t.Active,
+		&t.CreatedBy, &t.CreatedAt, &t.ActivatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func checkName(name string) error {
+	for _, n := range Names {
+		if n == name {
+			return nil
+		}
+	}
+	return ErrUnknownName
+}
+
+// List returns every version of a template, newest first.
+func List(ctx context.Context, name string) ([]model.PromptTemplate, error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+	rows, err := db.Pool.Query(ctx,
+		`SELECT `+templateColumns+` FROM prompt_templates WHERE name = $1 ORDER BY version DESC`, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query prompt templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []model.PromptTemplate{}
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan prompt template: %w", err)
+		}
+		templates = append(templates, *t)
+	}
+	return templates, rows.Err()
+}
+
+// Create stores t.Body as the next version of the template. Versions are
+// immutable; the new one only takes effect if activate is set.
+func Create(ctx context.Context, name string, t model.PromptTemplate, userID string, activate bool) (*model.PromptTemplate, error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(t.Body) == "" {
+		return nil, fmt.Errorf("%w: body is required", ErrInvalidTemplate)
+	}
+	if t.FewShotExamples < 0 || t.FewShotExamples > maxFewShotExamples {
+		return nil, fmt.Errorf("%w: few_shot_examples must be between 0 and %d", ErrInvalidTemplate, maxFewShotExamples)
+	}
+	if _, err := llm.NewPromptTemplate("new", t.Body, sampleExamples(t.FewShotExamples)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	if err := lockName(ctx, tx, name); err != nil {
+		return nil, err
+	}
+	created, err := scanTemplate(tx.QueryRow(ctx,
+		`INSERT INTO prompt_templates (name, version, body, few_shot_examples, notes, created_by)
+		 SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, $5 FROM prompt_templates WHERE name = $1
+		 RETURNING `+templateColumns,
+		name, t.Body, t.FewShotExamples, t.Notes, userID,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create prompt template: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create prompt template: %w", err)
+	}
+	if activate {
+		return Activate(ctx, name, created.Version)
+	}
+	return created, nil
+}
+
+// Activate makes version the one in use, pinning it until another version
+// is activated.
+func Activate(ctx context.Context, name string, version int) (*model.PromptTemplate, error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	if err := lockName(ctx, tx, name); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx,
+		`UPDATE prompt_templates SET active = FALSE WHERE name = $1 AND active AND version <> $2`, name, version,
+	); err != nil {
+		return nil, fmt.Errorf("failed to deactivate prompt template: %w", err)
+	}
+	t, err := scanTemplate(tx.QueryRow(ctx,
+		`UPDATE prompt_templates SET active = TRUE, activated_at = NOW()
+		 WHERE name = $1 AND version = $2
+		 RETURNING `+templateColumns, name, version))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to activate prompt template: %w", err)
+	}
+	return t, tx.Commit(ctx)
+}
+
+// lockName serializes changes to the versions of a template until tx ends,
+// so concurrent creates don't pick the same next version.
+func lockName(ctx context.Context, tx pgx.Tx, name string) error {
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "prompt_templates:"+name); err != nil {
+		return fmt.Errorf("failed to lock prompt template %s: %w", name, err)
+	}
+	return nil
+}
+
+// ForTask returns the active classification template with few-shot examples
+// for classifying taskID. It falls back to the built-in prompt when no
+// version is active or the active one can't be loaded.
+func ForTask(ctx context.Context, taskID string) *llm.PromptTemplate {
+	t, err := scanTemplate(db.Pool.QueryRow(ctx,
+		`SELECT `+templateColumns+` FROM prompt_templates WHERE name = $1 AND active`, Classification))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return llm.PromptTemplateFrom(ctx)
+	}
+	if err != nil {
+		log.Printf("error loading prompt template, using the built-in prompt: %v", err)
+		return llm.PromptTemplateFrom(ctx)
+	}
+
+	examples, err := Examples(ctx, taskID, t.FewShotExamples)
+	if err != nil {
+		log.Printf("error loading few-shot examples, classifying without them: %v", err)
+	}
+	p, err := llm.NewPromptTemplate(fmt.Sprintf("v%d", t.Version), t.Body, examples)
+	if err != nil {
+		log.Printf("prompt template v%d is invalid, using the built-in prompt: %v", t.Version, err)
+		return llm.PromptTemplateFrom(ctx)
+	}
+	return p
+}
+
+// maxExampleDescription caps the description of a few-shot example, in runes.
+const maxExampleDescription = 500
+
+// Examples returns up to n classifications users accepted in full, newest
+// first and at most one per task, excluding the task being classified.
+func Examples(ctx context.Context, excludeTaskID string, n int) ([]llm.PromptExample, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	rows, err := db.Pool.Query(ctx,
+		`SELECT title, description, priority, category, summary, tags FROM (
+		     SELECT DISTINCT ON (s.task_id) t.title, COALESCE(t.description, '') AS description,
+		            s.priority, s.category, s.summary, s.tags, s.decided_at
+		     FROM ai_suggestions s
+		     JOIN tasks t ON t.id = s.task_id
+		     WHERE s.status = 'accepted' AND s.task_id::text <> $1
+		     ORDER BY s.task_id, s.decided_at DESC
+		 ) latest
+		 ORDER BY decided_at DESC
+		 LIMIT $2`, excludeTaskID, n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query few-shot examples: %w", err)
+	}
+	defer rows.Close()
+
+	var examples []llm.PromptExample
+	for rows.Next() {
+		var e llm.PromptExample
+		c := &e.Classification
+		if err := rows.Scan(&e.Title, &e.Description, &c.Priority, &c.Category, &c.Summary, &c.Tags); err != nil {
+			return nil, fmt.Errorf("failed to scan few-shot example: %w", err)
+		}
+		if r := []rune(e.Description); len(r) > maxExampleDescription {
+			e.Description = string(r[:maxExampleDescription]) + "..."
+		}
+		examples = append(examples, e)
+	}
+	return examples, rows.Err()
+}
+
+// sampleExamples returns n placeholder examples to check that a template
+// renders with examples.
+func sampleExamples(n int) []llm.PromptExample {
+	examples := make([]llm.PromptExample, n)
+	for i := range examples {
+		examples[i] = llm.PromptExample{
+			Title:          "Example task",
+			Description:    "Example description",
+			Classification: llm.TaskClassification{Tags: []string{"example"}, Priority: "medium", Category: "feature", Summary: "Example"},
+		}
+	}
+	return examples
+}
@@ -0,0 +1,257 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+// JobFunc is the work performed by a scheduled job.
+type JobFunc func(ctx context.Context) error
+
+// ErrJobNotFound is returned when triggering a job that was never registered.
+var ErrJobNotFound = errors.New("job not found")
+
+// ErrJobRunning is returned when a job is already running on this or another
+// replica and therefore holds the job's advisory lock.
+var ErrJobRunning = errors.New("job already running")
+
+// errSlotTaken is returned when another replica already ran a scheduled slot.
+var errSlotTaken = errors.New("scheduled run already taken")
+
+type job struct {
+	name     string
+	spec     string
+	schedule Schedule
+	fn       JobFunc
+}
+
+// JobInfo describes a registered job for the admin API.
+type JobInfo struct {
+	Name     string    `json:"name"`
+	Schedule string    `json:"schedule"`
+	NextRun  time.Time `json:"next_run"`
+}
+
+// Scheduler runs registered jobs in-process on cron-style schedules. Each run
+// takes a Postgres advisory lock keyed by the job name, so when several
+// replicas run the same scheduler only one of them executes a given job.
+// Scheduled runs also claim their slot in job_runs, so a replica whose timer
+// fires after another one finished doesn't run the same slot again.
+type Scheduler struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+	// ctx is the scheduler's lifetime; manual runs inherit it so they stop on shutdown.
+	ctx context.Context
+}
+
+func New() *Scheduler {
+	return &Scheduler{jobs: make(map[string]*job), ctx: context.Background()}
+}
+
+// Register adds a job. It must be called before Start.
+func (s *Scheduler) Register(name, spec string, fn JobFunc) error {
+	sched, err := ParseSchedule(spec)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.jobs[name]; exists {
+		return fmt.Errorf("job %s already registered", name)
+	}
+	s.jobs[name] = &job{name: name, spec: spec, schedule: sched, fn: fn}
+	return nil
+}
+
+// Start launches one goroutine per job. Jobs stop when ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	jobs := make([]*job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j)
+	}
+	s.mu.Unlock()
+
+	for _, j := range jobs {
+		go s.loop(ctx, j)
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, j *job) {
+	for {
+		next := j.schedule.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("scheduler: job %s has no upcoming run, stopping", j.name)
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		conn, run, err := s.begin(ctx, j, "schedule", &next)
+		if errors.Is(err, ErrJobRunning) || errors.Is(err, errSlotTaken) {
+			// Another replica is the leader for this run.
+			continue
+		}
+		if err != nil {
+			log.Printf("scheduler: job %s failed to start: %v", j.name, err)
+			continue
+		}
+		s.execute(ctx, j, conn, run)
+	}
+}
+
+// Trigger starts a manual run of the named job in the background and returns
+// the run record as soon as the advisory lock is held.
+func (s *Scheduler) Trigger(ctx context.Context, name string) (*model.JobRun, error) {
+	s.mu.Lock()
+	j, ok := s.jobs[name]
+	runCtx := s.ctx
+	s.mu.Unlock()
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+
+	conn, run, err := s.begin(ctx, j, "manual", nil)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := *run
+	go s.execute(runCtx, j, conn, run)
+	return &snapshot, nil
+}
+
+// Jobs lists registered jobs sorted by name.
+func (s *Scheduler) Jobs() []JobInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	infos := make([]JobInfo, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		infos = append(infos, JobInfo{Name: j.name, Schedule: j.spec, NextRun: j.schedule.Next(now)})
+	}
+	sort.Slice(infos, func(a, b int) bool { return infos[a].Name < infos[b].Name })
+	return infos
+}
+
+// begin acquires the job's advisory lock on a dedicated connection and records
+// a "running" row in job_runs, claiming slot for scheduled runs. The returned
+// connection keeps the lock until execute releases it.
+func (s *Scheduler) begin(ctx context.Context, j *job, trigger string, slot *time.Time) (*pgxpool.Conn, *model.JobRun, error) {
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock(hashtext($1))`, lockKey(j.name)).Scan(&locked); err != nil {
+		conn.Release()
+		return nil, nil, fmt.Errorf("failed to take advisory lock: %w", err)
+	}
+	if !locked {
+		conn.Release()
+		return nil, nil, ErrJobRunning
+	}
+
+	run := &model.JobRun{JobName: j.name, Trigger: trigger, Status: "running", ScheduledFor: slot}
+	err = conn.QueryRow(ctx,
+		`INSERT INTO job_runs (job_name, trigger, status, scheduled_for) VALUES ($1, $2, 'running', $3)
+		 ON CONFLICT (job_name, scheduled_for) DO NOTHING
+		 RETURNING id, started_at`,
+		j.name, trigger, slot,
+	).Scan(&run.ID, &run.StartedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		unlock(conn, j.name)
+		return nil, nil, errSlotTaken
+	}
+	if err != nil {
+		unlock(conn, j.name)
+		return nil, nil, fmt.Errorf("failed to record job run: %w", err)
+	}
+	return conn, run, nil
+}
+
+// execute runs the job, records the outcome and releases the lock.
+func (s *Scheduler) execute(ctx context.Context, j *job, conn *pgxpool.Conn, run *model.JobRun) {
+	defer unlock(conn, j.name)
+
+	start := time.Now()
+	err := safeRun(ctx, j.fn)
+	duration := time.Since(start).Milliseconds()
+
+	status := "succeeded"
+	var errMsg *string
+	if err != nil {
+		status = "failed"
+		msg := err.Error()
+		errMsg = &msg
+		log.Printf("scheduler: job %s failed after %dms: %v", j.name, duration, err)
+	}
+
+	// Record the outcome even if the job's context was cancelled.
+	if _, err := conn.Exec(context.Background(),
+		`UPDATE job_runs SET status=$1, error=$2, finished_at=NOW(), duration_ms=$3 WHERE id=$4`,
+		status, errMsg, duration, run.ID,
+	); err != nil {
+		log.Printf("scheduler: failed to record result of job %s: %v", j.name, err)
+	}
+}
+
+func safeRun(ctx context.Context, fn JobFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
+
+func unlock(conn *pgxpool.Conn, name string) {
+	if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock(hashtext($1))`, lockKey(name)); err != nil {
+		log.Printf("scheduler: failed to release lock for job %s: %v", name, err)
+	}
+	conn.Release()
+}
+
+func lockKey(name string) string {
+	return "scheduler:" + name
+}
+
+// ListRuns returns the most recent runs of a job, newest first.
+func ListRuns(ctx context.Context, name string, limit int) ([]model.JobRun, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT id, job_name, trigger, status, error, scheduled_for, started_at, finished_at, duration_ms
+		 FROM job_runs WHERE job_name = $1 ORDER BY started_at DESC LIMIT $2`,
+		name, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query job runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []model.JobRun{}
+	for rows.Next() {
+		var r model.JobRun
+		if err := rows.Scan(&r.ID, &r.JobName, &r.Trigger, &r.Status, &r.Error, &r.ScheduledFor, &r.StartedAt, &r.FinishedAt, &r.DurationMS); err != nil {
+			return nil, fmt.Errorf("failed to scan job run: %w", err)
+		}
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
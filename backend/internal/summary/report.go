@@ -0,0 +1,325 @@
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/llm"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+const (
+	// BlockedAfter is how long a task may stay in progress or in review
+	// before a report lists it as blocked.
+	BlockedAfter = 7 * 24 * time.Hour
+	// MaxReportTasks caps each list of a report; counts include every task.
+	MaxReportTasks = 50
+	week           = 7 * 24 * time.Hour
+)
+
+// ErrReportNotFound is returned when no report was generated for a week.
+var ErrReportNotFound = errors.New("weekly report not found")
+
+// OpenTask is a task not done yet, as a report looks at it.
+type OpenTask struct {
+	model.ReportTask
+	StatusSince time.Time // when the task last changed status, or was created
+	Escalation  *string   // name of a rule the task was escalated under and not resolved
+}
+
+// WeekStart returns the Monday 00:00 UTC starting t's week.
+func WeekStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// GenerateLastWeek writes the report of the week before the current one; it
+// is run by the weekly-report job.
+func GenerateLastWeek(summarizer llm.Summarizer) func(context.Context) error {
+	return func(ctx context.Context) error {
+		r, err := GenerateWeekly(ctx, summarizer, WeekStart(time.Now()).Add(-week))
+		if err != nil {
+			return err
+		}
+		log.Printf("Weekly report for %s: %d moved, %d blocked, %d overdue",
+			r.WeekStart.Format("2006-01-02"), len(r.Moved), len(r.Blocked), len(r.Overdue))
+		return nil
+	}
+}
+
+// GenerateWeekly writes, or rewrites, the report of the week starting at
+// weekStart: the tasks created, those whose status changed, and those
+// blocked or overdue at the end of the week. Blocked and overdue tasks are
+// judged by their current status, so a report is most accurate when
+// generated right after its week. Merged duplicates are left out.
+func GenerateWeekly(ctx context.Context, summarizer llm.Summarizer, weekStart time.Time) (*model.WeeklyReport, error) {
+	if summarizer == nil {
+		return nil, llm.ErrUnsupported
+	}
+	start := WeekStart(weekStart)
+	r := &model.WeeklyReport{WeekStart: start, WeekEnd: start.Add(week)}
+
+	err := db.Pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM tasks WHERE created_at >= $1 AND created_at < $2 AND duplicate_of IS NULL`,
+		r.WeekStart, r.WeekEnd,
+	).Scan(&r.Created)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count created tasks: %w", err)
+	}
+	moved, err := loadMoved(ctx, r.WeekStart, r.WeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	open, err := loadOpen(ctx, r.WeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	Classify(r, moved, open)
+
+	callCtx, cancel := context.WithTimeout(ctx, callTimeout)
+	defer cancel()
+	s, err := summarizer.Summarize(callCtx, llm.SummaryRequest{
+		Kind:    llm.SummaryReport,
+		Subject: fmt.Sprintf("Week from %s to %s", r.WeekStart.Format("2006-01-02"), r.WeekEnd.AddDate(0, 0, -1).Format("2006-01-02")),
+		Facts:   ReportFacts(r),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSummarize, err)
+	}
+	r.Summary, r.Provider, r.Model = s.Text, optional(s.Provider), optional(s.Model)
+
+	movedJSON, _ := json.Marshal(r.Moved)
+	blockedJSON, _ := json.Marshal(r.Blocked)
+	overdueJSON, _ := json.Marshal(r.Overdue)
+	err = db.Pool.QueryRow(ctx,
+		`INSERT INTO weekly_reports (week_start, summary, created_count, completed_count, moved, blocked, overdue, provider, model)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 ON CONFLICT (week_start) DO UPDATE
+		 SET summary = EXCLUDED.summary, created_count = EXCLUDED.created_count, completed_count = EXCLUDED.completed_count,
+		     moved = EXCLUDED.moved, blocked = EXCLUDED.blocked, overdue = EXCLUDED.overdue,
+		     provider = EXCLUDED.provider, model = EXCLUDED.model, generated_at = NOW()
+		 RETURNING id, generated_at`,
+		r.WeekStart, r.Summary, r.Created, r.Completed, movedJSON, blockedJSON, overdueJSON, r.Provider, r.Model,
+	).Scan(&r.ID, &r.GeneratedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store weekly report: %w", err)
+	}
+	return r, nil
+}
+
+// Classify fills the report's lists from the tasks whose status changed
+// during the week, with their Note set to the change, and the tasks open at
+// its end. Tasks back in the status they started the week in didn't move.
+func Classify(r *model.WeeklyReport, moved []model.ReportTask, open []OpenTask) {
+	r.Moved, r.Blocked, r.Overdue = []model.ReportTask{}, []model.ReportTask{}, []model.ReportTask{}
+	r.Completed = 0
+	for _, t := range moved {
+		if t.Note == "" {
+			continue
+		}
+		if t.Status == "done" {
+			r.Completed++
+		}
+		r.Moved = append(r.Moved, t)
+	}
+
+	for _, t := range open {
+		switch {
+		case t.Escalation != nil:
+			b := t.ReportTask
+			b.Note = fmt.Sprintf("escalated under %q", *t.Escalation)
+			r.Blocked = append(r.Blocked, b)
+		case (t.Status == "in_progress" || t.Status == "review") && r.WeekEnd.Sub(t.StatusSince) >= BlockedAfter:
+			b := t.ReportTask
+			b.Note = fmt.Sprintf("%s for %s", statusLabel(t.Status), days(r.WeekEnd.Sub(t.StatusSince)))
+			r.Blocked = append(r.Blocked, b)
+		}
+		if t.DueDate != nil && t.DueDate.Before(r.WeekEnd) {
+			o := t.ReportTask
+			o.Note = days(r.WeekEnd.Sub(*t.DueDate)) + " overdue"
+			r.Overdue = append(r.Overdue, o)
+		}
+	}
+	// Longest overdue first.
+	sort.SliceStable(r.Overdue, func(i, j int) bool { return r.Overdue[i].DueDate.Before(*r.Overdue[j].DueDate) })
+
+	r.Moved, r.Blocked, r.Overdue = capTasks(r.Moved), capTasks(r.Blocked), capTasks(r.Overdue)
+}
+
+// ReportFacts turns a report's counts and lists into the facts the LLM
+// summarizes.
+func ReportFacts(r *model.WeeklyReport) []string {
+	facts := []string{fmt.Sprintf("%d tasks were created and %d completed.", r.Created, r.Completed)}
+	for _, section := range []struct {
+		name  string
+		tasks []model.ReportTask
+	}{{"Moved", r.Moved}, {"Blocked", r.Blocked}, {"Overdue", r.Overdue}} {
+		if len(section.tasks) == 0 {
+			facts = append(facts, section.name+": none.")
+		}
+		for _, t := range section.tasks {
+			assignee := "unassigned"
+			if t.Assignee != nil {
+				assignee = *t.Assignee
+			}
+			facts = append(facts, fmt.Sprintf("%s: %q (%s, %s): %s.", section.name, t.Title, assignee, t.Status, t.Note))
+		}
+	}
+	return facts
+}
+
+func statusLabel(status string) string {
+	if status == "in_progress" {
+		return "in progress"
+	}
+	return "in " + status
+}
+
+func days(d time.Duration) string {
+	n := int(math.Floor(d.Hours() / 24))
+	if n == 1 {
+		return "1 day"
+	}
+	if n < 1 {
+		return "less than a day"
+	}
+	return fmt.Sprintf("%d days", n)
+}
+
+func capTasks(tasks []model.ReportTask) []model.ReportTask {
+	if len(tasks) > MaxReportTasks {
+		return tasks[:MaxReportTasks]
+	}
+	return tasks
+}
+
+// ListWeekly returns the latest reports, newest first.
+func ListWeekly(ctx context.Context, limit int) ([]model.WeeklyReport, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT `+reportColumns+` FROM weekly_reports ORDER BY week_start DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query weekly reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []model.WeeklyReport{}
+	for rows.Next() {
+		r, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, r)
+	}
+	return reports, rows.Err()
+}
+
+// GetWeekly returns the report of the week t falls in.
+func GetWeekly(ctx context.Context, t time.Time) (*model.WeeklyReport, error) {
+	r, err := scanReport(db.Pool.QueryRow(ctx, `SELECT `+reportColumns+` FROM weekly_reports WHERE week_start = $1`, WeekStart(t)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrReportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+const reportColumns = `id, week_start, summary, created_count, completed_count, moved, blocked, overdue, provider, model, generated_at`
+
+func scanReport(row pgx.Row) (model.WeeklyReport, error) {
+	var r model.WeeklyReport
+	var moved, blocked, overdue []byte
+	if err := row.Scan(&r.ID, &r.WeekStart, &r.Summary, &r.Created, &r.Completed, &moved, &blocked, &overdue,
+		&r.Provider, &r.Model, &r.GeneratedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return r, err
+		}
+		return r, fmt.Errorf("failed to scan weekly report: %w", err)
+	}
+	r.WeekStart = r.WeekStart.UTC()
+	r.WeekEnd = r.WeekStart.Add(week)
+	for _, f := range []struct {
+		raw []byte
+		out *[]model.ReportTask
+	}{{moved, &r.Moved}, {blocked, &r.Blocked}, {overdue, &r.Overdue}} {
+		if err := json.Unmarshal(f.raw, f.out); err != nil {
+			return r, fmt.Errorf("failed to decode weekly report: %w", err)
+		}
+	}
+	return r, nil
+}
+
+// loadMoved returns the tasks whose status changed in [start, end), most
+// recently changed first, with Note set to their first and last status of
+// the week, or empty when they ended the week where they started it.
+func loadMoved(ctx context.Context, start, end time.Time) ([]model.ReportTask, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT t.id, t.title, t.status, u.name, t.due_date,
+		        (array_agg(h.old_value ORDER BY h.edited_at))[1], (array_agg(h.new_value ORDER BY h.edited_at DESC))[1]
+		 FROM edit_history h
+		 JOIN tasks t ON t.id = h.task_id
+		 LEFT JOIN users u ON u.id = t.assignee_id
+		 WHERE h.field_name = 'status' AND h.edited_at >= $1 AND h.edited_at < $2 AND t.duplicate_of IS NULL
+		 GROUP BY t.id, u.name
+		 ORDER BY MAX(h.edited_at) DESC`, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query moved tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []model.ReportTask
+	for rows.Next() {
+		var t model.ReportTask
+		var from, to *string
+		if err := rows.Scan(&t.TaskID, &t.Title, &t.Status, &t.Assignee, &t.DueDate, &from, &to); err != nil {
+			return nil, fmt.Errorf("failed to scan moved task: %w", err)
+		}
+		if from != nil && to != nil && *from != *to {
+			t.Note = *from + " → " + *to
+			// The status at the end of the week, which may have changed since.
+			t.Status = *to
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+// loadOpen returns the tasks created before end and not done, with when
+// their status last changed before end and an escalation open at end.
+func loadOpen(ctx context.Context, end time.Time) ([]OpenTask, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT t.id, t.title, t.status, u.name, t.due_date,
+		        COALESCE((SELECT MAX(h.edited_at) FROM edit_history h
+		                  WHERE h.task_id = t.id AND h.field_name = 'status' AND h.edited_at < $1), t.created_at),
+		        (SELECT r.name FROM escalation_log l JOIN escalation_rules r ON r.id = l.rule_id
+		         WHERE l.task_id = t.id AND l.triggered_at < $1 AND (l.resolved_at IS NULL OR l.resolved_at >= $1)
+		         ORDER BY l.triggered_at LIMIT 1)
+		 FROM tasks t
+		 LEFT JOIN users u ON u.id = t.assignee_id
+		 WHERE t.status <> 'done' AND t.duplicate_of IS NULL AND t.created_at < $1
+		 ORDER BY t.due_date NULLS LAST, t.created_at`, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query open tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []OpenTask
+	for rows.Next() {
+		var t OpenTask
+		if err := rows.Scan(&t.TaskID, &t.Title, &t.Status, &t.Assignee, &t.DueDate, &t.StatusSince, &t.Escalation); err != nil {
+			return nil, fmt.Errorf("failed to scan open task: %w", err)
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
@@ -0,0 +1,351 @@
+// Package summary has the LLM narrate task histories and write weekly
+// project reports from edit history, AI suggestions and escalations.
+package summary
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/llm"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+// Event kinds.
+const (
+	EventCreated      = "created"
+	EventStatus       = "status"
+	EventAssignee     = "assignee"
+	EventTime         = "time"
+	EventAISuggestion = "ai_suggestion"
+	EventEscalation   = "escalation"
+	EventChange       = "change"
+)
+
+const (
+	// MaxFacts caps the facts sent for a task: the creation and the most
+	// recent events are kept.
+	MaxFacts = 150
+	// maxValueLen shortens long field values, e.g. descriptions.
+	maxValueLen = 80
+	// callTimeout bounds a summary, including retries.
+	callTimeout = 60 * time.Second
+)
+
+var (
+	// ErrTaskNotFound is returned when the task to summarize does not exist.
+	ErrTaskNotFound = errors.New("task not found")
+	// ErrSummarize wraps errors from the LLM summarizing.
+	ErrSummarize = errors.New("llm summary failed")
+)
+
+// TaskState is where a task stands now.
+type TaskState struct {
+	Title          string
+	Status         string
+	Priority       string
+	Assignee       *string // name
+	DueDate        *time.Time
+	EstimatedHours *float64
+	ActualHours    *float64
+	Merged         bool // merged into another task
+}
+
+// TaskHistory summarizes a task's lifecycle. The summary is stored and
+// served again until the task's history changes, unless refresh is set.
+func TaskHistory(ctx context.Context, summarizer llm.Summarizer, taskID, userID string, refresh bool) (*model.TaskHistorySummary, error) {
+	state, events, err := loadTaskHistory(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	facts := TaskFacts(state, events)
+	hash := factsHash(facts)
+	out := &model.TaskHistorySummary{TaskID: taskID, Events: events}
+
+	if !refresh {
+		err := db.Pool.QueryRow(ctx,
+			`SELECT summary, provider, model, generated_at FROM task_history_summaries
+			 WHERE task_id = $1 AND events_hash = $2`, taskID, hash,
+		).Scan(&out.Summary, &out.Provider, &out.Model, &out.GeneratedAt)
+		if err == nil {
+			out.Cached = true
+			return out, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("failed to get task summary: %w", err)
+		}
+	}
+
+	if summarizer == nil {
+		return nil, llm.ErrUnsupported
+	}
+	callCtx, cancel := context.WithTimeout(llm.WithCaller(ctx, taskID, userID), callTimeout)
+	defer cancel()
+	s, err := summarizer.Summarize(callCtx, llm.SummaryRequest{Kind: llm.SummaryTask, Subject: "Task: " + state.Title, Facts: facts})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSummarize, err)
+	}
+
+	out.Summary, out.Provider, out.Model = s.Text, optional(s.Provider), optional(s.Model)
+	err = db.Pool.QueryRow(ctx,
+		`INSERT INTO task_history_summaries (task_id, summary, events_hash, provider, model)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (task_id) DO UPDATE
+		 SET summary = EXCLUDED.summary, events_hash = EXCLUDED.events_hash, provider = EXCLUDED.provider,
+		     model = EXCLUDED.model, generated_at = NOW()
+		 RETURNING generated_at`,
+		taskID, out.Summary, hash, out.Provider, out.Model,
+	).Scan(&out.GeneratedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store task summary: %w", err)
+	}
+	return out, nil
+}
+
+// TaskFacts turns a task's events, oldest first, into the facts the LLM
+// summarizes, ending with where the task stands now. Beyond MaxFacts, the
+// oldest events after the creation are left out.
+func TaskFacts(state TaskState, events []model.TaskEvent) []string {
+	var facts []string
+	// Room is left for the creation, the note on what's left out and the
+	// current state.
+	if n := len(events) - 1 - (MaxFacts - 3); n > 0 {
+		facts = append(facts, eventFact(events[0]), fmt.Sprintf("(%d earlier events left out)", n))
+		events = events[1+n:]
+	}
+	for _, e := range events {
+		facts = append(facts, eventFact(e))
+	}
+	return append(facts, stateFact(state))
+}
+
+func eventFact(e model.TaskEvent) string {
+	actor := "System"
+	if e.Actor != nil {
+		actor = *e.Actor
+	}
+	return fmt.Sprintf("%s, %s %s.", e.At.UTC().Format("2006-01-02 15:04 UTC"), actor, e.Text)
+}
+
+func stateFact(s TaskState) string {
+	parts := []string{"status " + s.Status, "priority " + s.Priority}
+	if s.Assignee != nil {
+		parts = append(parts, "assigned to "+*s.Assignee)
+	} else {
+		parts = append(parts, "unassigned")
+	}
+	if s.DueDate != nil {
+		parts = append(parts, "due "+s.DueDate.UTC().Format("2006-01-02"))
+	}
+	if s.EstimatedHours != nil {
+		parts = append(parts, "estimated "+hours(*s.EstimatedHours))
+	}
+	if s.ActualHours != nil {
+		parts = append(parts, hours(*s.ActualHours)+" logged")
+	}
+	if s.Merged {
+		parts = append(parts, "merged into another task as a duplicate")
+	}
+	return "Now: " + strings.Join(parts, ", ") + "."
+}
+
+// DescribeChange turns an edit_history row into an event. For assignee_id,
+// old and new are user names when the users still exist.
+func DescribeChange(field string, old, new *string) (kind, text string) {
+	switch field {
+	case "status":
+		return EventStatus, fmt.Sprintf("moved the task from %s to %s", value(old), value(new))
+	case "assignee_id":
+		switch {
+		case new == nil || *new == "":
+			return EventAssignee, fmt.Sprintf("unassigned the task from %s", value(old))
+		case old == nil || *old == "":
+			return EventAssignee, "assigned the task to " + *new
+		default:
+			return EventAssignee, fmt.Sprintf("reassigned the task from %s to %s", *old, *new)
+		}
+	case "actual_hours":
+		if old == nil {
+			return EventTime, "logged " + hoursValue(new)
+		}
+		return EventTime, fmt.Sprintf("changed the time logged from %s to %s", hoursValue(old), hoursValue(new))
+	case "estimated_hours":
+		if old == nil {
+			return EventChange, "estimated " + hoursValue(new)
+		}
+		return EventChange, fmt.Sprintf("changed the estimate from %s to %s", hoursValue(old), hoursValue(new))
+	case "duplicate_of":
+		return EventChange, "merged the task into another task as a duplicate"
+	default:
+		return EventChange, fmt.Sprintf("changed %s from %s to %s", strings.ReplaceAll(field, "_", " "), value(old), value(new))
+	}
+}
+
+func value(s *string) string {
+	if s == nil || *s == "" {
+		return "nothing"
+	}
+	if r := []rune(*s); len(r) > maxValueLen {
+		return strconv.Quote(string(r[:maxValueLen]) + "…")
+	}
+	if strings.ContainsAny(*s, " \n") {
+		return strconv.Quote(*s)
+	}
+	return *s
+}
+
+func hoursValue(s *string) string {
+	if s == nil {
+		return "no hours"
+	}
+	h, err := strconv.ParseFloat(*s, 64)
+	if err != nil {
+		return *s
+	}
+	return hours(h)
+}
+
+func hours(h float64) string {
+	return strconv.FormatFloat(h, 'f', -1, 64) + " h"
+}
+
+func factsHash(facts []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(facts, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// loadTaskHistory returns the task's state and its events, oldest first:
+// its creation, edits, AI suggestions and escalations.
+func loadTaskHistory(ctx context.Context, taskID string) (TaskState, []model.TaskEvent, error) {
+	var s TaskState
+	var createdAt time.Time
+	var creator *string
+	err := db.Pool.QueryRow(ctx,
+		`SELECT t.title, t.status, t.priority, a.name, t.due_date, t.estimated_hours::float8, t.actual_hours::float8,
+		        t.duplicate_of IS NOT NULL, t.created_at, c.name
+		 FROM tasks t
+		 LEFT JOIN users a ON a.id = t.assignee_id
+		 LEFT JOIN users c ON c.id = t.creator_id
+		 WHERE t.id = $1`, taskID,
+	).Scan(&s.Title, &s.Status, &s.Priority, &s.Assignee, &s.DueDate, &s.EstimatedHours, &s.ActualHours,
+		&s.Merged, &createdAt, &creator)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s, nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return s, nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	events := []model.TaskEvent{{At: createdAt, Kind: EventCreated, Actor: creator, Text: "created the task"}}
+
+	rows, err := db.Pool.Query(ctx,
+		`SELECT h.edited_at, u.name, h.field_name, COALESCE(ou.name, h.old_value), COALESCE(nu.name, h.new_value)
+		 FROM edit_history h
+		 LEFT JOIN users u ON u.id = h.user_id
+		 LEFT JOIN users ou ON h.field_name = 'assignee_id' AND ou.id::text = h.old_value
+		 LEFT JOIN users nu ON h.field_name = 'assignee_id' AND nu.id::text = h.new_value
+		 WHERE h.task_id = $1`, taskID)
+	if err != nil {
+		return s, nil, fmt.Errorf("failed to query task history: %w", err)
+	}
+	for rows.Next() {
+		var e model.TaskEvent
+		var field string
+		var old, new *string
+		if err := rows.Scan(&e.At, &e.Actor, &field, &old, &new); err != nil {
+			rows.Close()
+			return s, nil, fmt.Errorf("failed to scan task history: %w", err)
+		}
+		e.Kind, e.Text = DescribeChange(field, old, new)
+		events = append(events, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return s, nil, err
+	}
+
+	rows, err = db.Pool.Query(ctx,
+		`SELECT s.created_at, r.name, s.priority, s.category, s.tags, s.status, s.decided_at, d.name
+		 FROM ai_suggestions s
+		 LEFT JOIN users r ON r.id = s.requested_by
+		 LEFT JOIN users d ON d.id = s.decided_by
+		 WHERE s.task_id = $1`, taskID)
+	if err != nil {
+		return s, nil, fmt.Errorf("failed to query task suggestions: %w", err)
+	}
+	for rows.Next() {
+		var e model.TaskEvent
+		var priority, category, status string
+		var tags []string
+		var decidedAt *time.Time
+		var decider *string
+		if err := rows.Scan(&e.At, &e.Actor, &priority, &category, &tags, &status, &decidedAt, &decider); err != nil {
+			rows.Close()
+			return s, nil, fmt.Errorf("failed to scan task suggestion: %w", err)
+		}
+		e.Kind = EventAISuggestion
+		e.Text = fmt.Sprintf("had the AI classify the task, which suggested priority %s and category %s", priority, category)
+		if len(tags) > 0 {
+			e.Text += " with tags " + strings.Join(tags, ", ")
+		}
+		events = append(events, e)
+		if decidedAt != nil && status != "superseded" {
+			events = append(events, model.TaskEvent{At: *decidedAt, Kind: EventAISuggestion, Actor: decider,
+				Text: strings.ReplaceAll(status, "_", " ") + " the AI suggestion"})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return s, nil, err
+	}
+
+	rows, err = db.Pool.Query(ctx,
+		`SELECT l.triggered_at, r.name, l.acknowledged_at, a.name, l.resolved_at
+		 FROM escalation_log l
+		 JOIN escalation_rules r ON r.id = l.rule_id
+		 LEFT JOIN users a ON a.id = l.acknowledged_by
+		 WHERE l.task_id = $1`, taskID)
+	if err != nil {
+		return s, nil, fmt.Errorf("failed to query task escalations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var triggeredAt time.Time
+		var rule string
+		var acknowledgedAt, resolvedAt *time.Time
+		var acknowledger *string
+		if err := rows.Scan(&triggeredAt, &rule, &acknowledgedAt, &acknowledger, &resolvedAt); err != nil {
+			return s, nil, fmt.Errorf("failed to scan task escalation: %w", err)
+		}
+		events = append(events, model.TaskEvent{At: triggeredAt, Kind: EventEscalation, Text: fmt.Sprintf("escalated the task under the rule %q", rule)})
+		if acknowledgedAt != nil {
+			events = append(events, model.TaskEvent{At: *acknowledgedAt, Kind: EventEscalation, Actor: acknowledger,
+				Text: fmt.Sprintf("acknowledged the %q escalation", rule)})
+		}
+		if resolvedAt != nil {
+			events = append(events, model.TaskEvent{At: *resolvedAt, Kind: EventEscalation,
+				Text: fmt.Sprintf("resolved the %q escalation", rule)})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return s, nil, err
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At.Before(events[j].At) })
+	return s, events, nil
+}
@@ -0,0 +1,194 @@
+// Package taskparse turns free text into a task draft the user confirms
+// before it is created.
+package taskparse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/llm"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+// MaxTextLen caps the text a draft is parsed from.
+const MaxTextLen = 2000
+
+var (
+	// ErrInvalidText is returned for empty or overly long text.
+	ErrInvalidText = errors.New("invalid task text")
+	// ErrParse wraps errors from the LLM parsing the text.
+	ErrParse = errors.New("llm task parsing failed")
+)
+
+// callTimeout bounds parsing, including the retries made by the client.
+const callTimeout = 30 * time.Second
+
+// Preview parses text on behalf of userID into a draft: the assignee is
+// resolved against users and the due date in userID's timezone. Nothing is
+// stored.
+func Preview(ctx context.Context, parser llm.TaskParser, userID, text string) (*model.TaskDraft, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("%w: text is required", ErrInvalidText)
+	}
+	if utf8.RuneCountInString(text) > MaxTextLen {
+		return nil, fmt.Errorf("%w: text must be at most %d characters", ErrInvalidText, MaxTextLen)
+	}
+
+	var timezone string
+	if err := db.Pool.QueryRow(ctx, `SELECT timezone FROM users WHERE id = $1`, userID).Scan(&timezone); err != nil {
+		return nil, fmt.Errorf("failed to get user timezone: %w", err)
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	now := time.Now().In(loc)
+
+	callCtx, cancel := context.WithTimeout(llm.WithCaller(ctx, "", userID), callTimeout)
+	defer cancel()
+	p, err := parser.ParseTask(callCtx, text, llm.ParseOptions{Now: now})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrParse, err)
+	}
+
+	users, err := listUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	d := Draft(p, users, now)
+	d.Text = text
+	return d, nil
+}
+
+func listUsers(ctx context.Context) ([]model.User, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT id, email, name, role, avatar_url, created_at, updated_at FROM users ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+	var users []model.User
+	for rows.Next() {
+		var u model.User
+		if err := rows.Scan(&u.ID, &u.Email, &u.Name, &u.Role, &u.AvatarURL, &u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// Draft resolves a parsed task into a create request. now is the current
+// time in the user's location. Whatever can't be resolved is left out of the
+// request and explained in Warnings.
+func Draft(p *llm.ParsedTask, users []model.User, now time.Time) *model.TaskDraft {
+	d := &model.TaskDraft{Timezone: now.Location().String(), Warnings: []string{}, Provider: p.Provider, Model: p.Model}
+	req := model.CreateTaskRequest{Title: p.Title, Status: "todo", Priority: p.Priority}
+	if p.Description != "" {
+		desc := p.Description
+		req.Description = &desc
+	}
+	if req.Priority == "" {
+		req.Priority = "medium"
+	}
+
+	if p.Assignee != "" {
+		u, candidates := MatchUser(users, p.Assignee)
+		switch {
+		case u != nil:
+			req.AssigneeID, d.Assignee = &u.ID, u
+		case len(candidates) > 0:
+			d.AssigneeCandidates = candidates
+			names := make([]string, len(candidates))
+			for i, c := range candidates {
+				names[i] = c.Name
+			}
+			d.Warnings = append(d.Warnings, fmt.Sprintf("%q matches several users (%s); pick one", p.Assignee, strings.Join(names, ", ")))
+		default:
+			d.Warnings = append(d.Warnings, fmt.Sprintf("no user matches %q; the task is unassigned", p.Assignee))
+		}
+	}
+
+	if p.DueDate != "" {
+		due, err := ResolveDueDate(p.DueDate, now.Location())
+		switch {
+		case err != nil:
+			d.Warnings = append(d.Warnings, fmt.Sprintf("could not understand the due date %q", p.DueDate))
+		default:
+			if due.Before(now) {
+				d.Warnings = append(d.Warnings, "the due date is in the past")
+			}
+			s := due.Format(time.RFC3339)
+			req.DueDate = &s
+		}
+	}
+
+	d.Request = req
+	return d
+}
+
+// ResolveDueDate reads a model's due date in loc. A date without a time means
+// the end of that day.
+func ResolveDueDate(value string, loc *time.Location) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02T15:04:05"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 23, 59, 0, 0, loc), nil
+}
+
+var accents = strings.NewReplacer(
+	"á", "a", "à", "a", "ä", "a", "â", "a", "ã", "a",
+	"é", "e", "è", "e", "ë", "e", "ê", "e",
+	"í", "i", "ì", "i", "ï", "i", "î", "i",
+	"ó", "o", "ò", "o", "ö", "o", "ô", "o", "õ", "o",
+	"ú", "u", "ù", "u", "ü", "u", "û", "u",
+	"ñ", "n", "ç", "c",
+)
+
+// fold lowercases s and strips common accents, so "Lucia" finds "Lucía".
+func fold(s string) string {
+	return accents.Replace(strings.ToLower(strings.TrimSpace(s)))
+}
+
+// MatchUser finds the user a mention refers to: an email, a full name, or
+// else a first name or email local part. It returns the user when exactly
+// one matches, otherwise the candidates.
+func MatchUser(users []model.User, mention string) (*model.User, []model.User) {
+	m := fold(strings.TrimPrefix(mention, "@"))
+	if m == "" {
+		return nil, nil
+	}
+	var exact, partial []model.User
+	for _, u := range users {
+		name, email := fold(u.Name), fold(u.Email)
+		local, _, _ := strings.Cut(email, "@")
+		first, _, _ := strings.Cut(name, " ")
+		switch {
+		case m == email || m == name:
+			exact = append(exact, u)
+		case m == first || m == local:
+			partial = append(partial, u)
+		}
+	}
+	for _, matches := range [][]model.User{exact, partial} {
+		if len(matches) == 1 {
+			return &matches[0], nil
+		}
+		if len(matches) > 1 {
+			return nil, matches
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,61 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/llm"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+// RedactionAuditor stores what was redacted from LLM requests in
+// llm_redactions under one workspace.
+type RedactionAuditor struct {
+	Workspace string
+}
+
+var _ llm.RedactionAuditor = RedactionAuditor{}
+
+// RecordRedaction stores one row per redacted kind. Like Record it runs even
+// if the caller's context ended.
+func (a RedactionAuditor) RecordRedaction(ctx context.Context, rec llm.RedactionRecord) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	for kind, count := range rec.Counts {
+		_, err := db.Pool.Exec(ctx,
+			`INSERT INTO llm_redactions (workspace, operation, kind, count, task_id, user_id)
+			 VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, NULLIF($6, '')::uuid)`,
+			a.Workspace, rec.Operation, kind, count, rec.TaskID, rec.UserID)
+		if err != nil {
+			log.Printf("error recording llm redaction: %v", err)
+			return
+		}
+	}
+}
+
+// RedactionReport aggregates what was redacted in [from, to) by kind.
+func RedactionReport(ctx context.Context, workspace string, from, to time.Time) (*model.LLMRedactionReport, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT kind, COUNT(*), SUM(count), COUNT(DISTINCT task_id)
+		 FROM llm_redactions
+		 WHERE workspace = $1 AND created_at >= $2 AND created_at < $3
+		 GROUP BY kind ORDER BY kind`, workspace, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query llm redactions: %w", err)
+	}
+	defer rows.Close()
+
+	report := &model.LLMRedactionReport{Workspace: workspace, From: from, To: to, Rows: []model.LLMRedactionRow{}}
+	for rows.Next() {
+		var r model.LLMRedactionRow
+		if err := rows.Scan(&r.Kind, &r.Requests, &r.Values, &r.Tasks); err != nil {
+			return nil, fmt.Errorf("failed to scan llm redactions: %w", err)
+		}
+		report.Rows = append(report.Rows, r)
+	}
+	return report, rows.Err()
+}
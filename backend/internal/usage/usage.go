@@ -0,0 +1,200 @@
+// Package usage records LLM calls, aggregates them for reporting and
+// enforces monthly per-workspace token budgets.
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/llm"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+// Group-by dimensions of a usage report.
+const (
+	GroupByDay   = "day"
+	GroupByUser  = "user"
+	GroupByModel = "model"
+)
+
+// ErrInvalidGroupBy is returned for an unknown report dimension.
+var ErrInvalidGroupBy = errors.New("group_by must be day, user or model")
+
+var groupKeys = map[string]string{
+	GroupByDay:   `to_char(u.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD')`,
+	GroupByUser:  `COALESCE(us.email, 'system')`,
+	GroupByModel: `u.provider || ':' || u.model`,
+}
+
+// Workspace returns the workspace this deployment records usage under,
+// LLM_WORKSPACE (default "default").
+func Workspace() string {
+	if w := os.Getenv("LLM_WORKSPACE"); w != "" {
+		return w
+	}
+	return "default"
+}
+
+// periodStart returns the start of the calendar month (UTC) containing t.
+func periodStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Recorder stores LLM calls in llm_usage under one workspace.
+type Recorder struct {
+	Workspace string
+}
+
+var _ llm.UsageRecorder = Recorder{}
+
+// Allow returns llm.ErrBudgetExceeded once the workspace has used its
+// monthly token limit. Workspaces without a budget are unlimited.
+func (rec Recorder) Allow(ctx context.Context) error {
+	b, err := GetBudget(ctx, rec.Workspace)
+	if err != nil {
+		// Don't take classification down with the accounting database.
+		log.Printf("error checking llm budget of %s: %v", rec.Workspace, err)
+		return nil
+	}
+	if b.MonthlyTokenLimit != nil && b.UsedTokens >= *b.MonthlyTokenLimit {
+		return fmt.Errorf("%w: %d of %d tokens used this month", llm.ErrBudgetExceeded, b.UsedTokens, *b.MonthlyTokenLimit)
+	}
+	return nil
+}
+
+// Record stores a call. It runs even if the caller's context ended, since
+// the tokens were spent either way.
+func (rec Recorder) Record(ctx context.Context, c llm.CallRecord) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	var errText *string
+	if c.Err != nil {
+		s := c.Err.Error()
+		errText = &s
+	}
+	_, err := db.Pool.Exec(ctx,
+		`INSERT INTO llm_usage (workspace, provider, model, operation, input_tokens, output_tokens,
+		                        latency_ms, success, error, task_id, user_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, '')::uuid, NULLIF($11, '')::uuid)`,
+		rec.Workspace, c.Provider, c.Model, c.Operation, c.InputTokens, c.OutputTokens,
+		c.Latency.Milliseconds(), c.Err == nil, errText, c.TaskID, c.UserID)
+	if err != nil {
+		log.Printf("error recording llm usage: %v", err)
+	}
+}
+
+// Report aggregates the workspace's calls in [from, to) by groupBy.
+func Report(ctx context.Context, workspace string, from, to time.Time, groupBy string) (*model.LLMUsageReport, error) {
+	key, ok := groupKeys[groupBy]
+	if !ok {
+		return nil, ErrInvalidGroupBy
+	}
+
+	rows, err := db.Pool.Query(ctx,
+		`SELECT `+key+`, COUNT(*), COUNT(*) FILTER (WHERE NOT u.success),
+		        COALESCE(SUM(u.input_tokens), 0), COALESCE(SUM(u.output_tokens), 0),
+		        COALESCE(AVG(u.latency_ms), 0),
+		        COALESCE(SUM(u.input_tokens * p.input_per_million + u.output_tokens * p.output_per_million) / 1000000, 0)
+		 FROM llm_usage u
+		 LEFT JOIN users us ON us.id = u.user_id
+		 LEFT JOIN llm_model_prices p ON p.provider = u.provider AND p.model = u.model
+		 WHERE u.workspace = $1 AND u.created_at >= $2 AND u.created_at < $3
+		 GROUP BY 1 ORDER BY 1`, workspace, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query llm usage: %w", err)
+	}
+	defer rows.Close()
+
+	report := &model.LLMUsageReport{Workspace: workspace, From: from, To: to, GroupBy: groupBy, Rows: []model.LLMUsageRow{}}
+	var latencyTotal float64
+	for rows.Next() {
+		var r model.LLMUsageRow
+		if err := rows.Scan(&r.Key, &r.Calls, &r.Failures, &r.InputTokens, &r.OutputTokens,
+			&r.AvgLatencyMs, &r.EstimatedCostUSD); err != nil {
+			return nil, fmt.Errorf("failed to scan llm usage: %w", err)
+		}
+		report.Rows = append(report.Rows, r)
+
+		t := &report.Total
+		t.Calls += r.Calls
+		t.Failures += r.Failures
+		t.InputTokens += r.InputTokens
+		t.OutputTokens += r.OutputTokens
+		t.EstimatedCostUSD += r.EstimatedCostUSD
+		latencyTotal += r.AvgLatencyMs * float64(r.Calls)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	report.Total.Key = "total"
+	if report.Total.Calls > 0 {
+		report.Total.AvgLatencyMs = latencyTotal / float64(report.Total.Calls)
+	}
+	return report, nil
+}
+
+// GetBudget returns the workspace's budget and the tokens used this month.
+func GetBudget(ctx context.Context, workspace string) (*model.LLMBudget, error) {
+	b := model.LLMBudget{Workspace: workspace, PeriodStart: periodStart(time.Now())}
+	err := db.Pool.QueryRow(ctx,
+		`SELECT (SELECT monthly_token_limit FROM llm_budgets WHERE workspace = $1),
+		        COALESCE(SUM(input_tokens + output_tokens), 0)
+		 FROM llm_usage WHERE workspace = $1 AND created_at >= $2`,
+		workspace, b.PeriodStart,
+	).Scan(&b.MonthlyTokenLimit, &b.UsedTokens)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get llm budget: %w", err)
+	}
+	return &b, nil
+}
+
+// ListBudgets returns every workspace that has a budget or usage this month.
+func ListBudgets(ctx context.Context) ([]model.LLMBudget, error) {
+	start := periodStart(time.Now())
+	rows, err := db.Pool.Query(ctx,
+		`SELECT w.workspace, b.monthly_token_limit,
+		        COALESCE((SELECT SUM(input_tokens + output_tokens) FROM llm_usage u
+		                  WHERE u.workspace = w.workspace AND u.created_at >= $1), 0)
+		 FROM (SELECT workspace FROM llm_budgets
+		       UNION SELECT DISTINCT workspace FROM llm_usage WHERE created_at >= $1) w
+		 LEFT JOIN llm_budgets b ON b.workspace = w.workspace
+		 ORDER BY w.workspace`, start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list llm budgets: %w", err)
+	}
+	defer rows.Close()
+
+	budgets := []model.LLMBudget{}
+	for rows.Next() {
+		b := model.LLMBudget{PeriodStart: start}
+		if err := rows.Scan(&b.Workspace, &b.MonthlyTokenLimit, &b.UsedTokens); err != nil {
+			return nil, fmt.Errorf("failed to scan llm budget: %w", err)
+		}
+		budgets = append(budgets, b)
+	}
+	return budgets, rows.Err()
+}
+
+// SetBudget sets the workspace's monthly token limit; nil removes it.
+func SetBudget(ctx context.Context, workspace string, limit *int64) (*model.LLMBudget, error) {
+	var err error
+	if limit == nil {
+		_, err = db.Pool.Exec(ctx, `DELETE FROM llm_budgets WHERE workspace = $1`, workspace)
+	} else {
+		_, err = db.Pool.Exec(ctx,
+			`INSERT INTO llm_budgets (workspace, monthly_token_limit) VALUES ($1, $2)
+			 ON CONFLICT (workspace) DO UPDATE SET monthly_token_limit = EXCLUDED.monthly_token_limit, updated_at = NOW()`,
+			workspace, *limit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to set llm budget: %w", err)
+	}
+	return GetBudget(ctx, workspace)
+}
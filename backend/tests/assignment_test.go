@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KemenyStudio/task-manager/internal/assignment"
+	"github.com/KemenyStudio/task-manager/internal/estimation"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+var loginBug = estimation.Sample{Title: "Fix login redirect after OAuth callback", Category: "bug", Tags: []string{"security"}}
+
+func assignmentCandidates() []assignment.Candidate {
+	return []assignment.Candidate{
+		{
+			User: model.User{ID: "u1", Name: "Ana"},
+			Completed: []estimation.Sample{
+				{Title: "Fix login redirect loop", Category: "bug", Tags: []string{"security"}},
+				{Title: "Fix login error message", Category: "bug", Tags: []string{"security"}},
+			},
+			OpenTasks: 2, OpenHours: 10,
+		},
+		{
+			User:      model.User{ID: "u2", Name: "Bruno"},
+			Completed: []estimation.Sample{{Title: "Migrate styles to Tailwind", Category: "improvement", Tags: []string{"frontend"}}},
+		},
+		{
+			User:      model.User{ID: "u3", Name: "Carla"},
+			Completed: []estimation.Sample{{Title: "Fix login redirect loop", Category: "bug", Tags: []string{"security"}}},
+			OpenTasks: 6, OpenHours: 60, Unestimated: 2, DueSoon: 2, Overdue: 1,
+		},
+	}
+}
+
+func TestRankPrefersExpertiseAndAvailability(t *testing.T) {
+	got := assignment.Rank(assignment.Target{Task: loginBug}, assignmentCandidates())
+	if len(got) != 3 {
+		t.Fatalf("got %d suggestions, want 3", len(got))
+	}
+	if got[0].User.ID != "u1" || got[2].User.ID != "u3" {
+		t.Errorf("order = %s, %s, %s; want u1 first and u3 last", got[0].User.ID, got[1].User.ID, got[2].User.ID)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i].Score > got[i-1].Score {
+			t.Errorf("not sorted by score: %v after %v", got[i].Score, got[i-1].Score)
+		}
+	}
+
+	ana, bruno := got[0], got[1]
+	if ana.SimilarDone != 2 || bruno.SimilarDone != 0 || bruno.Expertise != 0 {
+		t.Errorf("similar done: ana %d, bruno %d (expertise %v)", ana.SimilarDone, bruno.SimilarDone, bruno.Expertise)
+	}
+	if bruno.Availability != 1 || bruno.Deadlines != 1 {
+		t.Errorf("idle user: availability %v, deadlines %v; want 1, 1", bruno.Availability, bruno.Deadlines)
+	}
+	// 60 h plus 2 unestimated tasks at 4 h each.
+	carla := got[2]
+	if want := 0.37; carla.Availability != want {
+		t.Errorf("availability = %v, want %v", carla.Availability, want)
+	}
+	if want := 0.2; carla.Deadlines != want {
+		t.Errorf("deadlines = %v, want %v", carla.Deadlines, want)
+	}
+}
+
+func TestRankReasons(t *testing.T) {
+	due := time.Now().Add(48 * time.Hour)
+	got := assignment.Rank(assignment.Target{Task: loginBug, DueDate: &due, AssigneeID: "u3"}, assignmentCandidates())
+
+	reasons := map[string]string{}
+	for _, s := range got {
+		reasons[s.User.ID] = strings.Join(s.Reasons, "; ")
+		if s.Current != (s.User.ID == "u3") {
+			t.Errorf("%s: current = %v", s.User.ID, s.Current)
+		}
+	}
+	for id, want := range map[string][]string{
+		"u1": {`Completed 2 tasks similar to this one, e.g. "Fix login`, "Completed 2 bug tasks", "tagged security", "10 h of estimated open work across 2 tasks"},
+		"u2": {"Has not completed tasks similar", "Has no open tasks"},
+		"u3": {"Currently assigned", "Completed 1 bug task", "2 without an estimate", "1 overdue task", "2 tasks due before this one"},
+	} {
+		for _, w := range want {
+			if !strings.Contains(reasons[id], w) {
+				t.Errorf("%s reasons %q lack %q", id, reasons[id], w)
+			}
+		}
+	}
+}
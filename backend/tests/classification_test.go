@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/KemenyStudio/task-manager/internal/classification"
+	"github.com/KemenyStudio/task-manager/internal/db"
+	"github.com/KemenyStudio/task-manager/internal/llm"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+func TestClassificationBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		6:  16 * time.Minute,
+		7:  30 * time.Minute,
+		20: 30 * time.Minute,
+	}
+	for attempt, want := range cases {
+		if got := classification.Backoff(attempt); got != want {
+			t.Errorf("Backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	dup := fmt.Errorf("failed to requeue: %w", &pgconn.PgError{Code: "23505"})
+	if !db.IsUniqueViolation(dup) {
+		t.Error("expected a wrapped 23505 to be a unique violation")
+	}
+	if db.IsUniqueViolation(&pgconn.PgError{Code: "23503"}) || db.IsUniqueViolation(errors.New("23505")) {
+		t.Error("expected other errors not to be unique violations")
+	}
+}
+
+func TestSuggestionSelectedFields(t *testing.T) {
+	all, err := classification.SelectedFields(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(all) != len(classification.Fields) {
+		t.Errorf("expected no fields to select all %d, got %d", len(classification.Fields), len(all))
+	}
+
+	some, err := classification.SelectedFields([]string{"priority", "tags", "priority"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(some) != 2 || !some["priority"] || !some["tags"] {
+		t.Errorf("expected priority and tags, got %v", some)
+	}
+
+	if _, err := classification.SelectedFields([]string{"title"}); !errors.Is(err, classification.ErrInvalidField) {
+		t.Errorf("expected ErrInvalidField for title, got %v", err)
+	}
+}
+
+func TestValidateBatch(t *testing.T) {
+	opts := classification.BatchOptions{}
+	if err := classification.ValidateBatch(model.ClassificationBatchFilter{Unclassified: true}, &opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.Concurrency != 4 {
+		t.Errorf("expected default concurrency 4, got %d", opts.Concurrency)
+	}
+
+	before := time.Now()
+	unknown := "chore"
+	invalid := []struct {
+		name   string
+		filter model.ClassificationBatchFilter
+		opts   classification.BatchOptions
+	}{
+		{"unknown category", model.ClassificationBatchFilter{Category: &unknown}, classification.BatchOptions{}},
+		{"contradictory filter", model.ClassificationBatchFilter{Unclassified: true, ClassifiedBefore: &before}, classification.BatchOptions{}},
+		{"negative limit", model.ClassificationBatchFilter{Limit: -1}, classification.BatchOptions{}},
+		{"too much concurrency", model.ClassificationBatchFilter{}, classification.BatchOptions{Concurrency: classification.MaxBatchConcurrency + 1}},
+		{"negative rate", model.ClassificationBatchFilter{}, classification.BatchOptions{RatePerMinute: -5}},
+	}
+	for _, tt := range invalid {
+		if err := classification.ValidateBatch(tt.filter, &tt.opts); !errors.Is(err, classification.ErrInvalidBatch) {
+			t.Errorf("%s: expected ErrInvalidBatch, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestBatchChanges(t *testing.T) {
+	str := func(s string) *string { return &s }
+	current := map[string]*string{
+		"priority": str("high"), "category": str("bug"), "summary": str("Fix login"), "tags": str("auth,backend"),
+	}
+
+	same := &llm.TaskClassification{Priority: "high", Category: "bug", Summary: "Fix login", Tags: []string{"backend", "auth"}}
+	if changes := classification.Changes(current, same); len(changes) != 0 {
+		t.Errorf("expected no changes for the same classification in another tag order, got %v", changes)
+	}
+
+	different := &llm.TaskClassification{Priority: "urgent", Category: "bug", Summary: "Fix login", Tags: []string{"auth"}}
+	changes := classification.Changes(current, different)
+	if len(changes) != 2 || changes["priority"].To != "urgent" || *changes["priority"].From != "high" || changes["tags"].To != "auth" {
+		t.Errorf("expected priority and tags to change, got %v", changes)
+	}
+
+	current["category"], current["summary"] = nil, nil
+	changes = classification.Changes(current, same)
+	if c, ok := changes["category"]; !ok || c.From != nil || c.To != "bug" {
+		t.Errorf("expected an unset category to change, got %v", changes)
+	}
+}
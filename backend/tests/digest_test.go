@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KemenyStudio/task-manager/internal/model"
+	"github.com/KemenyStudio/task-manager/internal/notification"
+)
+
+func TestDigestDueUsesUserTimezone(t *testing.T) {
+	// 11:05 UTC is 08:05 in Santiago (UTC-3 in October).
+	now := time.Date(2026, 10, 19, 11, 5, 0, 0, time.UTC)
+	settings := model.DigestSettings{Frequency: "daily", SendHour: 8, Timezone: "America/Santiago"}
+
+	due, err := notification.DigestDue(settings, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !due {
+		t.Error("expected digest to be due at 08:05 local time")
+	}
+
+	settings.Timezone = "UTC"
+	if due, _ := notification.DigestDue(settings, now); due {
+		t.Error("expected digest not to be due at 11:05 UTC for an 08:00 UTC subscriber")
+	}
+}
+
+func TestDigestDueOncePerPeriod(t *testing.T) {
+	now := time.Date(2026, 10, 19, 8, 30, 0, 0, time.UTC) // Monday
+	lastSent := now.Add(-15 * time.Minute)
+	settings := model.DigestSettings{Frequency: "daily", SendHour: 8, Timezone: "UTC", LastSentAt: &lastSent}
+
+	if due, _ := notification.DigestDue(settings, now); due {
+		t.Error("expected no second digest within the same period")
+	}
+
+	lastSent = now.Add(-24 * time.Hour)
+	if due, _ := notification.DigestDue(settings, now); !due {
+		t.Error("expected digest to be due a day after the previous one")
+	}
+}
+
+func TestDigestDueWeeklyWeekday(t *testing.T) {
+	monday := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	settings := model.DigestSettings{Frequency: "weekly", SendHour: 8, Weekday: 1, Timezone: "UTC"}
+
+	if due, _ := notification.DigestDue(settings, monday); !due {
+		t.Error("expected weekly digest to be due on Monday")
+	}
+	if due, _ := notification.DigestDue(settings, monday.AddDate(0, 0, 1)); due {
+		t.Error("expected weekly digest not to be due on Tuesday")
+	}
+	settings.Frequency = "off"
+	if due, _ := notification.DigestDue(settings, monday); due {
+		t.Error("expected disabled digest never to be due")
+	}
+}
+
+func TestRenderDigestEmail(t *testing.T) {
+	due := time.Date(2026, 10, 20, 15, 0, 0, 0, time.UTC)
+	old, status := "review", "done"
+	d := &notification.Digest{
+		Name:      "Lucía",
+		Email:     "lucia@kemeny.studio",
+		Frequency: "weekly",
+		DueSoon:   []notification.DigestTask{{TaskID: "1", Title: "Ship OAuth", DueDate: &due, URL: "http://x/tasks/1"}},
+		StatusChanges: []notification.DigestStatusChange{
+			{TaskID: "2", Title: "Write tests", OldStatus: &old, NewStatus: &status, ChangedBy: "Mateo"},
+		},
+	}
+
+	santiago, _ := time.LoadLocation("America/Santiago")
+	msg, err := notification.RenderDigestEmail(d, santiago)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.Subject != "Your weekly task digest" {
+		t.Errorf("unexpected subject %q", msg.Subject)
+	}
+	for _, want := range []string{"Ship OAuth (due Tue 20 Oct 12:00)", "Write tests: review → done by Mateo"} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("expected text to contain %q, got:\n%s", want, msg.Text)
+		}
+	}
+	if strings.Contains(msg.Text, "Overdue") {
+		t.Error("expected empty sections to be omitted")
+	}
+}
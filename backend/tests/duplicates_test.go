@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KemenyStudio/task-manager/internal/duplicates"
+	"github.com/KemenyStudio/task-manager/internal/llm"
+)
+
+func TestMockEmbeddings(t *testing.T) {
+	texts := []string{
+		"Dashboard shows wrong counts when filtering by date",
+		"Dashboard counts are wrong when filtering by date range",
+		"Migrate CSS modules to Tailwind",
+	}
+	e, err := llm.NewMockClient().Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Model != "mock" || len(e.Vectors) != 3 {
+		t.Fatalf("unexpected embeddings %s, %d vectors", e.Model, len(e.Vectors))
+	}
+	for i, v := range e.Vectors {
+		if norm := llm.Cosine(v, v); math.Abs(norm-1) > 1e-6 {
+			t.Errorf("vector %d: self similarity %v", i, norm)
+		}
+	}
+	dup, unrelated := llm.Cosine(e.Vectors[0], e.Vectors[1]), llm.Cosine(e.Vectors[0], e.Vectors[2])
+	if dup < 0.7 || unrelated > 0.3 {
+		t.Errorf("similarity: duplicate %v, unrelated %v", dup, unrelated)
+	}
+
+	again, _ := llm.NewMockClient().Embed(context.Background(), texts[:1])
+	if llm.Cosine(again.Vectors[0], e.Vectors[0]) < 1-1e-6 {
+		t.Error("mock embeddings are not deterministic")
+	}
+}
+
+func TestEmbedderFromEnvOllama(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		json.NewEncoder(w).Encode(map[string]any{"embeddings": [][]float32{{3, 4}}, "prompt_eval_count": 7})
+	}))
+	defer srv.Close()
+	t.Setenv("EMBEDDING_PROVIDER", "ollama")
+	t.Setenv("EMBEDDING_MODEL", "")
+	t.Setenv("LOCAL_LLM_BASE_URL", srv.URL)
+	t.Setenv("LLM_REDACTION", "")
+	t.Setenv("LLM_REDACT_TERMS", "")
+	t.Setenv("LLM_REDACT_TERMS_FILE", "")
+
+	embedder, err := llm.NewEmbedderFromEnv(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := embedder.Embed(context.Background(), []string{"Reply to ana@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Model != "ollama:"+llm.DefaultOllamaEmbeddingModel || e.Vectors[0][0] != 0.6 || e.Vectors[0][1] != 0.8 {
+		t.Errorf("unexpected embeddings %s %v", e.Model, e.Vectors)
+	}
+	if input, _ := got["input"].([]any); len(input) != 1 || input[0] == "Reply to ana@example.com" {
+		t.Errorf("expected redacted input, got %v", got["input"])
+	}
+}
+
+func TestEmbedderFromEnvLocal(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": []map[string]any{
+			{"index": 1, "embedding": []float32{0, 2}},
+			{"index": 0, "embedding": []float32{2, 0}},
+		}})
+	}))
+	defer srv.Close()
+	t.Setenv("EMBEDDING_PROVIDER", "local")
+	t.Setenv("LOCAL_LLM_BASE_URL", srv.URL+"/v1")
+	t.Setenv("EMBEDDING_MODEL", "")
+	if _, err := llm.NewEmbedderFromEnv(nil, nil); err == nil {
+		t.Error("expected an error without EMBEDDING_MODEL")
+	}
+
+	t.Setenv("EMBEDDING_MODEL", "bge-small")
+	embedder, err := llm.NewEmbedderFromEnv(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := embedder.Embed(context.Background(), []string{"first", "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Model != "local:bge-small" || e.Vectors[0][0] != 1 || e.Vectors[1][1] != 1 {
+		t.Errorf("embeddings not in input order: %s %v", e.Model, e.Vectors)
+	}
+
+	t.Setenv("EMBEDDING_PROVIDER", "off")
+	if embedder, err := llm.NewEmbedderFromEnv(nil, nil); embedder != nil || err != nil {
+		t.Errorf("off: got %v, %v", embedder, err)
+	}
+}
+
+func TestEmbedUnsupported(t *testing.T) {
+	plain := llmFunc(func(ctx context.Context, title, description string) (*llm.TaskClassification, error) { return nil, nil })
+	if _, err := llm.NewResilientClient(plain, fastConfig()).Embed(context.Background(), []string{"x"}); !errors.Is(err, llm.ErrUnsupported) {
+		t.Errorf("want ErrUnsupported, got %v", err)
+	}
+	routed, _ := llm.NewRoutingClient(llm.Route{Name: "plain", Client: plain}, llm.Route{Name: "mock", Client: llm.NewMockClient()})
+	if e, err := routed.Embed(context.Background(), []string{"x"}); err != nil || e.Model != "mock" {
+		t.Errorf("routing should fall through to the mock: %v, %v", e, err)
+	}
+}
+
+func TestDuplicateThreshold(t *testing.T) {
+	t.Setenv("DUPLICATE_THRESHOLD", "")
+	if got := duplicates.Threshold(); got != duplicates.DefaultThreshold {
+		t.Errorf("default = %v", got)
+	}
+	t.Setenv("DUPLICATE_THRESHOLD", "0.9")
+	if got := duplicates.Threshold(); got != 0.9 {
+		t.Errorf("0.9 = %v", got)
+	}
+	t.Setenv("DUPLICATE_THRESHOLD", "2")
+	if got := duplicates.Threshold(); got != duplicates.DefaultThreshold {
+		t.Errorf("out of range = %v", got)
+	}
+}
+
+func TestDuplicateContentHash(t *testing.T) {
+	text := duplicates.Text("Fix login", "")
+	sum := sha256.Sum256([]byte("Fix login\n\n"))
+	if got := duplicates.ContentHash(text); got != hex.EncodeToString(sum[:]) {
+		t.Errorf("ContentHash = %s", got)
+	}
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/KemenyStudio/task-manager/internal/escalation"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestValidateEscalationRule(t *testing.T) {
+	valid := model.EscalationRule{
+		Name:             "Stuck in review",
+		Condition:        escalation.ConditionStaleStatus,
+		Status:           strPtr("review"),
+		ThresholdMinutes: 4320,
+		Notify:           []string{escalation.TargetAssignee},
+	}
+	if err := escalation.ValidateRule(valid); err != nil {
+		t.Fatalf("expected valid rule, got %v", err)
+	}
+
+	cases := map[string]func(r *model.EscalationRule){
+		"unknown condition":    func(r *model.EscalationRule) { r.Condition = "late" },
+		"stale without status": func(r *model.EscalationRule) { r.Status = nil },
+		"stale in done":        func(r *model.EscalationRule) { r.Status = strPtr("done") },
+		"overdue with status":  func(r *model.EscalationRule) { r.Condition = escalation.ConditionOverdue },
+		"invalid priority":     func(r *model.EscalationRule) { r.Priority = strPtr("critical") },
+		"zero threshold":       func(r *model.EscalationRule) { r.ThresholdMinutes = 0 },
+		"no targets":           func(r *model.EscalationRule) { r.Notify = nil },
+		"unknown target":       func(r *model.EscalationRule) { r.Notify = []string{"managers"} },
+		"non-positive repeat":  func(r *model.EscalationRule) { zero := 0; r.RepeatMinutes = &zero },
+		"missing name":         func(r *model.EscalationRule) { r.Name = "" },
+	}
+	for name, mutate := range cases {
+		rule := valid
+		mutate(&rule)
+		if err := escalation.ValidateRule(rule); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestEscalationShouldNotify(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	repeat := 60
+	rule := model.EscalationRule{RepeatMinutes: &repeat}
+
+	if !escalation.ShouldNotify(rule, nil, now) {
+		t.Error("expected a new escalation to notify")
+	}
+
+	entry := &model.EscalationLog{LastNotifiedAt: now.Add(-30 * time.Minute)}
+	if escalation.ShouldNotify(rule, entry, now) {
+		t.Error("expected no re-notification before repeat interval")
+	}
+	entry.LastNotifiedAt = now.Add(-time.Hour)
+	if !escalation.ShouldNotify(rule, entry, now) {
+		t.Error("expected re-notification after repeat interval")
+	}
+
+	acked := now.Add(-5 * time.Minute)
+	entry.AcknowledgedAt = &acked
+	if escalation.ShouldNotify(rule, entry, now) {
+		t.Error("expected acknowledged escalation to be suppressed")
+	}
+
+	entry.AcknowledgedAt = nil
+	rule.RepeatMinutes = nil
+	if escalation.ShouldNotify(rule, entry, now.Add(24*time.Hour)) {
+		t.Error("expected rule without repeat to notify only once")
+	}
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/KemenyStudio/task-manager/internal/estimation"
+	"github.com/KemenyStudio/task-manager/internal/llm"
+	"github.com/KemenyStudio/task-manager/internal/model"
+)
+
+var effortHistory = []estimation.Sample{
+	{TaskID: "a", Title: "Fix login redirect loop", Description: "OAuth callback redirects forever", Category: "bug", Tags: []string{"security"}, ActualHours: 3},
+	{TaskID: "b", Title: "Fix login error message", Description: "Wrong message on invalid password", Category: "bug", Tags: []string{"security"}, ActualHours: 1},
+	{TaskID: "c", Title: "Migrate styles to Tailwind", Description: "Move TaskCard and Dashboard", Category: "improvement", Tags: []string{"frontend"}, ActualHours: 20},
+	{TaskID: "d", Title: "Research vector databases", Category: "research", ActualHours: 8},
+	{TaskID: "e", Title: "Fix login redirect on mobile", Category: "bug", ActualHours: 0}, // not done
+}
+
+func TestEffortSimilarity(t *testing.T) {
+	target := estimation.Sample{Title: "Fix login redirect after OAuth callback", Category: "bug", Tags: []string{"security"}}
+	if s := estimation.Similarity(target, target); math.Abs(s-1) > 1e-9 {
+		t.Errorf("self similarity = %v, want 1", s)
+	}
+	near, far := estimation.Similarity(target, effortHistory[0]), estimation.Similarity(target, effortHistory[2])
+	if near <= far || far != 0 {
+		t.Errorf("similarity: near %v, far %v", near, far)
+	}
+}
+
+func TestEffortNearest(t *testing.T) {
+	target := estimation.Sample{Title: "Fix login redirect after OAuth callback", Category: "bug", Tags: []string{"security"}}
+	got := estimation.Nearest(target, effortHistory, 5)
+	if len(got) != 2 || got[0].TaskID != "a" || got[1].TaskID != "b" {
+		t.Fatalf("Nearest = %+v, want a then b", got)
+	}
+	if got := estimation.Nearest(target, effortHistory, 1); len(got) != 1 {
+		t.Errorf("k not applied: %+v", got)
+	}
+	if got := estimation.Nearest(estimation.Sample{Title: "Write quarterly report"}, effortHistory, 5); len(got) != 0 {
+		t.Errorf("unrelated task matched %+v", got)
+	}
+}
+
+func TestEffortFromNeighbours(t *testing.T) {
+	e := estimation.FromNeighbours([]model.EffortNeighbour{
+		{TaskID: "a", ActualHours: 2, Similarity: 0.5},
+		{TaskID: "b", ActualHours: 8, Similarity: 0.5},
+	})
+	if e.Hours != 4 || e.Method != estimation.MethodNeighbours {
+		t.Errorf("geometric mean = %v (%s), want 4", e.Hours, e.Method)
+	}
+	if !(e.Low < 2 && e.High > 8) {
+		t.Errorf("interval [%v, %v] should cover both neighbours", e.Low, e.High)
+	}
+
+	tight := estimation.FromNeighbours([]model.EffortNeighbour{
+		{ActualHours: 4, Similarity: 0.8}, {ActualHours: 4, Similarity: 0.7}, {ActualHours: 5, Similarity: 0.6}, {ActualHours: 4, Similarity: 0.9},
+	})
+	if tight.Confidence != "high" || tight.Low > tight.Hours || tight.High < tight.Hours {
+		t.Errorf("consistent neighbours: %+v", tight)
+	}
+	if one := estimation.FromNeighbours([]model.EffortNeighbour{{ActualHours: 4, Similarity: 0.9}}); one.Confidence != "low" {
+		t.Errorf("one neighbour should be low confidence, got %s", one.Confidence)
+	}
+	if none := estimation.FromNeighbours(nil); none.Hours != 0 || none.Neighbours == nil {
+		t.Errorf("no neighbours: %+v", none)
+	}
+}
+
+func TestValidateEffortEstimate(t *testing.T) {
+	e := &llm.EffortEstimate{Hours: 4.444, Low: 2, High: 8, Rationale: " ok "}
+	if err := llm.ValidateEffortEstimate(e); err != nil || e.Hours != 4.44 || e.Rationale != "ok" {
+		t.Errorf("ValidateEffortEstimate = %v, %+v", err, e)
+	}
+	var ve *llm.ValidationError
+	for _, bad := range []llm.EffortEstimate{
+		{Hours: 0, Low: 0, High: 1},
+		{Hours: 4, Low: 5, High: 8},
+		{Hours: 4, Low: 2, High: 3},
+		{Hours: 4, Low: 2, High: 1000},
+	} {
+		if err := llm.ValidateEffortEstimate(&bad); !errors.As(err, &ve) {
+			t.Errorf("%+v: want a validation error, got %v", bad, err)
+		}
+	}
+}
+
+func TestMockEstimateEffort(t *testing.T) {
+	mock := llm.NewMockClient()
+	baseline := 6.0
+	e, err := mock.EstimateEffort(context.Background(), llm.EffortRequest{Title: "x", Baseline: &baseline})
+	if err != nil || e.Hours != 6 || e.Low != 3 || e.High != 12 {
+		t.Errorf("baseline: %+v, %v", e, err)
+	}
+	e, err = mock.EstimateEffort(context.Background(), llm.EffortRequest{Title: "x", Examples: []llm.EffortExample{{ActualHours: 1}, {ActualHours: 5}, {ActualHours: 3}}})
+	if err != nil || e.Hours != 3 {
+		t.Errorf("examples median: %+v, %v", e, err)
+	}
+
+	// Decorators pass the capability through, and report clients without it.
+	e, err = llm.NewResilientClient(mock, fastConfig()).EstimateEffort(context.Background(), llm.EffortRequest{Title: "x", Category: "bug"})
+	if err != nil || e.Hours != 3 {
+		t.Errorf("through ResilientClient: %+v, %v", e, err)
+	}
+	plain := llmFunc(func(ctx context.Context, title, description string) (*llm.TaskClassification, error) { return nil, nil })
+	if _, err := llm.NewResilientClient(plain, fastConfig()).EstimateEffort(context.Background(), llm.EffortRequest{}); !errors.Is(err, llm.ErrUnsupported) {
+		t.Errorf("want ErrUnsupported, got %v", err)
+	}
+}
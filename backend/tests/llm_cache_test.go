@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KemenyStudio/task-manager/internal/llm"
+)
+
+// memoryCache is an in-memory llm.CacheStore.
+type memoryCache struct {
+	entries map[string]*llm.TaskClassification
+	err     error
+}
+
+func (m *memoryCache) Get(ctx context.Context, key string) (*llm.TaskClassification, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.entries[key], nil
+}
+
+func (m *memoryCache) Put(ctx context.Context, key string, tc *llm.TaskClassification, ttl time.Duration) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.entries[key] = tc
+	return nil
+}
+
+func TestCachingClientHitsAndMisses(t *testing.T) {
+	provider := &fakeProvider{results: []func(context.Context) (*llm.TaskClassification, error){answerOK()}}
+	client := llm.NewCachingClient(provider, "openai", "gpt-4o-mini", &memoryCache{entries: map[string]*llm.TaskClassification{}}, time.Hour)
+	before := llm.CacheCounters()
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.ClassifyTask(context.Background(), "Login fails", "500 on submit"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if provider.calls != 1 {
+		t.Errorf("expected 1 provider call for an unchanged task, got %d", provider.calls)
+	}
+	if _, err := client.ClassifyTask(context.Background(), "Login fails", "500 on submit, only on Safari"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if provider.calls != 2 {
+		t.Errorf("expected an edited description to miss the cache, got %d calls", provider.calls)
+	}
+
+	after := llm.CacheCounters()
+	if hits, misses := after.Hits-before.Hits, after.Misses-before.Misses; hits != 2 || misses != 2 {
+		t.Errorf("expected 2 hits and 2 misses, got %d and %d", hits, misses)
+	}
+}
+
+func TestCachingClientForceRefresh(t *testing.T) {
+	provider := &fakeProvider{results: []func(context.Context) (*llm.TaskClassification, error){answerOK()}}
+	client := llm.NewCachingClient(provider, "openai", "gpt-4o-mini", &memoryCache{entries: map[string]*llm.TaskClassification{}}, time.Hour)
+
+	client.ClassifyTask(context.Background(), "Login fails", "")
+	if _, err := client.ClassifyTask(llm.WithForceRefresh(context.Background()), "Login fails", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if provider.calls != 2 {
+		t.Errorf("expected force to call the provider, got %d calls", provider.calls)
+	}
+}
+
+func TestCachingClientStoreFailure(t *testing.T) {
+	provider := &fakeProvider{results: []func(context.Context) (*llm.TaskClassification, error){answerOK()}}
+	client := llm.NewCachingClient(provider, "openai", "gpt-4o-mini", &memoryCache{err: errors.New("db down")}, time.Hour)
+
+	if _, err := client.ClassifyTask(context.Background(), "Login fails", ""); err != nil {
+		t.Errorf("expected store errors to fall through to the provider, got %v", err)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	key := llm.CacheKey("openai", "gpt-4o-mini", "v1", "Login fails", "")
+	if key != llm.CacheKey("openai", "gpt-4o-mini", "v1", "Login fails", "") {
+		t.Error("expected the same key for the same request")
+	}
+	for _, other := range []string{
+		llm.CacheKey("anthropic", "gpt-4o-mini", "v1", "Login fails", ""),
+		llm.CacheKey("openai", "gpt-4o", "v1", "Login fails", ""),
+		llm.CacheKey("openai", "gpt-4o-mini", "v2", "Login fails", ""),
+		llm.CacheKey("openai", "gpt-4o-mini", "v1", "Login fail", "s"),
+	} {
+		if other == key {
+			t.Errorf("expected a different key, got %s", other)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/KemenyStudio/task-manager/internal/llm"
+)
+
+const (
+	loginTitle       = "Login fails"
+	loginDescription = "Submitting the login form returns a 500 error."
+	darkTitle        = "Add dark mode"
+	darkDescription  = "Users want a dark theme toggle in the header. Nice to have."
+)
+
+// useCassette routes provider clients created in the test through a strict
+// cassette, so a request that isn't recorded fails instead of going out.
+func useCassette(t *testing.T, path string) *llm.Cassette {
+	t.Helper()
+	c, err := llm.LoadCassette(path, true)
+	if err != nil {
+		t.Fatalf("failed to load cassette: %v", err)
+	}
+	llm.SetHTTPClient(c.HTTPClient())
+	t.Cleanup(func() { llm.SetHTTPClient(nil) })
+	return c
+}
+
+func openAIFromCassette(t *testing.T, model string) *llm.OpenAIClient {
+	t.Helper()
+	useCassette(t, "../testdata/cassettes/openai.json")
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	t.Setenv("OPENAI_BASE_URL", "https://api.openai.com/v1/")
+	t.Setenv("OPENAI_MODEL", model)
+	client, err := llm.NewOpenAIClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return client
+}
+
+func anthropicFromCassette(t *testing.T, model string) *llm.AnthropicClient {
+	t.Helper()
+	useCassette(t, "../testdata/cassettes/anthropic.json")
+	t.Setenv("ANTHROPIC_API_KEY", "sk-ant-test")
+	t.Setenv("ANTHROPIC_BASE_URL", "https://api.anthropic.com")
+	t.Setenv("ANTHROPIC_MODEL", model)
+	client, err := llm.NewAnthropicClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return client
+}
+
+func assertClassification(t *testing.T, tc *llm.TaskClassification, provider, model, priority, category string) {
+	t.Helper()
+	if tc.Provider != provider || tc.Model != model {
+		t.Errorf("expected %s/%s, got %s/%s", provider, model, tc.Provider, tc.Model)
+	}
+	if tc.Priority != priority || tc.Category != category {
+		t.Errorf("expected %s %s, got %s %s", priority, category, tc.Priority, tc.Category)
+	}
+	if len(tc.Tags) == 0 || tc.Summary == "" {
+		t.Errorf("expected tags and a summary, got %+v", tc)
+	}
+}
+
+func TestCassetteOpenAIStructuredOutput(t *testing.T) {
+	client := openAIFromCassette(t, "gpt-4o-mini")
+	tc, err := client.ClassifyTask(context.Background(), loginTitle, loginDescription)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertClassification(t, tc, "openai", "gpt-4o-mini", "high", "bug")
+}
+
+func TestCassetteOpenAIFencedAnswer(t *testing.T) {
+	client := openAIFromCassette(t, "gpt-3.5-turbo")
+	tc, err := client.ClassifyTask(context.Background(), darkTitle, darkDescription)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertClassification(t, tc, "openai", "gpt-3.5-turbo", "low", "feature")
+}
+
+func TestCassetteOpenAIRateLimited(t *testing.T) {
+	client := openAIFromCassette(t, "gpt-4o-mini")
+	_, err := client.ClassifyTask(context.Background(), "Rate limited task", "")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if llm.StatusCode(err) != http.StatusTooManyRequests || !llm.IsTransient(err) {
+		t.Errorf("expected a transient 429, got %v", err)
+	}
+}
+
+func TestCassetteAnthropicToolUse(t *testing.T) {
+	client := anthropicFromCassette(t, "claude-3-5-haiku-latest")
+	tc, err := client.ClassifyTask(context.Background(), loginTitle, loginDescription)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertClassification(t, tc, "anthropic", "claude-3-5-haiku-latest", "high", "bug")
+}
+
+func TestCassetteAnthropicTextAnswer(t *testing.T) {
+	client := anthropicFromCassette(t, "claude-2.1")
+	tc, err := client.ClassifyTask(context.Background(), darkTitle, darkDescription)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertClassification(t, tc, "anthropic", "claude-2.1", "low", "feature")
+}
+
+func TestCassetteAnthropicOverloaded(t *testing.T) {
+	client := anthropicFromCassette(t, "claude-3-5-haiku-latest")
+	_, err := client.ClassifyTask(context.Background(), "Overloaded task", "")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if llm.StatusCode(err) != 529 || !llm.IsTransient(err) {
+		t.Errorf("expected a transient 529, got %v", err)
+	}
+}
+
+// TestCassetteThroughConfiguredClient replays through the client handlers
+// are given, decorators included. The handlers themselves load tasks and
+// store suggestions in Postgres, so they can't run here.
+func TestCassetteThroughConfiguredClient(t *testing.T) {
+	openAIFromCassette(t, "gpt-4o-mini")
+	t.Setenv("LLM_PROVIDERS", "openai:gpt-4o-mini")
+	t.Setenv("LLM_REDACT_TERMS", "")
+	t.Setenv("LLM_REDACT_TERMS_FILE", "")
+	recorder := &fakeRecorder{}
+	client, err := llm.NewClientFromEnv(&memoryCache{entries: map[string]*llm.TaskClassification{}}, recorder, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		tc, err := client.ClassifyTask(context.Background(), loginTitle, loginDescription)
+		if err != nil {
+			t.Fatalf("call %d: unexpected error: %v", i+1, err)
+		}
+		assertClassification(t, tc, "openai", "gpt-4o-mini", "high", "bug")
+	}
+	if len(recorder.records) != 1 || recorder.records[0].Operation != "classify" || recorder.records[0].Err != nil {
+		t.Errorf("expected one metered call, the second served from the cache, got %+v", recorder.records)
+	}
+
+	if _, err := client.ClassifyTask(context.Background(), "Never recorded", ""); !errors.Is(err, llm.ErrCassetteMiss) {
+		t.Errorf("expected ErrCassetteMiss through the decorators, got %v", err)
+	}
+}
+
+func TestCassetteStrictMiss(t *testing.T) {
+	client := openAIFromCassette(t, "gpt-4o-mini")
+	_, err := client.ClassifyTask(context.Background(), "Never recorded", "")
+	if !errors.Is(err, llm.ErrCassetteMiss) {
+		t.Errorf("expected ErrCassetteMiss, got %v", err)
+	}
+}
+
+func TestCassetteRecordsAndReplays(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": ` + quote(localAnswer) + `}}]}`))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "local.json")
+	recorder, err := llm.LoadCassette(path, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	llm.SetHTTPClient(recorder.HTTPClient())
+	defer llm.SetHTTPClient(nil)
+	client, err := llm.NewLocalClient(llm.FlavorOpenAI, srv.URL+"/v1/", "qwen2.5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := client.ClassifyTask(context.Background(), loginTitle, loginDescription); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected the second call to be replayed, got %d server calls", calls)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("failed to save cassette: %v", err)
+	}
+
+	// A strict replay of the saved file works with the server gone.
+	srv.Close()
+	useCassette(t, path)
+	client, err = llm.NewLocalClient(llm.FlavorOpenAI, srv.URL+"/v1/", "qwen2.5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tc, err := client.ClassifyTask(context.Background(), loginTitle, loginDescription)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tc.Category != "bug" || tc.Priority != "high" {
+		t.Errorf("unexpected replayed classification %+v", tc)
+	}
+}
+
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
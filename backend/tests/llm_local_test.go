@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KemenyStudio/task-manager/internal/llm"
+)
+
+const localAnswer = `{"tags": ["backend"], "priority": "high", "category": "bug", "summary": "Fix login"}`
+
+func TestLocalClientOpenAICompatible(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("expected no Authorization header, got %q", auth)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": localAnswer}}},
+		})
+	}))
+	defer srv.Close()
+
+	client, err := llm.NewLocalClient(llm.FlavorOpenAI, srv.URL+"/v1/", "qwen2.5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tc, err := client.ClassifyTask(context.Background(), "Login fails", "500 on submit")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tc.Category != "bug" || tc.Priority != "high" {
+		t.Errorf("unexpected classification %+v", tc)
+	}
+	if tc.Provider != "local" || tc.Model != "qwen2.5" {
+		t.Errorf("expected provider local/qwen2.5, got %s/%s", tc.Provider, tc.Model)
+	}
+	if got["model"] != "qwen2.5" {
+		t.Errorf("expected model in request, got %v", got["model"])
+	}
+	if rf, _ := got["response_format"].(map[string]interface{}); rf["type"] != "json_schema" {
+		t.Errorf("expected json_schema response format, got %v", got["response_format"])
+	}
+}
+
+func TestLocalClientOllama(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": map[string]string{"role": "assistant", "content": localAnswer},
+			"done":    true,
+		})
+	}))
+	defer srv.Close()
+
+	client, err := llm.NewLocalClient(llm.FlavorOllama, srv.URL, "llama3.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tc, err := client.ClassifyTask(context.Background(), "Login fails", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tc.Summary != "Fix login" || tc.Provider != "ollama" {
+		t.Errorf("unexpected classification %+v", tc)
+	}
+	format, _ := got["format"].(map[string]interface{})
+	if got["stream"] != false || format["type"] != "object" {
+		t.Errorf("expected non-streaming request constrained to the schema, got %v", got)
+	}
+}
+
+func TestLocalClientErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/chat" {
+			http.Error(w, "model is loading", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": "I think it's a bug"}}},
+		})
+	}))
+	defer srv.Close()
+
+	ollama, _ := llm.NewLocalClient(llm.FlavorOllama, srv.URL, "llama3.1")
+	_, err := ollama.ClassifyTask(context.Background(), "t", "d")
+	if llm.StatusCode(err) != http.StatusServiceUnavailable || !llm.IsTransient(err) {
+		t.Errorf("expected transient 503 error, got %v", err)
+	}
+
+	local, _ := llm.NewLocalClient(llm.FlavorOpenAI, srv.URL, "")
+	var mErr *llm.MalformedResponseError
+	if _, err := local.ClassifyTask(context.Background(), "t", "d"); !errors.As(err, &mErr) {
+		t.Errorf("expected MalformedResponseError, got %v", err)
+	}
+
+	if _, err := llm.NewLocalClient(llm.FlavorOpenAI, "", ""); err == nil {
+		t.Error("expected error without base URL")
+	}
+}
@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KemenyStudio/task-manager/internal/llm"
+)
+
+func TestDefaultPromptTemplate(t *testing.T) {
+	p, err := llm.NewPromptTemplate("v1", llm.DefaultPromptTemplate, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, err := p.Render("Login fails", "500 on submit")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasSuffix(out, "}\n\nTitle: Login fails\n\nDescription: 500 on submit\n") {
+		t.Errorf("unexpected prompt without examples:\n%s", out)
+	}
+	if strings.Contains(out, "Example:") {
+		t.Errorf("expected no examples section, got:\n%s", out)
+	}
+}
+
+func TestPromptTemplateFewShotExamples(t *testing.T) {
+	examples := []llm.PromptExample{{
+		Title:          "Checkout times out",
+		Description:    "Payments hang for 30s",
+		Classification: llm.TaskClassification{Tags: []string{"payments"}, Priority: "urgent", Category: "bug", Summary: "Fix checkout timeout"},
+	}}
+	p, err := llm.NewPromptTemplate("v2", llm.DefaultPromptTemplate, examples)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, _ := p.Render("Login fails", "")
+	want := `Answer: {"tags":["payments"],"priority":"urgent","category":"bug","summary":"Fix checkout timeout"}`
+	if !strings.Contains(out, "Title: Checkout times out") || !strings.Contains(out, want) {
+		t.Errorf("expected the example in the prompt, got:\n%s", out)
+	}
+	if strings.Index(out, "Checkout times out") > strings.Index(out, "Title: Login fails") {
+		t.Error("expected examples before the task")
+	}
+}
+
+func TestPromptTemplateRejectsInvalid(t *testing.T) {
+	for _, body := range []string{"Title: {{.Title", "Title: {{.Assignee}}", "{{range .Examples}}{{.Priority}}{{end}}"} {
+		examples := []llm.PromptExample{{Title: "x"}}
+		if _, err := llm.NewPromptTemplate("bad", body, examples); err == nil {
+			t.Errorf("expected %q to be rejected", body)
+		}
+	}
+}
+
+func TestClientsUsePromptTemplateFromContext(t *testing.T) {
+	var got struct {
+		Messages []struct {
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": map[string]string{"role": "assistant", "content": localAnswer},
+		})
+	}))
+	defer srv.Close()
+
+	client, err := llm.NewLocalClient(llm.FlavorOllama, srv.URL, "llama3.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p, err := llm.NewPromptTemplate("v7", "Triage this ticket: {{.Title}}", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.ClassifyTask(llm.WithPromptTemplate(context.Background(), p), "Login fails", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Messages) != 1 || got.Messages[0].Content != "Triage this ticket: Login fails" {
+		t.Errorf("expected the custom prompt, got %+v", got.Messages)
+	}
+}
+
+func TestCacheKeyFollowsPromptVersion(t *testing.T) {
+	provider := &fakeProvider{results: []func(context.Context) (*llm.TaskClassification, error){answerOK()}}
+	client := llm.NewCachingClient(provider, "openai", "gpt-4o-mini", &memoryCache{entries: map[string]*llm.TaskClassification{}}, time.Hour)
+	v2, _ := llm.NewPromptTemplate("v2", llm.DefaultPromptTemplate, nil)
+
+	client.ClassifyTask(context.Background(), "Login fails", "")
+	client.ClassifyTask(llm.WithPromptTemplate(context.Background(), v2), "Login fails", "")
+	if provider.calls != 2 {
+		t.Errorf("expected a new prompt version to miss the cache, got %d calls", provider.calls)
+	}
+}
@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KemenyStudio/task-manager/internal/llm"
+)
+
+// fakeProvider answers each call with the next scripted result.
+type fakeProvider struct {
+	results []func(ctx context.Context) (*llm.TaskClassification, error)
+	calls   int
+}
+
+func (f *fakeProvider) ClassifyTask(ctx context.Context, title, description string) (*llm.TaskClassification, error) {
+	i := f.calls
+	f.calls++
+	if i >= len(f.results) {
+		i = len(f.results) - 1
+	}
+	return f.results[i](ctx)
+}
+
+// fakeCompleter is a fakeProvider that also accepts free-form prompts.
+type fakeCompleter struct {
+	fakeProvider
+	prompts []string
+	answer  string
+}
+
+func (f *fakeCompleter) Complete(ctx context.Context, prompt string) (string, error) {
+	f.prompts = append(f.prompts, prompt)
+	return f.answer, nil
+}
+
+func answerOK() func(context.Context) (*llm.TaskClassification, error) {
+	return func(context.Context) (*llm.TaskClassification, error) {
+		return &llm.TaskClassification{Priority: "high", Category: "bug"}, nil
+	}
+}
+
+func answerStatus(status int) func(context.Context) (*llm.TaskClassification, error) {
+	return func(context.Context) (*llm.TaskClassification, error) {
+		return nil, &llm.HTTPStatusError{StatusCode: status}
+	}
+}
+
+func fastConfig() llm.ResilientConfig {
+	return llm.ResilientConfig{
+		Timeout:          50 * time.Millisecond,
+		MaxRetries:       2,
+		BaseBackoff:      time.Millisecond,
+		MaxBackoff:       2 * time.Millisecond,
+		FailureThreshold: 2,
+		OpenDuration:     30 * time.Millisecond,
+	}
+}
+
+func TestResilientClientRetriesTransientErrors(t *testing.T) {
+	provider := &fakeProvider{results: []func(context.Context) (*llm.TaskClassification, error){answerStatus(429), answerStatus(503), answerOK()}}
+	client := llm.NewResilientClient(provider, fastConfig())
+
+	tc, err := client.ClassifyTask(context.Background(), "title", "desc")
+	if err != nil {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+	if tc.Category != "bug" || provider.calls != 3 {
+		t.Errorf("expected 3 calls ending in success, got %d calls and %+v", provider.calls, tc)
+	}
+}
+
+func TestResilientClientDoesNotRetryClientErrors(t *testing.T) {
+	provider := &fakeProvider{results: []func(context.Context) (*llm.TaskClassification, error){answerStatus(400), answerOK()}}
+	client := llm.NewResilientClient(provider, fastConfig())
+
+	if _, err := client.ClassifyTask(context.Background(), "title", "desc"); llm.StatusCode(err) != 400 {
+		t.Fatalf("expected the 400 error to be returned, got %v", err)
+	}
+	if provider.calls != 1 {
+		t.Errorf("expected a single call, got %d", provider.calls)
+	}
+}
+
+func TestResilientClientPerAttemptTimeout(t *testing.T) {
+	slow := func(ctx context.Context) (*llm.TaskClassification, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	provider := &fakeProvider{results: []func(context.Context) (*llm.TaskClassification, error){slow, answerOK()}}
+	client := llm.NewResilientClient(provider, fastConfig())
+
+	if _, err := client.ClassifyTask(context.Background(), "title", "desc"); err != nil {
+		t.Fatalf("expected timed-out attempt to be retried, got %v", err)
+	}
+	if provider.calls != 2 {
+		t.Errorf("expected 2 calls, got %d", provider.calls)
+	}
+}
+
+func TestResilientClientCircuitBreaker(t *testing.T) {
+	provider := &fakeProvider{results: []func(context.Context) (*llm.TaskClassification, error){answerStatus(500)}}
+	cfg := fastConfig()
+	cfg.MaxRetries = -1
+	client := llm.NewResilientClient(provider, cfg)
+
+	for i := 0; i < 2; i++ {
+		client.ClassifyTask(context.Background(), "title", "desc")
+	}
+	if _, err := client.ClassifyTask(context.Background(), "title", "desc"); !errors.Is(err, llm.ErrCircuitOpen) {
+		t.Fatalf("expected open circuit after 2 failures, got %v", err)
+	}
+	if provider.calls != 2 {
+		t.Errorf("expected provider not to be called while open, got %d calls", provider.calls)
+	}
+
+	// After OpenDuration a trial call goes through and closes the breaker.
+	time.Sleep(cfg.OpenDuration)
+	provider.results = append(provider.results, answerOK())
+	provider.calls = len(provider.results) - 1
+	if _, err := client.ClassifyTask(context.Background(), "title", "desc"); err != nil {
+		t.Fatalf("expected half-open trial to succeed, got %v", err)
+	}
+	if _, err := client.ClassifyTask(context.Background(), "title", "desc"); err != nil {
+		t.Errorf("expected closed breaker after success, got %v", err)
+	}
+}
+
+func TestResilientClientBreakerIgnoresCallerErrors(t *testing.T) {
+	provider := &fakeProvider{results: []func(context.Context) (*llm.TaskClassification, error){answerStatus(401)}}
+	cfg := fastConfig()
+	cfg.MaxRetries = -1
+	client := llm.NewResilientClient(provider, cfg)
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.ClassifyTask(context.Background(), "title", "desc"); errors.Is(err, llm.ErrCircuitOpen) {
+			t.Fatalf("call %d: a rejected request opened the breaker", i+1)
+		}
+	}
+	if provider.calls != 3 {
+		t.Errorf("expected every call to reach the provider, got %d calls", provider.calls)
+	}
+}
+
+func TestResilientClientRepromptsOnMalformedJSON(t *testing.T) {
+	malformed := func(context.Context) (*llm.TaskClassification, error) {
+		return nil, &llm.MalformedResponseError{Provider: "fake", Output: "Sure! priority: high", Err: errors.New("no JSON")}
+	}
+	provider := &fakeCompleter{answer: `{"priority": "low", "category": "research", "summary": "s", "tags": []}`}
+	provider.results = []func(context.Context) (*llm.TaskClassification, error){malformed}
+	client := llm.NewResilientClient(provider, fastConfig())
+
+	tc, err := client.ClassifyTask(context.Background(), "title", "desc")
+	if err != nil {
+		t.Fatalf("expected repaired classification, got %v", err)
+	}
+	if tc.Category != "research" {
+		t.Errorf("expected repaired category research, got %q", tc.Category)
+	}
+	if provider.calls != 1 || len(provider.prompts) != 1 {
+		t.Errorf("expected one classify call and one repair prompt, got %d and %d", provider.calls, len(provider.prompts))
+	}
+
+	// A second malformed answer is returned rather than re-prompting forever.
+	provider.answer = "still not json"
+	var mErr *llm.MalformedResponseError
+	if _, err := client.ClassifyTask(context.Background(), "title", "desc"); !errors.As(err, &mErr) {
+		t.Errorf("expected MalformedResponseError, got %v", err)
+	}
+	if len(provider.prompts) != 2 {
+		t.Errorf("expected exactly one repair per call, got %d prompts", len(provider.prompts))
+	}
+}
@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KemenyStudio/task-manager/internal/llm"
+)
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := llm.ParseRoutes("openai:gpt-4o-mini=80, openai:gpt-4o=20 ,anthropic,mock")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []llm.RouteSpec{
+		{Provider: "openai", Model: "gpt-4o-mini", Weight: 80},
+		{Provider: "openai", Model: "gpt-4o", Weight: 20},
+		{Provider: "anthropic"},
+		{Provider: "mock"},
+	}
+	if len(routes) != len(want) {
+		t.Fatalf("expected %d routes, got %v", len(want), routes)
+	}
+	for i := range want {
+		if routes[i] != want[i] {
+			t.Errorf("route %d: expected %+v, got %+v", i, want[i], routes[i])
+		}
+	}
+
+	for _, bad := range []string{"", "openai=abc", "openai=-1", ":gpt-4o"} {
+		if _, err := llm.ParseRoutes(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestRoutingClientWeightedOrder(t *testing.T) {
+	a := llm.Route{Name: "a", Client: llm.NewMockClient(), Weight: 80}
+	b := llm.Route{Name: "b", Client: llm.NewMockClient(), Weight: 20}
+	c := llm.Route{Name: "c", Client: llm.NewMockClient()}
+	client, err := llm.NewRoutingClient(a, b, c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	names := func(routes []llm.Route) string {
+		s := ""
+		for _, r := range routes {
+			s += r.Name
+		}
+		return s
+	}
+	if got := names(client.Order(0)); got != "abc" {
+		t.Errorf("expected abc for n=0, got %s", got)
+	}
+	if got := names(client.Order(79)); got != "abc" {
+		t.Errorf("expected abc for n=79, got %s", got)
+	}
+	if got := names(client.Order(80)); got != "bac" {
+		t.Errorf("expected b first with the rest as fallback for n=80, got %s", got)
+	}
+}
+
+func TestRoutingClientFallsBackAndReportsProvider(t *testing.T) {
+	failing := &fakeProvider{results: []func(context.Context) (*llm.TaskClassification, error){answerStatus(500)}}
+	client, err := llm.NewRoutingClient(
+		llm.Route{Name: "primary", Client: failing},
+		llm.Route{Name: "backup", Client: llm.NewMockClient()},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tc, err := client.ClassifyTask(context.Background(), "Fix crash", "")
+	if err != nil {
+		t.Fatalf("expected fallback to succeed, got %v", err)
+	}
+	if failing.calls != 1 {
+		t.Errorf("expected primary to be tried once, got %d", failing.calls)
+	}
+	if tc.Provider != "mock" {
+		t.Errorf("expected answering provider to be reported, got %q", tc.Provider)
+	}
+
+	onlyFailing, _ := llm.NewRoutingClient(llm.Route{Name: "primary", Client: failing})
+	if _, err := onlyFailing.ClassifyTask(context.Background(), "t", "d"); llm.StatusCode(err) != 500 {
+		t.Errorf("expected provider error to be preserved, got %v", err)
+	}
+}
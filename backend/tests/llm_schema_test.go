@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KemenyStudio/task-manager/internal/llm"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	got := llm.NormalizeTags([]string{" Backend API ", "", "SECURITY!", "security", "a", "b", "c", "d", "e", "f", "g"})
+	want := []string{"backend-api", "security", "a", "b", "c", "d", "e", "f"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected tags %v, got %v", want, got)
+	}
+}
+
+func TestNormalizeClassification(t *testing.T) {
+	tc := &llm.TaskClassification{Priority: " High", Category: "BUG", Summary: strings.Repeat("a", 200)}
+	llm.NormalizeClassification(tc)
+	if tc.Priority != "high" || tc.Category != "bug" {
+		t.Errorf("expected lowercased enums, got %s/%s", tc.Priority, tc.Category)
+	}
+	if len(tc.Summary) != llm.MaxSummaryLen || !strings.HasSuffix(tc.Summary, "...") {
+		t.Errorf("expected summary truncated to %d chars, got %d", llm.MaxSummaryLen, len(tc.Summary))
+	}
+}
+
+func TestValidateClassification(t *testing.T) {
+	valid := llm.TaskClassification{Priority: "urgent", Category: "research", Summary: "Look into it"}
+	if err := llm.ValidateClassification(&valid); err != nil {
+		t.Errorf("expected valid classification, got %v", err)
+	}
+
+	cases := map[string]llm.TaskClassification{
+		"priority": {Priority: "critical", Category: "bug", Summary: "x"},
+		"category": {Priority: "low", Category: "chore", Summary: "x"},
+		"summary":  {Priority: "low", Category: "bug"},
+	}
+	for field, tc := range cases {
+		var verr *llm.ValidationError
+		if err := llm.ValidateClassification(&tc); !errors.As(err, &verr) || verr.Field != field {
+			t.Errorf("expected ValidationError on %s, got %v", field, err)
+		}
+	}
+}
+
+func TestClassificationStrictDecoding(t *testing.T) {
+	cases := map[string]string{
+		"unknown enum":  `{"tags": [], "priority": "critical", "category": "bug", "summary": "x"}`,
+		"unknown field": `{"tags": [], "priority": "low", "category": "bug", "summary": "x", "confidence": 0.9}`,
+		"trailing text": `{"tags": [], "priority": "low", "category": "bug", "summary": "x"} Hope this helps!`,
+		"not an object": `Priority: low`,
+	}
+	for name, answer := range cases {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"message": map[string]string{"role": "assistant", "content": answer},
+			})
+		}))
+		client, err := llm.NewLocalClient(llm.FlavorOllama, srv.URL, "llama3.1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_, err = client.ClassifyTask(context.Background(), "Login fails", "")
+		var malformed *llm.MalformedResponseError
+		if !errors.As(err, &malformed) {
+			t.Errorf("%s: expected MalformedResponseError, got %v", name, err)
+		}
+		srv.Close()
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": map[string]string{"role": "assistant", "content": "```json\n" + localAnswer + "\n```"},
+		})
+	}))
+	defer srv.Close()
+	client, _ := llm.NewLocalClient(llm.FlavorOllama, srv.URL, "llama3.1")
+	if _, err := client.ClassifyTask(context.Background(), "Login fails", ""); err != nil {
+		t.Errorf("expected fenced JSON to decode, got %v", err)
+	}
+}
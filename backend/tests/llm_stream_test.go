@@ -0,0 +1,257 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KemenyStudio/task-manager/internal/llm"
+)
+
+// streamFunc is a streaming provider; ClassifyTask streams into nothing.
+type streamFunc func(ctx context.Context, title, description string, onDelta func(string)) (*llm.TaskClassification, error)
+
+func (f streamFunc) ClassifyTask(ctx context.Context, title, description string) (*llm.TaskClassification, error) {
+	return f(ctx, title, description, func(string) {})
+}
+
+func (f streamFunc) ClassifyTaskStream(ctx context.Context, title, description string, onDelta func(string)) (*llm.TaskClassification, error) {
+	return f(ctx, title, description, onDelta)
+}
+
+// collect returns an onDelta that appends to pieces.
+func collect(pieces *[]string) func(string) {
+	return func(s string) { *pieces = append(*pieces, s) }
+}
+
+// sseServer answers every request with the given server-sent events.
+func sseServer(t *testing.T, events ...string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, ev := range events {
+			name, data, _ := strings.Cut(ev, " ")
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestMockClassifyTaskStream(t *testing.T) {
+	mock := llm.NewMockClient()
+	var pieces []string
+	tc, err := mock.ClassifyTaskStream(context.Background(), "Fix the crash on login", "The API returns 500", collect(&pieces))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pieces) < 2 {
+		t.Errorf("expected the answer in several pieces, got %q", pieces)
+	}
+	var streamed llm.TaskClassification
+	if err := json.Unmarshal([]byte(strings.Join(pieces, "")), &streamed); err != nil {
+		t.Fatalf("streamed output isn't the JSON answer: %v", err)
+	}
+	if streamed.Category != tc.Category || streamed.Priority != tc.Priority || streamed.Summary != tc.Summary {
+		t.Errorf("streamed %+v, returned %+v", streamed, tc)
+	}
+}
+
+func TestOpenAIClassifyTaskStream(t *testing.T) {
+	srv := sseServer(t,
+		`response.output_text.delta {"type":"response.output_text.delta","item_id":"msg_1","output_index":0,"content_index":0,"delta":"{\"tags\":[\"bug\"],\"priority\":\"high\",","sequence_number":1}`,
+		`response.output_text.delta {"type":"response.output_text.delta","item_id":"msg_1","output_index":0,"content_index":0,"delta":"\"category\":\"bug\",\"summary\":\"Fix login\"}","sequence_number":2}`,
+		`response.completed {"type":"response.completed","response":{"id":"resp_1","object":"response","status":"completed","output":[],"usage":{"input_tokens":50,"output_tokens":20,"total_tokens":70}},"sequence_number":3}`,
+	)
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	t.Setenv("OPENAI_BASE_URL", srv.URL+"/")
+	t.Setenv("OPENAI_MODEL", "gpt-4o-mini")
+	client, err := llm.NewOpenAIClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var pieces []string
+	tc, err := client.ClassifyTaskStream(context.Background(), loginTitle, loginDescription, collect(&pieces))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pieces) != 2 {
+		t.Errorf("expected 2 pieces, got %q", pieces)
+	}
+	assertClassification(t, tc, "openai", "gpt-4o-mini", "high", "bug")
+}
+
+func TestAnthropicClassifyTaskStream(t *testing.T) {
+	srv := sseServer(t,
+		`message_start {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-3-5-haiku-latest","content":[],"stop_reason":null,"usage":{"input_tokens":40,"output_tokens":1}}}`,
+		`content_block_start {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"record_classification","input":{}}}`,
+		`content_block_delta {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"tags\": [\"bug\"], \"priority\": \"high\", "}}`,
+		`content_block_delta {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"\"category\": \"bug\", \"summary\": \"Fix login\"}"}}`,
+		`content_block_stop {"type":"content_block_stop","index":0}`,
+		`message_delta {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":30}}`,
+		`message_stop {"type":"message_stop"}`,
+	)
+	t.Setenv("ANTHROPIC_API_KEY", "sk-ant-test")
+	t.Setenv("ANTHROPIC_BASE_URL", srv.URL)
+	t.Setenv("ANTHROPIC_MODEL", "claude-3-5-haiku-latest")
+	client, err := llm.NewAnthropicClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var pieces []string
+	tc, err := client.ClassifyTaskStream(context.Background(), loginTitle, loginDescription, collect(&pieces))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pieces) != 2 || !strings.HasPrefix(pieces[0], `{"tags"`) {
+		t.Errorf("expected the tool input in 2 pieces, got %q", pieces)
+	}
+	assertClassification(t, tc, "anthropic", "claude-3-5-haiku-latest", "high", "bug")
+}
+
+func TestResilientStreamRetriesOnlyBeforeOutput(t *testing.T) {
+	unavailable := &llm.HTTPStatusError{StatusCode: 503, Body: "overloaded"}
+	calls := 0
+	provider := streamFunc(func(ctx context.Context, title, description string, onDelta func(string)) (*llm.TaskClassification, error) {
+		calls++
+		if calls == 1 {
+			return nil, unavailable
+		}
+		onDelta(`{"tags":`)
+		return nil, unavailable
+	})
+
+	var pieces []string
+	_, err := llm.NewResilientClient(provider, fastConfig()).ClassifyTaskStream(context.Background(), "t", "d", collect(&pieces))
+	if !errors.Is(err, unavailable) {
+		t.Fatalf("expected the provider error, got %v", err)
+	}
+	if calls != 2 || len(pieces) != 1 {
+		t.Errorf("expected a retry before output only, got %d calls and pieces %q", calls, pieces)
+	}
+}
+
+func TestResilientStreamIdleTimeout(t *testing.T) {
+	cfg := fastConfig() // 50ms timeout
+	slow := streamFunc(func(ctx context.Context, title, description string, onDelta func(string)) (*llm.TaskClassification, error) {
+		for i := 0; i < 5; i++ {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(20 * time.Millisecond):
+			}
+			onDelta(".")
+		}
+		return &llm.TaskClassification{Tags: []string{"bug"}, Priority: "high", Category: "bug", Summary: "Fix login"}, nil
+	})
+	if _, err := llm.NewResilientClient(slow, cfg).ClassifyTaskStream(context.Background(), "t", "d", func(string) {}); err != nil {
+		t.Errorf("a stream making progress should outlive the timeout: %v", err)
+	}
+
+	cfg.MaxRetries = -1
+	stalled := streamFunc(func(ctx context.Context, title, description string, onDelta func(string)) (*llm.TaskClassification, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	_, err := llm.NewResilientClient(stalled, cfg).ClassifyTaskStream(context.Background(), "t", "d", func(string) {})
+	if !errors.Is(err, context.DeadlineExceeded) || !llm.IsTransient(err) {
+		t.Errorf("expected a transient deadline error, got %v", err)
+	}
+}
+
+func TestRedactingStreamRestoresSplitPlaceholders(t *testing.T) {
+	var sent string
+	echo := streamFunc(func(ctx context.Context, title, description string, onDelta func(string)) (*llm.TaskClassification, error) {
+		sent = description
+		for s := description; s != ""; {
+			n := min(3, len(s))
+			onDelta(s[:n])
+			s = s[n:]
+		}
+		return &llm.TaskClassification{Tags: []string{"sales"}, Priority: "medium", Category: "feature", Summary: description}, nil
+	})
+	client := llm.NewRedactingClient(echo, envRedactor(t), &fakeAuditor{})
+
+	description := "Call Acme Corp, then mail bob@example.com [asap]"
+	var pieces []string
+	tc, err := client.ClassifyTaskStream(context.Background(), "Follow up", description, collect(&pieces))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(sent, "Acme") || strings.Contains(sent, "example.com") {
+		t.Errorf("provider saw unredacted text: %q", sent)
+	}
+	if got := strings.Join(pieces, ""); got != description {
+		t.Errorf("streamed %q, want %q", got, description)
+	}
+	if tc.Summary != description {
+		t.Errorf("summary not re-hydrated: %q", tc.Summary)
+	}
+}
+
+func TestRoutingStreamFallsBackOnlyBeforeOutput(t *testing.T) {
+	failing := func(emit bool) llm.LLMClient {
+		return streamFunc(func(ctx context.Context, title, description string, onDelta func(string)) (*llm.TaskClassification, error) {
+			if emit {
+				onDelta(`{"tags":`)
+			}
+			return nil, errors.New("connection reset")
+		})
+	}
+	local := llmFunc(func(ctx context.Context, title, description string) (*llm.TaskClassification, error) {
+		return nil, errors.New("not called")
+	})
+
+	client, _ := llm.NewRoutingClient(
+		llm.Route{Name: "a", Client: failing(false)},
+		llm.Route{Name: "local", Client: local},
+		llm.Route{Name: "mock", Client: llm.NewMockClient()},
+	)
+	tc, err := client.ClassifyTaskStream(context.Background(), "Fix login", "", func(string) {})
+	if err != nil || tc.Provider != "mock" {
+		t.Errorf("expected the mock route to answer, got %+v, %v", tc, err)
+	}
+
+	client, _ = llm.NewRoutingClient(
+		llm.Route{Name: "a", Client: failing(true)},
+		llm.Route{Name: "mock", Client: llm.NewMockClient()},
+	)
+	if _, err := client.ClassifyTaskStream(context.Background(), "Fix login", "", func(string) {}); err == nil {
+		t.Error("expected no fallback once output was streamed")
+	}
+
+	anonymous := streamFunc(func(ctx context.Context, title, description string, onDelta func(string)) (*llm.TaskClassification, error) {
+		return &llm.TaskClassification{Tags: []string{"bug"}, Priority: "high", Category: "bug", Summary: "Fix login"}, nil
+	})
+	client, _ = llm.NewRoutingClient(llm.Route{Name: "custom", Client: anonymous})
+	if tc, err := client.ClassifyTaskStream(context.Background(), "Fix login", "", func(string) {}); err != nil || tc.Provider != "custom" {
+		t.Errorf("expected the answering route as provider, got %+v, %v", tc, err)
+	}
+
+	client, _ = llm.NewRoutingClient(llm.Route{Name: "local", Client: local})
+	if _, err := client.ClassifyTaskStream(context.Background(), "Fix login", "", func(string) {}); !errors.Is(err, llm.ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported without streaming routes, got %v", err)
+	}
+}
+
+func TestCachingStreamReplaysHits(t *testing.T) {
+	client := llm.NewCachingClient(llm.NewMockClient(), "mock", "", &memoryCache{entries: map[string]*llm.TaskClassification{}}, time.Hour)
+	var first, second []string
+	if _, err := client.ClassifyTaskStream(context.Background(), "Fix login", "", collect(&first)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.ClassifyTaskStream(context.Background(), "Fix login", "", collect(&second)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(second) != 1 || second[0] != strings.Join(first, "") {
+		t.Errorf("expected the hit as one piece equal to the stream, got %q and %q", first, second)
+	}
+}
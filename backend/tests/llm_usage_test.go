@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KemenyStudio/task-manager/internal/llm"
+)
+
+// fakeRecorder is an in-memory llm.UsageRecorder.
+type fakeRecorder struct {
+	records []llm.CallRecord
+	blocked bool
+}
+
+func (f *fakeRecorder) Allow(ctx context.Context) error {
+	if f.blocked {
+		return llm.ErrBudgetExceeded
+	}
+	return nil
+}
+
+func (f *fakeRecorder) Record(ctx context.Context, rec llm.CallRecord) {
+	f.records = append(f.records, rec)
+}
+
+func TestMeteringClientRecordsTokens(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": localAnswer}}},
+			"usage":   map[string]int{"prompt_tokens": 120, "completion_tokens": 30},
+		})
+	}))
+	defer srv.Close()
+
+	local, err := llm.NewLocalClient(llm.FlavorOpenAI, srv.URL, "qwen2.5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	recorder := &fakeRecorder{}
+	client := llm.NewMeteringClient(local, "local", "qwen2.5", recorder)
+	if _, ok := client.(llm.Completer); !ok {
+		t.Error("expected a metered Completer to still implement Completer")
+	}
+
+	ctx := llm.WithCaller(context.Background(), "task-1", "user-1")
+	if _, err := client.ClassifyTask(ctx, "Login fails", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recorder.records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(recorder.records))
+	}
+	rec := recorder.records[0]
+	if rec.InputTokens != 120 || rec.OutputTokens != 30 {
+		t.Errorf("expected 120/30 tokens, got %d/%d", rec.InputTokens, rec.OutputTokens)
+	}
+	if rec.Provider != "local" || rec.Model != "qwen2.5" || rec.Operation != "classify" || rec.Err != nil {
+		t.Errorf("unexpected record %+v", rec)
+	}
+	if rec.TaskID != "task-1" || rec.UserID != "user-1" {
+		t.Errorf("expected caller task-1/user-1, got %s/%s", rec.TaskID, rec.UserID)
+	}
+}
+
+func TestMeteringClientRecordsFailures(t *testing.T) {
+	provider := &fakeProvider{results: []func(context.Context) (*llm.TaskClassification, error){answerStatus(503)}}
+	recorder := &fakeRecorder{}
+	client := llm.NewMeteringClient(provider, "openai", "gpt-4o-mini", recorder)
+	if _, ok := client.(llm.Completer); ok {
+		t.Error("expected a metered plain provider not to implement Completer")
+	}
+
+	client.ClassifyTask(context.Background(), "Login fails", "")
+	if len(recorder.records) != 1 || recorder.records[0].Err == nil {
+		t.Errorf("expected the failed call to be recorded, got %+v", recorder.records)
+	}
+}
+
+func TestBudgetExceededBlocksCalls(t *testing.T) {
+	provider := &fakeProvider{results: []func(context.Context) (*llm.TaskClassification, error){answerOK()}}
+	recorder := &fakeRecorder{blocked: true}
+	cfg := fastConfig()
+	cfg.FailureThreshold = 1
+	client := llm.NewResilientClient(llm.NewMeteringClient(provider, "openai", "gpt-4o-mini", recorder), cfg)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.ClassifyTask(context.Background(), "Login fails", ""); !errors.Is(err, llm.ErrBudgetExceeded) {
+			t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+		}
+	}
+	if provider.calls != 0 || len(recorder.records) != 0 {
+		t.Errorf("expected no provider calls, got %d calls and %d records", provider.calls, len(recorder.records))
+	}
+
+	recorder.blocked = false
+	if _, err := client.ClassifyTask(context.Background(), "Login fails", ""); err != nil {
+		t.Errorf("expected a spent budget not to open the circuit breaker, got %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KemenyStudio/task-manager/internal/model"
+	"github.com/KemenyStudio/task-manager/internal/notification"
+)
+
+// smtpStandIn is a minimal SMTP server that accepts a single message and
+// captures the envelope and DATA payload.
+type smtpStandIn struct {
+	addr     string
+	rcpt     chan string
+	messages chan string
+}
+
+func startSMTPStandIn(t *testing.T) *smtpStandIn {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &smtpStandIn{addr: ln.Addr().String(), rcpt: make(chan string, 1), messages: make(chan string, 1)}
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		reply("220 localhost stand-in")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL FROM"):
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO"):
+				s.rcpt <- strings.TrimSpace(line[len("RCPT TO:"):])
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 end with <CRLF>.<CRLF>")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				s.messages <- data.String()
+				reply("250 OK queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return s
+}
+
+func TestSMTPNotifierSendsMultipartEmail(t *testing.T) {
+	server := startSMTPStandIn(t)
+	host, port, _ := net.SplitHostPort(server.addr)
+
+	n, err := notification.NewSMTPNotifier(notification.SMTPConfig{
+		Host: host,
+		Port: port,
+		From: "tasks@kemeny.studio",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msg, err := notification.RenderDeadlineEmail(notification.TaskNotification{
+		TaskID:    "11111111-1111-1111-1111-111111111111",
+		TaskTitle: "Implement OAuth <Google>",
+		Email:     "lucia@kemeny.studio",
+		DueDate:   time.Date(2026, 10, 20, 15, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("unexpected render error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := n.Notify(ctx, msg); err != nil {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+
+	if rcpt := <-server.rcpt; rcpt != "<lucia@kemeny.studio>" {
+		t.Errorf("expected recipient <lucia@kemeny.studio>, got %s", rcpt)
+	}
+
+	data := <-server.messages
+	for _, want := range []string{
+		"Subject: Task deadline approaching: Implement OAuth <Google>",
+		"Content-Type: multipart/alternative",
+		"Content-Type: text/plain; charset=UTF-8",
+		"Content-Type: text/html; charset=UTF-8",
+		"Implement OAuth &lt;Google&gt;",
+		"/tasks/11111111-1111-1111-1111-111111111111",
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("expected email to contain %q", want)
+		}
+	}
+}
+
+func TestSMTPNotifierRejectsHeaderInjection(t *testing.T) {
+	n, err := notification.NewSMTPNotifier(notification.SMTPConfig{Host: "localhost", Port: "1", From: "tasks@kemeny.studio"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, to := range []string{"lucia@kemeny.studio\r\nBcc: everyone@example.com", "not an address"} {
+		err := n.Notify(context.Background(), notification.Message{To: to, Subject: "Hi", Text: "Hi"})
+		if err == nil || !strings.Contains(err.Error(), "invalid recipient") {
+			t.Errorf("Notify(%q) = %v, want an invalid recipient error", to, err)
+		}
+	}
+}
+
+func TestNewSMTPNotifierRequiresHostAndFrom(t *testing.T) {
+	if _, err := notification.NewSMTPNotifier(notification.SMTPConfig{From: "a@b.c"}); err == nil {
+		t.Error("expected error when host is missing")
+	}
+	if _, err := notification.NewSMTPNotifier(notification.SMTPConfig{Host: "localhost"}); err == nil {
+		t.Error("expected error when from is missing")
+	}
+}
+
+func TestMentionHandles(t *testing.T) {
+	got := notification.MentionHandles("Ping @Lucia and @mateo. Mail carlos@kemeny.studio, (@lucia again)")
+	want := []string{"lucia", "mateo"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestRenderTaskEventEmailOmitsLinkForDeletedTasks(t *testing.T) {
+	ev := notification.TaskEvent{
+		Type:    notification.TypeTaskDeleted,
+		TaskID:  "22222222-2222-2222-2222-222222222222",
+		Title:   "Task deleted: Fix dashboard filter",
+		Deleted: true,
+	}
+	msg, err := notification.RenderTaskEventEmail("mateo@kemeny.studio", ev)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(msg.Text, "/tasks/") || strings.Contains(msg.HTML, "/tasks/") {
+		t.Errorf("expected no task link for a deleted task, got %q", msg.Text)
+	}
+
+	ev.Deleted = false
+	msg, _ = notification.RenderTaskEventEmail("mateo@kemeny.studio", ev)
+	if !strings.Contains(msg.HTML, "/tasks/22222222-2222-2222-2222-222222222222") {
+		t.Errorf("expected task link in email, got %q", msg.HTML)
+	}
+}
+
+func TestRecipientsSkipActorAndAlreadyNotifiedUsers(t *testing.T) {
+	watchers := []model.TaskWatcher{
+		{UserID: "actor", InApp: true},
+		{UserID: "assignee", InApp: true},
+		{UserID: "creator", Email: true},
+		{UserID: "watcher", InApp: true, Email: true},
+	}
+	ev := notification.TaskEvent{Type: notification.TypeTaskUpdated, TaskID: "t1", ActorID: "actor", Skip: []string{"assignee"}}
+	var got []string
+	for _, w := range notification.Recipients(ev, watchers) {
+		got = append(got, w.UserID)
+	}
+	if want := []string{"creator", "watcher"}; !slices.Equal(got, want) {
+		t.Errorf("expected recipients %v, got %v", want, got)
+	}
+
+	ev.Skip = nil
+	if got := notification.Recipients(ev, watchers); len(got) != 3 {
+		t.Errorf("expected everyone but the actor, got %v", got)
+	}
+}
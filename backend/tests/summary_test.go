@@ -0,0 +1,179 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KemenyStudio/task-manager/internal/llm"
+	"github.com/KemenyStudio/task-manager/internal/model"
+	"github.com/KemenyStudio/task-manager/internal/summary"
+)
+
+func TestWeekStart(t *testing.T) {
+	monday := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	for _, in := range []time.Time{
+		monday,
+		time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC),
+		time.Date(2026, 10, 18, 23, 59, 0, 0, time.UTC),                     // Sunday
+		time.Date(2026, 10, 19, 1, 0, 0, 0, time.FixedZone("CEST", 2*3600)), // Sunday in UTC
+	} {
+		if got := summary.WeekStart(in); !got.Equal(monday) {
+			t.Errorf("WeekStart(%v) = %v, want %v", in, got, monday)
+		}
+	}
+}
+
+func TestDescribeChange(t *testing.T) {
+	for _, tc := range []struct {
+		field    string
+		old, new *string
+		kind     string
+		text     string
+	}{
+		{"status", strPtr("todo"), strPtr("in_progress"), summary.EventStatus, "moved the task from todo to in_progress"},
+		{"assignee_id", nil, strPtr("Ana"), summary.EventAssignee, "assigned the task to Ana"},
+		{"assignee_id", strPtr("Ana"), strPtr("Bruno"), summary.EventAssignee, "reassigned the task from Ana to Bruno"},
+		{"assignee_id", strPtr("Ana"), nil, summary.EventAssignee, "unassigned the task from Ana"},
+		{"actual_hours", nil, strPtr("2.5"), summary.EventTime, "logged 2.5 h"},
+		{"actual_hours", strPtr("2.5"), strPtr("4"), summary.EventTime, "changed the time logged from 2.5 h to 4 h"},
+		{"estimated_hours", strPtr("3"), strPtr("5"), summary.EventChange, "changed the estimate from 3 h to 5 h"},
+		{"priority", strPtr("medium"), strPtr("high"), summary.EventChange, "changed priority from medium to high"},
+		{"summary", nil, strPtr("Fix the login"), summary.EventChange, `changed summary from nothing to "Fix the login"`},
+	} {
+		kind, text := summary.DescribeChange(tc.field, tc.old, tc.new)
+		if kind != tc.kind || text != tc.text {
+			t.Errorf("DescribeChange(%s) = %s %q, want %s %q", tc.field, kind, text, tc.kind, tc.text)
+		}
+	}
+}
+
+func TestTaskFacts(t *testing.T) {
+	at := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	state := summary.TaskState{Title: "Fix login", Status: "review", Priority: "high", Assignee: strPtr("Ana"),
+		ActualHours: func() *float64 { h := 6.5; return &h }()}
+	events := []model.TaskEvent{
+		{At: at, Kind: summary.EventCreated, Actor: strPtr("Bruno"), Text: "created the task"},
+		{At: at.Add(time.Hour), Kind: summary.EventEscalation, Text: `escalated the task under the rule "Stale"`},
+	}
+	got := summary.TaskFacts(state, events)
+	want := []string{
+		"2026-10-01 09:00 UTC, Bruno created the task.",
+		`2026-10-01 10:00 UTC, System escalated the task under the rule "Stale".`,
+		"Now: status review, priority high, assigned to Ana, 6.5 h logged.",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("TaskFacts = %q, want %q", got, want)
+	}
+
+	for i := 0; i < summary.MaxFacts+10; i++ {
+		events = append(events, model.TaskEvent{At: at.Add(time.Duration(i+2) * time.Hour), Actor: strPtr("Ana"), Text: fmt.Sprintf("change %d", i)})
+	}
+	got = summary.TaskFacts(state, events)
+	if len(got) != summary.MaxFacts {
+		t.Fatalf("got %d facts, want %d", len(got), summary.MaxFacts)
+	}
+	if !strings.Contains(got[0], "created the task") || got[1] != "(14 earlier events left out)" ||
+		!strings.Contains(got[len(got)-2], fmt.Sprintf("change %d", summary.MaxFacts+9)) {
+		t.Errorf("truncated facts start %q, %q and end %q", got[0], got[1], got[len(got)-2])
+	}
+}
+
+func TestClassifyWeeklyReport(t *testing.T) {
+	start := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	r := &model.WeeklyReport{WeekStart: start, WeekEnd: start.AddDate(0, 0, 7), Created: 4}
+	due := func(days int) *time.Time { d := r.WeekEnd.AddDate(0, 0, days); return &d }
+
+	moved := []model.ReportTask{
+		{TaskID: "1", Title: "Ship export", Status: "done", Note: "review → done"},
+		{TaskID: "2", Title: "Back and forth", Status: "todo"}, // ended the week where it started
+		{TaskID: "3", Title: "Start docs", Status: "in_progress", Note: "todo → in_progress"},
+	}
+	open := []summary.OpenTask{
+		{ReportTask: model.ReportTask{TaskID: "4", Title: "Stuck review", Status: "review"}, StatusSince: r.WeekEnd.AddDate(0, 0, -9)},
+		{ReportTask: model.ReportTask{TaskID: "5", Title: "Fresh work", Status: "in_progress", DueDate: due(-1)}, StatusSince: r.WeekEnd.AddDate(0, 0, -2)},
+		{ReportTask: model.ReportTask{TaskID: "6", Title: "Escalated", Status: "todo", DueDate: due(-3)}, StatusSince: start, Escalation: strPtr("Urgent overdue")},
+		{ReportTask: model.ReportTask{TaskID: "7", Title: "Not due", Status: "todo", DueDate: due(2)}, StatusSince: start},
+	}
+	summary.Classify(r, moved, open)
+
+	if r.Completed != 1 || len(r.Moved) != 2 {
+		t.Errorf("completed %d, moved %+v", r.Completed, r.Moved)
+	}
+	if len(r.Blocked) != 2 || r.Blocked[0].Note != "in review for 9 days" || r.Blocked[1].Note != `escalated under "Urgent overdue"` {
+		t.Errorf("blocked = %+v", r.Blocked)
+	}
+	if len(r.Overdue) != 2 || r.Overdue[0].TaskID != "6" || r.Overdue[0].Note != "3 days overdue" || r.Overdue[1].Note != "1 day overdue" {
+		t.Errorf("overdue = %+v", r.Overdue)
+	}
+
+	facts := strings.Join(summary.ReportFacts(r), "\n")
+	for _, want := range []string{
+		"4 tasks were created and 1 completed.",
+		`Moved: "Ship export" (unassigned, done): review → done.`,
+		`Blocked: "Stuck review" (unassigned, review): in review for 9 days.`,
+		`Overdue: "Escalated" (unassigned, todo): 3 days overdue.`,
+	} {
+		if !strings.Contains(facts, want) {
+			t.Errorf("facts lack %q:\n%s", want, facts)
+		}
+	}
+}
+
+func TestValidateSummary(t *testing.T) {
+	s := &llm.Summary{Text: "  Done.  "}
+	if err := llm.ValidateSummary(s); err != nil || s.Text != "Done." {
+		t.Errorf("ValidateSummary = %v, %q", err, s.Text)
+	}
+	var ve *llm.ValidationError
+	if err := llm.ValidateSummary(&llm.Summary{Text: " "}); !errors.As(err, &ve) {
+		t.Errorf("empty summary: want ValidationError, got %v", err)
+	}
+	if err := llm.ValidateSummary(&llm.Summary{Text: strings.Repeat("a", llm.MaxNarrativeLen+1)}); !errors.As(err, &ve) {
+		t.Errorf("long summary: want ValidationError, got %v", err)
+	}
+}
+
+type capturingSummarizer struct {
+	*llm.MockClient
+	req llm.SummaryRequest
+}
+
+func (c *capturingSummarizer) Summarize(ctx context.Context, req llm.SummaryRequest) (*llm.Summary, error) {
+	c.req = req
+	return c.MockClient.Summarize(ctx, req)
+}
+
+func TestSummarizeThroughDecorators(t *testing.T) {
+	provider := &capturingSummarizer{MockClient: llm.NewMockClient()}
+	audit := &fakeAuditor{}
+	client := llm.NewRedactingClient(llm.NewResilientClient(provider, fastConfig()), envRedactor(t), audit)
+
+	s, err := client.Summarize(context.Background(), llm.SummaryRequest{
+		Kind: llm.SummaryTask, Subject: "Task: Reply to Acme Corp",
+		Facts: []string{"Ana created the task.", "Ana wrote to bob@example.com."},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := provider.req.Subject + strings.Join(provider.req.Facts, " ")
+	if strings.Contains(sent, "Acme") || strings.Contains(sent, "example.com") {
+		t.Errorf("provider saw unredacted text: %q", sent)
+	}
+	if !strings.Contains(s.Text, "Acme Corp") || !strings.Contains(s.Text, "bob@example.com") {
+		t.Errorf("summary not re-hydrated: %q", s.Text)
+	}
+	if len(audit.records) != 1 || audit.records[0].Operation != "summarize" {
+		t.Errorf("audit records = %+v", audit.records)
+	}
+
+	unsupported := llmFunc(func(ctx context.Context, title, description string) (*llm.TaskClassification, error) {
+		return nil, errors.New("not called")
+	})
+	if _, err := llm.NewResilientClient(unsupported, fastConfig()).Summarize(context.Background(), llm.SummaryRequest{}); !errors.Is(err, llm.ErrUnsupported) {
+		t.Errorf("want ErrUnsupported, got %v", err)
+	}
+}
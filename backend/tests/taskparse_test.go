@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KemenyStudio/task-manager/internal/llm"
+	"github.com/KemenyStudio/task-manager/internal/model"
+	"github.com/KemenyStudio/task-manager/internal/taskparse"
+)
+
+var parseUsers = []model.User{
+	{ID: "u1", Name: "Lucía Fernández", Email: "lucia@example.com"},
+	{ID: "u2", Name: "Sam Lee", Email: "sam.lee@example.com"},
+	{ID: "u3", Name: "Sam Ortiz", Email: "sortiz@example.com"},
+}
+
+func TestMockParseTask(t *testing.T) {
+	madrid, _ := time.LoadLocation("Europe/Madrid")
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, madrid) // a Monday
+
+	p, err := llm.NewMockClient().ParseTask(context.Background(),
+		"Lucía should fix the dashboard date filter bug by Friday, urgent", llm.ParseOptions{Now: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := llm.ParsedTask{Title: "Fix the dashboard date filter bug", Assignee: "Lucía", DueDate: "2026-10-23", Priority: "urgent", Provider: "mock"}
+	if *p != want {
+		t.Errorf("ParseTask = %+v, want %+v", *p, want)
+	}
+
+	if _, err := llm.NewMockClient().ParseTask(context.Background(), "urgent", llm.ParseOptions{Now: now}); err == nil {
+		t.Error("expected an error for text without a title")
+	}
+}
+
+func TestValidateParsedTask(t *testing.T) {
+	p := &llm.ParsedTask{Title: "  Ship   release ", Assignee: " @sam ", Priority: "HIGH"}
+	if err := llm.ValidateParsedTask(p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Title != "Ship release" || p.Assignee != "sam" || p.Priority != "high" {
+		t.Errorf("not normalized: %+v", p)
+	}
+
+	var ve *llm.ValidationError
+	if err := llm.ValidateParsedTask(&llm.ParsedTask{Title: "x", Priority: "critical"}); !errors.As(err, &ve) || ve.Field != "priority" {
+		t.Errorf("unknown priority: got %v", err)
+	}
+	if err := llm.ValidateParsedTask(&llm.ParsedTask{Title: strings.Repeat("x", llm.MaxTitleLen+1)}); !errors.As(err, &ve) || ve.Field != "title" {
+		t.Errorf("long title: got %v", err)
+	}
+}
+
+func TestMatchUser(t *testing.T) {
+	for _, mention := range []string{"Lucia", "lucía fernández", "@lucia", "LUCIA@example.com"} {
+		if u, _ := taskparse.MatchUser(parseUsers, mention); u == nil || u.ID != "u1" {
+			t.Errorf("MatchUser(%q) = %v, want u1", mention, u)
+		}
+	}
+	if u, _ := taskparse.MatchUser(parseUsers, "Sam Ortiz"); u == nil || u.ID != "u3" {
+		t.Errorf("full name should win over first names, got %v", u)
+	}
+	if u, candidates := taskparse.MatchUser(parseUsers, "sam"); u != nil || len(candidates) != 2 {
+		t.Errorf("MatchUser(sam) = %v, %v; want two candidates", u, candidates)
+	}
+	if u, candidates := taskparse.MatchUser(parseUsers, "Alex"); u != nil || candidates != nil {
+		t.Errorf("MatchUser(Alex) = %v, %v; want no match", u, candidates)
+	}
+}
+
+func TestResolveDueDate(t *testing.T) {
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	due, err := taskparse.ResolveDueDate("2026-10-23", tokyo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := due.Format(time.RFC3339); got != "2026-10-23T23:59:00+09:00" {
+		t.Errorf("date = %s", got)
+	}
+	due, err = taskparse.ResolveDueDate("2026-10-23T10:30", tokyo)
+	if err != nil || due.UTC().Format(time.RFC3339) != "2026-10-23T01:30:00Z" {
+		t.Errorf("date and time = %v, %v", due, err)
+	}
+	if _, err := taskparse.ResolveDueDate("next friday", tokyo); err == nil {
+		t.Error("expected an error for an unresolved date")
+	}
+}
+
+func TestDraft(t *testing.T) {
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+
+	d := taskparse.Draft(&llm.ParsedTask{Title: "Fix filter", Assignee: "Lucia", DueDate: "2026-10-23"}, parseUsers, now)
+	if d.Request.Title != "Fix filter" || d.Request.Status != "todo" || d.Request.Priority != "medium" {
+		t.Errorf("request = %+v", d.Request)
+	}
+	if d.Request.AssigneeID == nil || *d.Request.AssigneeID != "u1" || d.Assignee == nil {
+		t.Errorf("assignee not resolved: %+v", d)
+	}
+	if d.Request.DueDate == nil || *d.Request.DueDate != "2026-10-23T23:59:00Z" {
+		t.Errorf("due date = %v", d.Request.DueDate)
+	}
+	if len(d.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", d.Warnings)
+	}
+
+	d = taskparse.Draft(&llm.ParsedTask{Title: "Fix filter", Assignee: "sam", DueDate: "2026-10-01"}, parseUsers, now)
+	if d.Request.AssigneeID != nil || len(d.AssigneeCandidates) != 2 {
+		t.Errorf("ambiguous assignee: %+v", d)
+	}
+	if len(d.Warnings) != 2 {
+		t.Errorf("want ambiguity and past due warnings, got %v", d.Warnings)
+	}
+
+	d = taskparse.Draft(&llm.ParsedTask{Title: "Fix filter", Assignee: "Alex", DueDate: "soon"}, parseUsers, now)
+	if d.Request.AssigneeID != nil || d.Request.DueDate != nil || len(d.Warnings) != 2 {
+		t.Errorf("unresolved draft: %+v", d)
+	}
+}
+
+func TestParseTaskThroughDecorators(t *testing.T) {
+	ctx := context.Background()
+	opts := llm.ParseOptions{Now: time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)}
+	unsupported := llmFunc(func(ctx context.Context, title, description string) (*llm.TaskClassification, error) {
+		return nil, errors.New("not called")
+	})
+
+	routed, err := llm.NewRoutingClient(
+		llm.Route{Name: "plain", Client: unsupported, Weight: 1},
+		llm.Route{Name: "mock", Client: llm.NewResilientClient(llm.NewMockClient(), fastConfig())},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := routed.ParseTask(ctx, "Review the onboarding copy @sam", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Title != "Review the onboarding copy" || p.Assignee != "sam" {
+		t.Errorf("ParseTask = %+v", p)
+	}
+
+	recorder, audit := &fakeRecorder{}, &fakeAuditor{}
+	for _, client := range []llm.TaskParser{
+		llm.NewResilientClient(unsupported, fastConfig()),
+		llm.NewMeteringClient(unsupported, "plain", "", recorder).(llm.TaskParser),
+		llm.NewCachingClient(unsupported, "plain", "", &memoryCache{}, time.Hour),
+		llm.NewRedactingClient(unsupported, envRedactor(t), audit),
+	} {
+		if _, err := client.ParseTask(ctx, "Email bob@example.com", opts); !errors.Is(err, llm.ErrUnsupported) {
+			t.Errorf("%T: want ErrUnsupported, got %v", client, err)
+		}
+	}
+	if len(recorder.records) != 0 || len(audit.records) != 0 {
+		t.Errorf("unsupported calls were recorded: %v, %v", recorder.records, audit.records)
+	}
+}
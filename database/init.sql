@@ -16,6 +16,8 @@ CREATE TABLE users (
     password_hash VARCHAR(255) NOT NULL,
     role VARCHAR(50) NOT NULL DEFAULT 'member', -- 'admin', 'member'
     avatar_url TEXT,
+    email_notifications BOOLEAN NOT NULL DEFAULT TRUE, -- per-user email opt-out
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC', -- IANA name, e.g. 'America/Santiago'
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
@@ -25,14 +27,15 @@ CREATE TABLE tasks (
     title VARCHAR(500) NOT NULL,
     description TEXT,
     status VARCHAR(50) NOT NULL DEFAULT 'todo', -- 'todo', 'in_progress', 'review', 'done'
-    priority VARCHAR(20) NOT NULL DEFAULT 'medium', -- 'low', 'medium', 'high', 'urgent'
-    category VARCHAR(100),
+    priority VARCHAR(20) NOT NULL DEFAULT 'medium' CHECK (priority IN ('low', 'medium', 'high', 'urgent')),
+    category VARCHAR(100) CHECK (category IN ('bug', 'feature', 'improvement', 'research')),
     summary TEXT,
     creator_id UUID NOT NULL REFERENCES users(id),
     assignee_id UUID REFERENCES users(id),
     due_date TIMESTAMP WITH TIME ZONE,
     estimated_hours DECIMAL(5,2),
     actual_hours DECIMAL(5,2),
+    duplicate_of UUID REFERENCES tasks(id) ON DELETE SET NULL, -- set when merged into another task
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
@@ -62,6 +65,289 @@ CREATE TABLE task_tags (
     PRIMARY KEY (task_id, tag_id)
 );
 
+CREATE TABLE task_watchers (
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    in_app BOOLEAN NOT NULL DEFAULT TRUE,
+    email BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (task_id, user_id)
+);
+
+CREATE TABLE sent_notifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    kind VARCHAR(50) NOT NULL, -- 'deadline'
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    channel VARCHAR(20) NOT NULL DEFAULT 'email', -- 'email', 'in_app'
+    window_key VARCHAR(100) NOT NULL, -- dedup window, e.g. the due date notified about
+    sent_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (kind, task_id, user_id, channel, window_key)
+);
+
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL, -- 'assignment', 'mention', 'status_change', 'deadline', 'classification', 'task_updated', 'task_deleted', 'escalation'
+    task_id UUID REFERENCES tasks(id) ON DELETE SET NULL,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    title VARCHAR(500) NOT NULL,
+    body TEXT,
+    read_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE notification_preferences (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    in_app BOOLEAN NOT NULL DEFAULT TRUE,
+    email BOOLEAN NOT NULL DEFAULT TRUE,
+    PRIMARY KEY (user_id, type)
+);
+
+CREATE TABLE digest_subscriptions (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    frequency VARCHAR(20) NOT NULL DEFAULT 'off', -- 'off', 'daily', 'weekly'
+    send_hour SMALLINT NOT NULL DEFAULT 8, -- local hour in the user's timezone
+    weekday SMALLINT NOT NULL DEFAULT 1, -- 0 = Sunday, for weekly digests
+    last_sent_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE job_runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    job_name VARCHAR(100) NOT NULL,
+    trigger VARCHAR(20) NOT NULL, -- 'schedule', 'manual'
+    status VARCHAR(20) NOT NULL, -- 'running', 'succeeded', 'failed'
+    error TEXT,
+    scheduled_for TIMESTAMP WITH TIME ZONE, -- slot of a scheduled run, NULL for manual runs
+    started_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    finished_at TIMESTAMP WITH TIME ZONE,
+    duration_ms BIGINT
+);
+
+CREATE TABLE classification_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    requested_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued', -- 'queued', 'running', 'succeeded', 'failed'
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 5,
+    force BOOLEAN NOT NULL DEFAULT FALSE, -- bypass the classification cache
+    last_error TEXT,
+    result JSONB, -- the stored classification
+    run_after TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Bulk re-classification runs; items snapshot the selected tasks so a run can resume
+CREATE TABLE classification_batches (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    requested_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'running', -- 'running', 'succeeded', 'failed', 'cancelled'
+    filter JSONB NOT NULL DEFAULT '{}',
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE, -- report changes without storing suggestions
+    force BOOLEAN NOT NULL DEFAULT FALSE, -- bypass the classification cache
+    concurrency INTEGER NOT NULL DEFAULT 4,
+    rate_per_minute INTEGER NOT NULL DEFAULT 0, -- 0 means unlimited
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE classification_batch_items (
+    batch_id UUID NOT NULL REFERENCES classification_batches(id) ON DELETE CASCADE,
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- 'pending', 'running', 'done', 'failed'
+    changes JSONB, -- field -> {from, to}, for fields the classification would change
+    error TEXT,
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (batch_id, task_id)
+);
+
+-- LLM answers keyed by a hash of provider, model, prompt version, title and description
+CREATE TABLE classification_cache (
+    key CHAR(64) PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    model VARCHAR(100),
+    result JSONB NOT NULL,
+    hits INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Versioned LLM prompts (Go text/template); one active version per name
+CREATE TABLE prompt_templates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(50) NOT NULL, -- 'classification'
+    version INTEGER NOT NULL,
+    body TEXT NOT NULL,
+    few_shot_examples INTEGER NOT NULL DEFAULT 3, -- accepted classifications shown as examples
+    notes TEXT,
+    active BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    activated_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (name, version)
+);
+
+-- One row per call made to an LLM provider, including retries and failures
+CREATE TABLE llm_usage (
+    id BIGSERIAL PRIMARY KEY,
+    workspace VARCHAR(100) NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    model VARCHAR(100) NOT NULL,
+    operation VARCHAR(20) NOT NULL, -- 'classify', 'complete', 'parse', 'estimate', 'embed', 'summarize'
+    input_tokens INTEGER NOT NULL DEFAULT 0,
+    output_tokens INTEGER NOT NULL DEFAULT 0,
+    latency_ms INTEGER NOT NULL,
+    success BOOLEAN NOT NULL,
+    error TEXT,
+    task_id UUID REFERENCES tasks(id) ON DELETE SET NULL,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Monthly token budgets; workspaces without a row are unlimited
+CREATE TABLE llm_budgets (
+    workspace VARCHAR(100) PRIMARY KEY,
+    monthly_token_limit BIGINT NOT NULL CHECK (monthly_token_limit >= 0),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- USD per million tokens, used to estimate cost in usage reports
+CREATE TABLE llm_model_prices (
+    provider VARCHAR(50) NOT NULL,
+    model VARCHAR(100) NOT NULL,
+    input_per_million NUMERIC(10,4) NOT NULL,
+    output_per_million NUMERIC(10,4) NOT NULL,
+    PRIMARY KEY (provider, model)
+);
+
+-- What was redacted from task text before it was sent to a provider; counts only, never the values
+CREATE TABLE llm_redactions (
+    id BIGSERIAL PRIMARY KEY,
+    workspace VARCHAR(100) NOT NULL,
+    operation VARCHAR(20) NOT NULL, -- 'classify', 'parse', 'estimate', 'embed', 'summarize'
+    kind VARCHAR(50) NOT NULL, -- 'token', 'email', 'card', 'phone', 'term'
+    count INTEGER NOT NULL CHECK (count > 0),
+    task_id UUID REFERENCES tasks(id) ON DELETE SET NULL,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE ai_suggestions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    requested_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- 'pending', 'accepted', 'partially_accepted', 'rejected', 'superseded'
+    priority VARCHAR(20) NOT NULL CHECK (priority IN ('low', 'medium', 'high', 'urgent')),
+    category VARCHAR(50) NOT NULL CHECK (category IN ('bug', 'feature', 'improvement', 'research')),
+    summary TEXT NOT NULL,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    provider VARCHAR(50), -- LLM provider and model that answered
+    model VARCHAR(100),
+    prompt_version VARCHAR(20), -- prompt template version, e.g. 'v2' or 'builtin'
+    reject_reason TEXT,
+    decided_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    decided_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE ai_suggestion_decisions (
+    suggestion_id UUID NOT NULL REFERENCES ai_suggestions(id) ON DELETE CASCADE,
+    field VARCHAR(20) NOT NULL, -- 'priority', 'category', 'summary', 'tags'
+    suggested_value TEXT NOT NULL,
+    previous_value TEXT,
+    accepted BOOLEAN NOT NULL,
+    reason TEXT,
+    decided_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    decided_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (suggestion_id, field)
+);
+
+-- AI effort estimates, compared with actual_hours once the task is done
+CREATE TABLE effort_estimates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    requested_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    method VARCHAR(20) NOT NULL, -- 'neighbours', 'llm'
+    hours DECIMAL(5,2) NOT NULL CHECK (hours > 0),
+    low_hours DECIMAL(5,2) NOT NULL,
+    high_hours DECIMAL(5,2) NOT NULL,
+    confidence VARCHAR(10) NOT NULL, -- 'low', 'medium', 'high'
+    neighbours JSONB NOT NULL DEFAULT '[]', -- similar completed tasks: task_id, title, actual_hours, similarity
+    rationale TEXT,
+    provider VARCHAR(50), -- set when the LLM refined the estimate
+    model VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Embeddings of task text for duplicate detection, compared by brute force
+-- since the database has no vector extension. Vectors are unit length.
+CREATE TABLE task_embeddings (
+    task_id UUID PRIMARY KEY REFERENCES tasks(id) ON DELETE CASCADE,
+    model VARCHAR(150) NOT NULL, -- provider:model; only vectors of the same model are compared
+    content_hash CHAR(64) NOT NULL, -- SHA-256 of the embedded title and description
+    embedding REAL[] NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- AI summaries of task histories, regenerated when the history changes
+CREATE TABLE task_history_summaries (
+    task_id UUID PRIMARY KEY REFERENCES tasks(id) ON DELETE CASCADE,
+    summary TEXT NOT NULL,
+    events_hash CHAR(64) NOT NULL, -- SHA-256 of the facts summarized
+    provider VARCHAR(50),
+    model VARCHAR(100),
+    generated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- AI weekly project reports, one per week
+CREATE TABLE weekly_reports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    week_start TIMESTAMP WITH TIME ZONE NOT NULL UNIQUE, -- Monday 00:00 UTC
+    summary TEXT NOT NULL,
+    created_count INTEGER NOT NULL DEFAULT 0,
+    completed_count INTEGER NOT NULL DEFAULT 0,
+    moved JSONB NOT NULL DEFAULT '[]', -- tasks: task_id, title, status, assignee, due_date, note
+    blocked JSONB NOT NULL DEFAULT '[]',
+    overdue JSONB NOT NULL DEFAULT '[]',
+    provider VARCHAR(50),
+    model VARCHAR(100),
+    generated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE escalation_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    condition VARCHAR(20) NOT NULL, -- 'overdue', 'stale_status'
+    priority VARCHAR(20), -- only tasks with this priority; any if NULL
+    status VARCHAR(20), -- status the task is stuck in, for 'stale_status'
+    threshold_minutes INTEGER NOT NULL,
+    notify TEXT[] NOT NULL, -- 'assignee', 'creator', 'watchers', 'admins'
+    repeat_minutes INTEGER, -- re-notify until acknowledged; once if NULL
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE escalation_log (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    rule_id UUID NOT NULL REFERENCES escalation_rules(id) ON DELETE CASCADE,
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    notified_user_ids UUID[] NOT NULL DEFAULT '{}',
+    notify_count INTEGER NOT NULL DEFAULT 1,
+    triggered_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_notified_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    acknowledged_at TIMESTAMP WITH TIME ZONE,
+    acknowledged_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMP WITH TIME ZONE -- set once the task no longer matches the rule
+);
+
 -- ============================================
 -- INDEXES
 -- ============================================
@@ -73,6 +359,29 @@ CREATE INDEX idx_tasks_due_date ON tasks(due_date);
 CREATE INDEX idx_edit_history_task ON edit_history(task_id);
 CREATE INDEX idx_task_tags_task ON task_tags(task_id);
 CREATE INDEX idx_task_tags_tag ON task_tags(tag_id);
+CREATE INDEX idx_task_watchers_user ON task_watchers(user_id);
+CREATE INDEX idx_sent_notifications_user ON sent_notifications(user_id);
+CREATE INDEX idx_notifications_user ON notifications(user_id, created_at DESC);
+CREATE INDEX idx_notifications_unread ON notifications(user_id) WHERE read_at IS NULL;
+CREATE INDEX idx_notifications_created ON notifications(created_at);
+CREATE INDEX idx_edit_history_field ON edit_history(field_name, edited_at);
+CREATE INDEX idx_job_runs_job ON job_runs(job_name, started_at DESC);
+CREATE UNIQUE INDEX idx_job_runs_slot ON job_runs(job_name, scheduled_for);
+CREATE INDEX idx_classification_jobs_pending ON classification_jobs(run_after) WHERE status IN ('queued', 'running');
+CREATE INDEX idx_classification_jobs_task ON classification_jobs(task_id, created_at DESC);
+CREATE UNIQUE INDEX idx_classification_jobs_queued ON classification_jobs(task_id) WHERE status = 'queued';
+CREATE INDEX idx_classification_cache_expires ON classification_cache(expires_at);
+CREATE INDEX idx_classification_batch_items_open ON classification_batch_items(batch_id, position) WHERE status IN ('pending', 'running');
+CREATE UNIQUE INDEX idx_prompt_templates_active ON prompt_templates(name) WHERE active;
+CREATE INDEX idx_llm_usage_workspace ON llm_usage(workspace, created_at);
+CREATE INDEX idx_llm_redactions_workspace ON llm_redactions(workspace, created_at);
+CREATE INDEX idx_ai_suggestions_task ON ai_suggestions(task_id, created_at DESC);
+CREATE INDEX idx_effort_estimates_task ON effort_estimates(task_id, created_at DESC);
+CREATE INDEX idx_task_embeddings_model ON task_embeddings(model);
+CREATE INDEX idx_tasks_duplicate_of ON tasks(duplicate_of) WHERE duplicate_of IS NOT NULL;
+CREATE UNIQUE INDEX idx_ai_suggestions_pending ON ai_suggestions(task_id) WHERE status = 'pending';
+CREATE INDEX idx_escalation_log_task ON escalation_log(task_id, triggered_at DESC);
+CREATE UNIQUE INDEX idx_escalation_log_open ON escalation_log(rule_id, task_id) WHERE resolved_at IS NULL;
 
 -- ============================================
 -- SEED DATA
@@ -157,6 +466,46 @@ INSERT INTO tasks (id, title, description, status, priority, creator_id, assigne
      'b2c3d4e5-f6a7-8901-bcde-f12345678901', 'c3d4e5f6-a7b8-9012-cdef-123456789012',
      NOW() + INTERVAL '20 days', 6);
 
+-- Watchers (creators and assignees auto-watch their tasks)
+INSERT INTO task_watchers (task_id, user_id)
+SELECT id, creator_id FROM tasks
+UNION
+SELECT id, assignee_id FROM tasks WHERE assignee_id IS NOT NULL;
+
+-- Escalation rules
+INSERT INTO escalation_rules (name, condition, priority, status, threshold_minutes, notify, repeat_minutes) VALUES
+    ('Urgent task overdue by 4h', 'overdue', 'urgent', NULL, 240, '{creator,admins}', NULL),
+    ('Task stuck in review for 3 days', 'stale_status', NULL, 'review', 4320, '{assignee}', 1440);
+
+-- Classification prompt, version 1 (same as the built-in prompt)
+INSERT INTO prompt_templates (name, version, body, few_shot_examples, notes, active, activated_at) VALUES
+    ('classification', 1, 'Classify the following task. Reply with JSON only.
+{
+  "tags": ["tag1","tag2"],
+  "priority": "low|medium|high|urgent",
+  "category": "bug|feature|improvement|research",
+  "summary": "one-line summary"
+}
+{{range .Examples}}
+Example:
+Title: {{.Title}}
+Description: {{.Description}}
+Answer: {{json .Classification}}
+{{end}}
+Title: {{.Title}}
+
+Description: {{.Description}}
+', 3, 'Initial prompt', TRUE, NOW());
+
+-- LLM prices (USD per million tokens); add rows for other models as needed
+INSERT INTO llm_model_prices (provider, model, input_per_million, output_per_million) VALUES
+    ('openai', 'gpt-3.5-turbo', 0.50, 1.50),
+    ('openai', 'gpt-4o-mini', 0.15, 0.60),
+    ('openai', 'gpt-4o', 2.50, 10.00),
+    ('anthropic', 'claude-2.1', 8.00, 24.00),
+    ('anthropic', 'claude-3-5-haiku-latest', 0.80, 4.00),
+    ('anthropic', 'claude-3-5-sonnet-latest', 3.00, 15.00);
+
 -- Edit History
 INSERT INTO edit_history (task_id, user_id, field_name, old_value, new_value) VALUES
     ('11111111-1111-1111-1111-111111111111', 'a1b2c3d4-e5f6-7890-abcd-ef1234567890', 'status', 'todo', 'in_progress'),